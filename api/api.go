@@ -35,8 +35,13 @@ func LoadSource(file string, data []byte, id string, guard ...string) (*API, err
 		return nil, err
 	}
 	apisMu.Lock()
+	if old, has := APIs[id]; has {
+		removeEndpoints(old)
+	}
 	APIs[id] = api
 	apisMu.Unlock()
+
+	registerEndpoints(api)
 	return api, nil
 }
 
@@ -65,6 +70,40 @@ func parseSource(file string, data []byte, id string, guard ...string) (*API, er
 			return nil, fmt.Errorf("[API] Load %s Error: is already registered", id)
 		}
 		uniquePathCheck[unique] = true
+
+		if path.Validate != nil {
+			if _, err := path.Validate.compile(); err != nil {
+				log.Error("[API] Load %s %s %s Error: %s", id, path.Method, path.Path, err.Error())
+				return nil, fmt.Errorf("[API] Load %s %s %s Error: %s", id, path.Method, path.Path, err.Error())
+			}
+		}
+
+		if path.Cache != nil {
+			if _, err := path.Cache.compile(""); err != nil {
+				log.Error("[API] Load %s %s %s Error: %s", id, path.Method, path.Path, err.Error())
+				return nil, fmt.Errorf("[API] Load %s %s %s Error: %s", id, path.Method, path.Path, err.Error())
+			}
+		}
+
+		if path.RateLimit != nil && path.RateLimit.Limit > 0 {
+			if _, err := path.RateLimit.compile(""); err != nil {
+				log.Error("[API] Load %s %s %s Error: %s", id, path.Method, path.Path, err.Error())
+				return nil, fmt.Errorf("[API] Load %s %s %s Error: %s", id, path.Method, path.Path, err.Error())
+			}
+		}
+	}
+
+	if http.RateLimit != nil && http.RateLimit.Limit > 0 {
+		if _, err := http.RateLimit.compile(""); err != nil {
+			log.Error("[API] Load %s Error: %s", id, err.Error())
+			return nil, fmt.Errorf("[API] Load %s Error: %s", id, err.Error())
+		}
+	}
+
+	// Versioning and deprecations
+	if err := http.checkVersion(); err != nil {
+		log.Error("[API] Load %s Error: %s", id, err.Error())
+		return nil, fmt.Errorf("[API] Load %s Error: %s", id, err.Error())
 	}
 
 	// Default Guard
@@ -133,10 +172,28 @@ func SetRoutes(router *gin.Engine, path string, allows ...string) {
 		snapshot = append(snapshot, api)
 	}
 	apisMu.RUnlock()
+	routeAPIs(router, path, snapshot, allows...)
 
-	for _, api := range snapshot {
+	// The OpenAPI document
+	setOpenAPIRoute(router, path)
+}
+
+// routeAPIs registers the routes of the APIs
+func routeAPIs(router *gin.Engine, path string, apis []*API, allows ...string) {
+
+	// the versions of a group versioned by header share the routes
+	versions := map[string][]*API{}
+	for _, api := range apis {
+		if api.HTTP.Versioning == VersioningHeader {
+			versions[api.HTTP.Group] = append(versions[api.HTTP.Group], api)
+			continue
+		}
 		api.HTTP.Routes(router, path, allows...)
 	}
+
+	for _, apis := range versions {
+		routeVersions(router, path, apis, allows...)
+	}
 }
 
 // SetGuards set guards
@@ -165,8 +222,17 @@ func FindHandler(method, path string) (*API, *Path, gin.HandlerFunc, map[string]
 		return nil, nil, nil, nil, fmt.Errorf("route not found: %s %s", method, path)
 	}
 
-	handler := BuildHandler(entry.API.HTTP, *entry.Path)
-	return entry.API, entry.Path, handler, params, nil
+	// the versions of a group versioned by header share the routes
+	if entry.API.HTTP.Versioning == VersioningHeader {
+		entries := routeTable.versions(method, entry)
+		routes := make([]versionRoute, 0, len(entries))
+		for _, e := range entries {
+			routes = append(routes, versionRoute{version: e.API.HTTP.Version, handlers: []gin.HandlerFunc{e.buildHandler()}})
+		}
+		return entries[0].API, entries[0].Path, versionDispatcher(routes), params, nil
+	}
+
+	return entry.API, entry.Path, entry.buildHandler(), params, nil
 }
 
 // ReloadAPIs reloads all API definitions from the specified directory
@@ -223,9 +289,16 @@ func ReloadAPIs(root string) error {
 
 	// Atomically replace global APIs and rebuild route table
 	apisMu.Lock()
+	for _, api := range APIs {
+		removeEndpoints(api)
+	}
 	APIs = newAPIs
 	apisMu.Unlock()
 
+	for _, api := range newAPIs {
+		registerEndpoints(api)
+	}
+
 	routeTable.mu.Lock()
 	defer routeTable.mu.Unlock()
 
@@ -233,7 +306,7 @@ func ReloadAPIs(root string) error {
 	for _, api := range newAPIs {
 		for i := range api.HTTP.Paths {
 			path := &api.HTTP.Paths[i]
-			fullPath := buildFullPath(api.HTTP.Group, path.Path)
+			fullPath := buildFullPath(api.HTTP.routeGroup(), path.Path)
 
 			entry := &RouteEntry{
 				Pattern: fullPath,
@@ -269,7 +342,7 @@ func BuildRouteTable() {
 	for _, api := range snapshot {
 		for i := range api.HTTP.Paths {
 			path := &api.HTTP.Paths[i]
-			fullPath := buildFullPath(api.HTTP.Group, path.Path)
+			fullPath := buildFullPath(api.HTTP.routeGroup(), path.Path)
 
 			entry := &RouteEntry{
 				Pattern: fullPath,
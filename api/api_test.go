@@ -196,6 +196,49 @@ func testRouter(t *testing.T, middlewares ...gin.HandlerFunc) *gin.Engine {
 	return router
 }
 
+// testRequest serves the request with the headers, the body is sent as JSON
+func testRequest(router *gin.Engine, method string, url string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(response, req)
+	return response
+}
+
+// loadTestAPIs loads the API sources (id: source) and returns a router serving them under /api
+func loadTestAPIs(t *testing.T, sources map[string]string, middlewares ...gin.HandlerFunc) *gin.Engine {
+	apis := []*API{}
+	for id, source := range sources {
+		api, err := LoadSource("/apis/"+id+".http.yao", []byte(source), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		apis = append(apis, api)
+	}
+
+	router := gin.New()
+	router.Use(middlewares...)
+	routeAPIs(router, "/api", apis)
+	return router
+}
+
+// unloadTestAPIs removes the APIs loaded by loadTestAPIs
+func unloadTestAPIs(ids ...string) {
+	apisMu.Lock()
+	defer apisMu.Unlock()
+	for _, id := range ids {
+		if api, has := APIs[id]; has {
+			removeEndpoints(api)
+		}
+		delete(APIs, id)
+	}
+}
+
 func responseMap(resp *httptest.ResponseRecorder) maps.MapStrAny {
 	body := resp.Body.Bytes()
 	res := map[string]interface{}{}
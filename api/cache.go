@@ -0,0 +1,312 @@
+package api
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// CachePrefix the key prefix of the cached responses in the store
+var CachePrefix = "yao:api:cache:"
+
+// Cache the response cache of a GET path, the process result is cached by the path, the process
+// arguments, the given request headers and the user. The guarded paths are cached for each user by default,
+// the process runs with the session and the authorized information of the caller.
+type Cache struct {
+	Store   string   `json:"store,omitempty"`   // the store of the cached results, the results are kept in memory if empty
+	TTL     string   `json:"ttl,omitempty"`     // the time to live, e.g. 30s, 5m, 1h, default is 1m
+	Headers []string `json:"headers,omitempty"` // the request headers the response varies on, e.g. Accept-Language
+	User    *bool    `json:"user,omitempty"`    // cache the result for each user, default is true if the path is guarded
+}
+
+// responseCache the compiled cache of a path
+type responseCache struct {
+	Cache
+	scope   string
+	version string
+	user    bool
+	ttl     time.Duration
+}
+
+// cacheEntry the cached process result
+type cacheEntry struct {
+	value    interface{}
+	etag     string
+	modified time.Time
+}
+
+// notModified the result of a conditional request matching the cached result
+type notModified struct{}
+
+// compile validates the cache
+func (cache Cache) compile(scope string) (*responseCache, error) {
+	if cache.TTL == "" {
+		cache.TTL = "1m"
+	}
+	ttl, err := time.ParseDuration(cache.TTL)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("cache.ttl %s is not a valid duration", cache.TTL)
+	}
+	return &responseCache{Cache: cache, scope: scope, ttl: ttl}, nil
+}
+
+// responseCache the response cache of the path, nil if the path is not cached
+func (http HTTP) responseCache(path Path) *responseCache {
+	if path.Cache == nil {
+		return nil
+	}
+
+	cache, err := path.Cache.compile(cacheScope(http, path))
+	if err != nil {
+		log.Error("[Path] %s %s %s", path.Method, path.Path, err.Error())
+		return nil
+	}
+
+	// the versions versioned by header share the scope
+	cache.version = http.Version
+
+	guard := path.Guard
+	if guard == "" {
+		guard = http.Guard
+	}
+	cache.user = guard != "" && guard != "-"
+	if path.Cache.User != nil {
+		cache.user = *path.Cache.User
+	}
+	return cache
+}
+
+// cacheScope the scope of the cached results of the path, e.g. GET /user/:id
+func cacheScope(http HTTP, path Path) string {
+	return fmt.Sprintf("%s %s", strings.ToUpper(path.Method), buildFullPath(http.routeGroup(), path.Path))
+}
+
+// enabled the cache is used for the GET and HEAD requests only
+func (cache *responseCache) enabled(c *gin.Context) bool {
+	return cache != nil && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead)
+}
+
+// exec returns the cached result or runs the process and caches the result.
+// Sends notModified if the request is a conditional request matching the result.
+func (cache *responseCache) exec(ctx context.Context, chRes chan<- interface{}, c *gin.Context, path Path, getArgs argsHandler) {
+	args := getArgs(c)
+	fixedArgs := func(c *gin.Context) []interface{} { return args }
+
+	stor, err := cache.store()
+	if err != nil {
+		log.Error("[Path] %s cache %s", path.Path, err.Error())
+		path.execProcess(ctx, chRes, c, fixedArgs)
+		return
+	}
+
+	key, err := cache.key(c, args)
+	if err != nil {
+		log.Error("[Path] %s cache %s", path.Path, err.Error())
+		path.execProcess(ctx, chRes, c, fixedArgs)
+		return
+	}
+
+	status := "HIT"
+	entry, hit := cache.get(stor, key)
+	if !hit {
+		status = "MISS"
+		res := make(chan interface{}, 1)
+		path.execProcess(ctx, res, c, fixedArgs)
+		value := <-res
+		if !cacheable(value) {
+			chRes <- value
+			return
+		}
+
+		entry, err = newCacheEntry(value)
+		if err != nil {
+			log.Error("[Path] %s cache %s", path.Path, err.Error())
+			chRes <- value
+			return
+		}
+
+		data := map[string]interface{}{"value": entry.value, "etag": entry.etag, "modified": entry.modified.Unix()}
+		if err := stor.Set(key, data, cache.ttl); err != nil {
+			log.Error("[Path] %s cache %s", path.Path, err.Error())
+		}
+	}
+
+	c.Header("ETag", entry.etag)
+	c.Header("Last-Modified", entry.modified.UTC().Format(http.TimeFormat))
+	c.Header("X-Cache", status)
+	if len(cache.Headers) > 0 {
+		c.Header("Vary", strings.Join(cache.Headers, ", "))
+	}
+
+	if entry.matches(c) {
+		chRes <- notModified{}
+		return
+	}
+	chRes <- entry.value
+}
+
+// key the store key of the request, the hash of the arguments, the varying headers, the user and the API version
+func (cache *responseCache) key(c *gin.Context, args []interface{}) (string, error) {
+	headers := map[string]string{}
+	for _, name := range cache.Headers {
+		headers[http.CanonicalHeaderKey(name)] = c.GetHeader(name)
+	}
+
+	user := ""
+	if cache.user {
+		if authorized := getAuthorizedInfo(c); authorized != nil {
+			if id, has := authorized["user_id"]; has && id != nil {
+				user = fmt.Sprintf("%v", id)
+			} else if sub, has := authorized["sub"]; has && sub != nil {
+				user = fmt.Sprintf("%v", sub)
+			}
+		}
+
+		// the session of the caller if the user is unknown
+		if user == "" {
+			if sid, ok := c.Get("__sid"); ok {
+				user = fmt.Sprintf("sid:%v", sid)
+			}
+		}
+	}
+
+	data, err := jsoniter.Marshal(map[string]interface{}{"args": args, "headers": headers, "user": user, "version": cache.version})
+	if err != nil {
+		return "", err
+	}
+	hash := sha1.Sum(data)
+	return CachePrefix + cache.scope + ":" + hex.EncodeToString(hash[:]), nil
+}
+
+// get the cached entry
+func (cache *responseCache) get(stor store.Store, key string) (*cacheEntry, bool) {
+	value, ok := stor.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	etag, ok := data["etag"].(string)
+	if !ok {
+		return nil, false
+	}
+	return &cacheEntry{value: data["value"], etag: etag, modified: time.Unix(toInt(data["modified"]), 0)}, true
+}
+
+// store the store of the cached results
+func (cache *responseCache) store() (store.Store, error) {
+	if cache.Store != "" {
+		return store.Get(cache.Store)
+	}
+	return memoryStore("cache")
+}
+
+// newCacheEntry the entry of the process result, the ETag is the hash of the result
+func newCacheEntry(value interface{}) (*cacheEntry, error) {
+	data, err := jsoniter.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum(data)
+	return &cacheEntry{value: value, etag: fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:])), modified: time.Now()}, nil
+}
+
+// matches the conditional request matches the entry, If-None-Match takes precedence over If-Modified-Since
+func (entry *cacheEntry) matches(c *gin.Context) bool {
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == entry.etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil {
+			return !entry.modified.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// cacheable the process results which could be cached, the errors and the streams are not cached
+func cacheable(value interface{}) bool {
+	switch value.(type) {
+	case string, bool, int, int64, float64, map[string]interface{}, []interface{}, maps.Map, []maps.Map, []map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// ClearCache removes the cached results of the given routes, e.g. "GET /user/:id", or "/user/:id" for all methods.
+// Removes all the cached results if no route is given. Returns the number of the cleared paths.
+func ClearCache(routes ...string) int {
+	apisMu.RLock()
+	scopes := map[string]*responseCache{}
+	for _, api := range APIs {
+		for _, path := range api.HTTP.Paths {
+			if cache := api.HTTP.responseCache(path); cache != nil {
+				scopes[cache.scope] = cache
+			}
+		}
+	}
+	apisMu.RUnlock()
+
+	names := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		if len(routes) == 0 || matchRoute(scope, routes) {
+			names = append(names, scope)
+		}
+	}
+	sort.Strings(names)
+
+	for _, scope := range names {
+		cache := scopes[scope]
+		stor, err := cache.store()
+		if err != nil {
+			log.Error("[API] clear cache %s %s", scope, err.Error())
+			continue
+		}
+		if err := stor.Del(CachePrefix + scope + ":*"); err != nil {
+			log.Error("[API] clear cache %s %s", scope, err.Error())
+		}
+	}
+	return len(names)
+}
+
+// matchRoute the scope matches one of the routes
+func matchRoute(scope string, routes []string) bool {
+	for _, route := range routes {
+		route = strings.TrimSpace(route)
+		if strings.Contains(route, " ") {
+			parts := strings.SplitN(route, " ", 2)
+			route = strings.ToUpper(parts[0]) + " " + strings.TrimSpace(parts[1])
+			if scope == route {
+				return true
+			}
+			continue
+		}
+
+		if strings.SplitN(scope, " ", 2)[1] == route {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestCache(t *testing.T) {
+	router, calls := prepareCache(t)
+	defer cleanCache()
+
+	response := testRequest(router, "GET", "/api/cache/user?id=1", "", nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "MISS", response.Header().Get("X-Cache"))
+	etag := response.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, response.Header().Get("Last-Modified"))
+
+	// the result is cached
+	response = testRequest(router, "GET", "/api/cache/user?id=1", "", nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "HIT", response.Header().Get("X-Cache"))
+	assert.Equal(t, etag, response.Header().Get("ETag"))
+	assert.Equal(t, "1", responseMap(response).Get("id"))
+	assert.Equal(t, 1, *calls)
+
+	// conditional request
+	response = testRequest(router, "GET", "/api/cache/user?id=1", "", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, 304, response.Code)
+	assert.Empty(t, response.Body.String())
+
+	// the arguments and the varying headers are the part of the key
+	assert.Equal(t, "MISS", testRequest(router, "GET", "/api/cache/user?id=2", "", nil).Header().Get("X-Cache"))
+	assert.Equal(t, "MISS", testRequest(router, "GET", "/api/cache/user?id=1", "", map[string]string{"Accept-Language": "zh-CN"}).Header().Get("X-Cache"))
+	assert.Equal(t, 3, *calls)
+
+	// invalidation
+	res, err := process.New("api.ClearCache", "GET /cache/user").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, res)
+	assert.Equal(t, "MISS", testRequest(router, "GET", "/api/cache/user?id=1", "", nil).Header().Get("X-Cache"))
+	assert.Equal(t, 4, *calls)
+	assert.Equal(t, 0, ClearCache("/cache/unknown"))
+}
+
+func TestCacheGuarded(t *testing.T) {
+	process.Register("tests.cache.guarded", func(process *process.Process) interface{} {
+		return map[string]interface{}{"id": process.ArgsString(0)}
+	})
+	AddGuard("tests-cache-guard", func(c *gin.Context) {
+		c.Set("__user_id", c.GetHeader("X-User"))
+	})
+	defer func() {
+		ClearCache()
+		unloadTestAPIs("cache.guarded")
+		delete(HTTPGuards, "tests-cache-guard")
+	}()
+
+	router := loadTestAPIs(t, map[string]string{"cache.guarded": `{
+		"name": "Cache Guarded",
+		"version": "1.0.0",
+		"group": "cache-guarded",
+		"guard": "tests-cache-guard",
+		"paths": [
+			{"path": "/user", "method": "GET", "process": "tests.cache.guarded", "in": ["$query.id"], "out": {"status": 200, "type": "application/json"}, "cache": {"ttl": "1h"}},
+			{"path": "/shared", "method": "GET", "process": "tests.cache.guarded", "in": ["$query.id"], "out": {"status": 200, "type": "application/json"}, "cache": {"ttl": "1h", "user": false}}
+		]
+	}`})
+
+	// cached for each user by default
+	u1 := map[string]string{"X-User": "u1"}
+	u2 := map[string]string{"X-User": "u2"}
+	assert.Equal(t, "MISS", testRequest(router, "GET", "/api/cache-guarded/user?id=1", "", u1).Header().Get("X-Cache"))
+	assert.Equal(t, "HIT", testRequest(router, "GET", "/api/cache-guarded/user?id=1", "", u1).Header().Get("X-Cache"))
+	assert.Equal(t, "MISS", testRequest(router, "GET", "/api/cache-guarded/user?id=1", "", u2).Header().Get("X-Cache"))
+
+	// shared by the users if disabled explicitly
+	assert.Equal(t, "MISS", testRequest(router, "GET", "/api/cache-guarded/shared?id=1", "", u1).Header().Get("X-Cache"))
+	assert.Equal(t, "HIT", testRequest(router, "GET", "/api/cache-guarded/shared?id=1", "", u2).Header().Get("X-Cache"))
+}
+
+func TestCacheInvalid(t *testing.T) {
+	_, err := parseSource("/apis/cache.http.yao", []byte(`{
+		"name": "Cache",
+		"paths": [{"path": "/", "method": "GET", "process": "tests.cache.user", "cache": {"ttl": "soon"}}]
+	}`), "cache")
+	assert.Error(t, err)
+}
+
+func prepareCache(t *testing.T) (*gin.Engine, *int) {
+	calls := 0
+	process.Register("tests.cache.user", func(process *process.Process) interface{} {
+		calls++
+		return map[string]interface{}{"id": process.ArgsString(0)}
+	})
+
+	router := loadTestAPIs(t, map[string]string{"cache": `{
+		"name": "Cache",
+		"version": "1.0.0",
+		"group": "cache",
+		"guard": "-",
+		"paths": [{
+			"path": "/user", "method": "GET", "process": "tests.cache.user", "in": ["$query.id"], "out": {"status": 200, "type": "application/json"},
+			"cache": {"ttl": "1h", "headers": ["Accept-Language"]}
+		}]
+	}`})
+	return router, &calls
+}
+
+func cleanCache() {
+	ClearCache()
+	unloadTestAPIs("cache")
+}
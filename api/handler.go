@@ -25,6 +25,31 @@ import (
 // BuildHandler builds a gin.HandlerFunc for the given HTTP and Path configuration
 // This is the public API for building handlers dynamically
 func BuildHandler(http HTTP, path Path) gin.HandlerFunc {
+	handler := buildHandler(http, path)
+	middlewares := []gin.HandlerFunc{}
+	for _, middleware := range []gin.HandlerFunc{http.versionHandler(path), http.rateLimitHandler(path), path.validateHandler()} {
+		if middleware != nil {
+			middlewares = append(middlewares, middleware)
+		}
+	}
+
+	if len(middlewares) == 0 {
+		return handler
+	}
+
+	return func(c *gin.Context) {
+		for _, middleware := range middlewares {
+			middleware(c)
+			if c.IsAborted() {
+				return
+			}
+		}
+		handler(c)
+	}
+}
+
+// buildHandler creates the HTTP handler of the path without the version, the rate limit and the request validation
+func buildHandler(http HTTP, path Path) gin.HandlerFunc {
 	getArgs := http.parseIn(path.In)
 
 	if path.Out.Redirect != nil {
@@ -34,11 +59,11 @@ func BuildHandler(http HTTP, path Path) gin.HandlerFunc {
 	} else if strings.HasPrefix(path.Out.Type, "text/event-stream") {
 		return path.streamHandler(getArgs)
 	}
-	return path.defaultHandler(getArgs)
+	return path.defaultHandler(getArgs, http.responseCache(path))
 }
 
-// defaultHandler creates the default HTTP handler
-func (path Path) defaultHandler(getArgs argsHandler) func(c *gin.Context) {
+// defaultHandler creates the default HTTP handler, the process result is cached if the cache is given
+func (path Path) defaultHandler(getArgs argsHandler, cache *responseCache) func(c *gin.Context) {
 	return func(c *gin.Context) {
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -48,7 +73,11 @@ func (path Path) defaultHandler(getArgs argsHandler) func(c *gin.Context) {
 		var contentType = path.reqContentType(c) // Get the defined content type at API DSL
 
 		chRes := make(chan interface{}, 1)
-		go path.execProcess(ctx, chRes, c, getArgs)
+		if cache.enabled(c) {
+			go cache.exec(ctx, chRes, c, path, getArgs)
+		} else {
+			go path.execProcess(ctx, chRes, c, getArgs)
+		}
 
 		select {
 		case resp := <-chRes:
@@ -58,6 +87,13 @@ func (path Path) defaultHandler(getArgs argsHandler) func(c *gin.Context) {
 				return
 			}
 
+			// The conditional request matches the cached result
+			if _, ok := resp.(notModified); ok {
+				c.Status(304)
+				c.Done()
+				return
+			}
+
 			// Set Headers and renew Content-Type
 			contentType = path.setResponseHeaders(c, resp, contentType)
 
@@ -143,8 +143,8 @@ func IsAllowed(c *gin.Context, allowsMap map[string]bool) bool {
 // Routes 配置转换为路由
 func (http HTTP) Routes(router *gin.Engine, path string, allows ...string) {
 	var group gin.IRoutes = router
-	if http.Group != "" {
-		path = stdpath.Join(path, "/", http.Group)
+	if prefix := http.routeGroup(); prefix != "" {
+		path = stdpath.Join(path, "/", prefix)
 	}
 	group = router.Group(path)
 	for _, path := range http.Paths {
@@ -156,6 +156,11 @@ func (http HTTP) Routes(router *gin.Engine, path string, allows ...string) {
 
 // Route 路径配置转换为路由
 func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
+	http.method(path.Method, path.Path, router, http.handlers(router, path, allows...)...)
+}
+
+// handlers the handlers of the path, the CORS option route of the path is registered to the router
+func (http HTTP) handlers(router gin.IRoutes, path Path, allows ...string) []gin.HandlerFunc {
 	getArgs := http.parseIn(path.In)
 	handlers := []gin.HandlerFunc{}
 
@@ -190,9 +195,24 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 		})
 	}
 
+	// the version and deprecation headers, the removed paths response 410 before the guards
+	if version := http.versionHandler(path); version != nil {
+		handlers = append(handlers, version)
+	}
+
 	// set middlewares
 	http.guard(&handlers, path.Guard, http.Guard)
 
+	// limit the request rate, after the guards so the authorized information is available
+	if limit := http.rateLimitHandler(path); limit != nil {
+		handlers = append(handlers, limit)
+	}
+
+	// validate the request
+	if validate := path.validateHandler(); validate != nil {
+		handlers = append(handlers, validate)
+	}
+
 	// set http handler
 	if path.Out.Redirect != nil {
 		handlers = append(handlers, path.redirectHandler(getArgs))
@@ -204,10 +224,9 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 		handlers = append(handlers, path.streamHandler(getArgs))
 
 	} else {
-		handlers = append(handlers, path.defaultHandler(getArgs))
+		handlers = append(handlers, path.defaultHandler(getArgs, http.responseCache(path)))
 	}
-
-	http.method(path.Method, path.Path, router, handlers...)
+	return handlers
 }
 
 // 加载特定中间件
@@ -5,6 +5,8 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+
+	"github.com/gin-gonic/gin"
 )
 
 // RouteTable is a thread-safe route table for dynamic route lookup
@@ -20,6 +22,8 @@ type RouteEntry struct {
 	Params  []string       // Parameter names, e.g., ["id"]
 	API     *API           // Reference to the API definition
 	Path    *Path          // Reference to the Path definition
+	once    sync.Once
+	handler gin.HandlerFunc // the handler built on the first lookup
 }
 
 // Global route table instance
@@ -110,6 +114,15 @@ func buildFullPath(apiGroup, pathPattern string) string {
 	return stdpath.Join("/", apiGroup, pathPattern)
 }
 
+// buildHandler the handler of the entry, built once so the validation, the rate limit and the cache
+// are compiled once for the route table instead of on every request
+func (entry *RouteEntry) buildHandler() gin.HandlerFunc {
+	entry.once.Do(func() {
+		entry.handler = BuildHandler(entry.API.HTTP, *entry.Path)
+	})
+	return entry.handler
+}
+
 // addEntry adds a route entry to the route table
 func (rt *RouteTable) addEntry(method string, entry *RouteEntry) {
 	method = strings.ToUpper(method)
@@ -0,0 +1,556 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	stdpath "path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/doc"
+	"github.com/yaoapp/gou/model"
+)
+
+// OpenAPIPath the built-in route serving the OpenAPI document, relative to the API root path, e.g. /openapi.json.
+// Empty (default) to disable, the document lists every route, guard and bound process.
+var OpenAPIPath = ""
+
+// OpenAPIGuard the guards of the built-in OpenAPI route, comma separated like the API guard. Empty or "-" for none.
+var OpenAPIGuard = ""
+
+// OpenAPI the OpenAPI 3.1 document
+type OpenAPI struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    OpenAPIInfo                      `json:"info"`
+	Servers []OpenAPIServer                  `json:"servers,omitempty"`
+	Paths   map[string]map[string]*Operation `json:"paths"`
+	Tags    []OpenAPITag                     `json:"tags,omitempty"`
+}
+
+// OpenAPIInfo the document information
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIServer the server of the APIs
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPITag the tag of the operations, one for each API
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operation the OpenAPI operation of a path
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Process     string               `json:"x-yao-process,omitempty"`
+	Guard       string               `json:"x-yao-guard,omitempty"`
+	Versions    []string             `json:"x-yao-versions,omitempty"` // the versions sharing the route of a group versioned by header, the latest first
+}
+
+// Parameter the OpenAPI parameter
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"` // path, query, header
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+// RequestBody the OpenAPI request body
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response the OpenAPI response
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType the OpenAPI media type
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Schema the JSON Schema (draft 2020-12) of a value
+type Schema map[string]interface{}
+
+// OpenAPIOption the OpenAPI document option
+type OpenAPIOption struct {
+	Title       string `json:"title,omitempty"`       // default is "Yao API"
+	Version     string `json:"version,omitempty"`     // default is "1.0.0"
+	Description string `json:"description,omitempty"` //
+	Root        string `json:"root,omitempty"`        // the API root path, default is "/api"
+}
+
+var ginParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+// OpenAPIDocument generate the OpenAPI 3.1 document of the loaded APIs. The request and response
+// schemas are derived from the documentation of the bound processes, and from the model columns
+// for the models.* processes.
+func OpenAPIDocument(option OpenAPIOption) *OpenAPI {
+	if option.Title == "" {
+		option.Title = "Yao API"
+	}
+	if option.Version == "" {
+		option.Version = "1.0.0"
+	}
+	if option.Root == "" {
+		option.Root = "/api"
+	}
+
+	document := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    OpenAPIInfo{Title: option.Title, Version: option.Version, Description: option.Description},
+		Servers: []OpenAPIServer{{URL: option.Root}},
+		Paths:   map[string]map[string]*Operation{},
+		Tags:    []OpenAPITag{},
+	}
+
+	apisMu.RLock()
+	ids := make([]string, 0, len(APIs))
+	for id := range APIs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	apis := make([]*API, 0, len(ids))
+	for _, id := range ids {
+		apis = append(apis, APIs[id])
+	}
+	apisMu.RUnlock()
+
+	tags := map[string]bool{}
+	versions := map[*Operation]string{} // the version of the operations of the groups versioned by header
+	for _, api := range apis {
+		tag := api.HTTP.Name
+		if tag == "" {
+			tag = api.ID
+		}
+		if !tags[tag] {
+			tags[tag] = true
+			document.Tags = append(document.Tags, OpenAPITag{Name: tag, Description: api.HTTP.Description})
+		}
+
+		for _, path := range api.HTTP.Paths {
+			method := strings.ToLower(path.Method)
+			if method == "any" {
+				method = "post"
+			}
+
+			route := ginParam.ReplaceAllString(buildFullPath(api.HTTP.routeGroup(), path.Path), "{$1}")
+			if _, has := document.Paths[route]; !has {
+				document.Paths[route] = map[string]*Operation{}
+			}
+
+			operation := path.operation(api.HTTP, route)
+			operation.Tags = []string{tag}
+
+			// the versions share the route, the latest one is documented
+			if api.HTTP.Versioning == VersioningHeader {
+				operation.Versions = []string{api.HTTP.Version}
+				if existing, has := document.Paths[route][method]; has && versions[existing] != "" {
+					all := append(existing.Versions, api.HTTP.Version)
+					sort.SliceStable(all, func(i, j int) bool { return compareVersion(all[i], all[j]) > 0 })
+					if compareVersion(versions[existing], api.HTTP.Version) > 0 {
+						existing.Versions = all
+						continue
+					}
+					operation.Versions = all
+				}
+				versions[operation] = api.HTTP.Version
+			}
+			document.Paths[route][method] = operation
+		}
+	}
+	return document
+}
+
+// operation the OpenAPI operation of the path
+func (path Path) operation(api HTTP, route string) *Operation {
+	guard := path.Guard
+	if guard == "" {
+		guard = api.Guard
+	}
+	if guard == "-" {
+		guard = ""
+	}
+
+	operation := &Operation{
+		Summary:     path.Label,
+		Description: path.Description,
+		OperationID: operationID(path.Method, route),
+		Parameters:  []Parameter{},
+		Responses:   map[string]*Response{},
+		Deprecated:  api.deprecation(path) != nil,
+		Process:     path.Process,
+		Guard:       guard,
+	}
+
+	entry, _ := doc.Get(doc.TypeProcess, path.Process)
+	args := []doc.TypeValue{}
+	var ret *doc.TypeValue
+	if entry != nil {
+		args = entry.Args
+		ret = entry.Return
+	}
+	args, ret = modelSchemas(path.Process, args, ret)
+
+	// path parameters
+	params := map[string]int{}
+	for _, match := range ginParam.FindAllStringSubmatch(path.Path, -1) {
+		params[match[1]] = len(operation.Parameters)
+		operation.Parameters = append(operation.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: Schema{"type": "string"}})
+	}
+
+	body := Schema{"type": "object", "properties": map[string]interface{}{}}
+	form := Schema{"type": "object", "properties": map[string]interface{}{}}
+	var payload Schema
+	var raw bool
+	for i, in := range path.In {
+		name, ok := in.(string)
+		if !ok {
+			continue
+		}
+
+		arg := doc.TypeValue{Type: "string"}
+		if i < len(args) {
+			arg = args[i]
+		}
+
+		switch name {
+		case ":payload":
+			payload = schemaOf(arg)
+			continue
+		case ":body":
+			raw = true
+			continue
+		}
+
+		parts := strings.Split(name, ".")
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "$param":
+			if index, has := params[parts[1]]; has {
+				operation.Parameters[index].Schema = schemaOf(arg)
+				operation.Parameters[index].Description = arg.Desc
+			}
+		case "$query":
+			operation.Parameters = append(operation.Parameters, Parameter{Name: parts[1], In: "query", Description: arg.Desc, Required: arg.Required, Schema: schemaOf(arg)})
+		case "$header":
+			operation.Parameters = append(operation.Parameters, Parameter{Name: parts[1], In: "header", Description: arg.Desc, Required: arg.Required, Schema: schemaOf(arg)})
+		case "$payload":
+			body["properties"].(map[string]interface{})[parts[1]] = schemaOf(arg)
+		case "$form":
+			form["properties"].(map[string]interface{})[parts[1]] = schemaOf(arg)
+		case "$file":
+			form["properties"].(map[string]interface{})[parts[1]] = Schema{"type": "string", "contentMediaType": "application/octet-stream"}
+		}
+	}
+
+	// the declared validation schema takes precedence over the process documentation
+	if path.Validate != nil && path.Validate.Body != nil {
+		if schema, err := validateSchema(path.Validate.Body); err == nil {
+			payload = Schema(schema)
+		}
+	}
+
+	// request body
+	content := map[string]MediaType{}
+	if payload == nil && len(body["properties"].(map[string]interface{})) > 0 {
+		payload = body
+	}
+	if payload != nil {
+		content["application/json"] = MediaType{Schema: payload}
+	}
+	if len(form["properties"].(map[string]interface{})) > 0 {
+		content["multipart/form-data"] = MediaType{Schema: form}
+	}
+	if raw && len(content) == 0 {
+		content["text/plain"] = MediaType{Schema: Schema{"type": "string"}}
+	}
+	if len(content) > 0 {
+		operation.RequestBody = &RequestBody{Content: content}
+	}
+
+	// responses
+	status := path.Out.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if path.Out.Redirect != nil {
+		code := path.Out.Redirect.Code
+		if code == 0 {
+			code = http.StatusFound
+		}
+		operation.Responses[fmt.Sprintf("%d", code)] = &Response{Description: fmt.Sprintf("Redirect to %s", path.Out.Redirect.Location)}
+		return operation
+	}
+
+	contentType := path.Out.Type
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	response := &Response{Description: http.StatusText(status), Content: map[string]MediaType{}}
+	schema := Schema{}
+	if ret != nil && path.Out.Body == nil {
+		schema = schemaOf(*ret)
+		if ret.Desc != "" {
+			response.Description = ret.Desc
+		}
+	}
+
+	if ret != nil && (ret.Type == "void" || ret.Type == "null") && path.Out.Body == nil {
+		response.Content = nil
+	} else {
+		response.Content[contentType] = MediaType{Schema: schema}
+	}
+	operation.Responses[fmt.Sprintf("%d", status)] = response
+	if path.Validate != nil {
+		operation.Responses[fmt.Sprintf("%d", http.StatusUnprocessableEntity)] = &Response{Description: "Validation failed"}
+	}
+	return operation
+}
+
+// schemaOf convert the documented value type to a JSON Schema
+func schemaOf(value doc.TypeValue) Schema {
+	schema := typeSchema(value.Type)
+
+	if len(value.Variants) > 0 {
+		variants := []Schema{}
+		for _, variant := range value.Variants {
+			variants = append(variants, schemaOf(variant))
+		}
+		schema = Schema{"oneOf": variants}
+	}
+
+	if value.Items != nil {
+		schema = Schema{"type": "array", "items": schemaOf(*value.Items)}
+	}
+
+	if len(value.Fields) > 0 {
+		properties := map[string]interface{}{}
+		required := []string{}
+		for _, field := range value.Fields {
+			properties[field.Name] = schemaOf(field)
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		schema = Schema{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+
+	if value.Desc != "" {
+		schema["description"] = value.Desc
+	}
+	if value.Example != nil {
+		schema["examples"] = []interface{}{value.Example}
+	}
+	return schema
+}
+
+// typeSchema the JSON Schema of the documented type name, e.g. "string", "int", "array of strings", "QueryParam object"
+func typeSchema(typ string) Schema {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if strings.HasPrefix(typ, "array of ") {
+		item := strings.TrimSuffix(strings.TrimPrefix(typ, "array of "), "s")
+		return Schema{"type": "array", "items": typeSchema(item)}
+	}
+
+	switch {
+	case typ == "string":
+		return Schema{"type": "string"}
+	case typ == "int" || typ == "integer" || typ == "bigint":
+		return Schema{"type": "integer"}
+	case typ == "number" || typ == "float":
+		return Schema{"type": "number"}
+	case typ == "bool" || typ == "boolean":
+		return Schema{"type": "boolean"}
+	case typ == "null":
+		return Schema{"type": "null"}
+	case typ == "bytes":
+		return Schema{"type": "string", "contentEncoding": "base64"}
+	case typ == "array" || strings.HasSuffix(typ, " array") || strings.HasSuffix(typ, "[]"):
+		return Schema{"type": "array"}
+	case typ == "object" || strings.HasSuffix(typ, " object") || typ == "array of arrays":
+		return Schema{"type": "object"}
+	}
+	return Schema{}
+}
+
+// modelSchemas replace the generic row types of the models.* processes with the model columns
+func modelSchemas(name string, args []doc.TypeValue, ret *doc.TypeValue) ([]doc.TypeValue, *doc.TypeValue) {
+	parts := strings.Split(name, ".")
+	if len(parts) < 3 || strings.ToLower(parts[0]) != "models" {
+		return args, ret
+	}
+	method := strings.ToLower(parts[len(parts)-1])
+
+	mod, err := model.Get(strings.Join(parts[1:len(parts)-1], "."))
+	if err != nil {
+		return args, ret
+	}
+
+	row := modelRow(mod)
+	rows := doc.TypeValue{Type: "array", Items: &row}
+	replaced := make([]doc.TypeValue, len(args))
+	for i, arg := range args {
+		replaced[i] = arg
+		switch arg.Name {
+		case "row":
+			replaced[i] = withDesc(row, arg)
+		case "rows":
+			if method != "insert" {
+				replaced[i] = withDesc(rows, arg)
+			}
+		}
+	}
+
+	switch method {
+	case "find":
+		ret = &row
+	case "get":
+		ret = &rows
+	case "paginate":
+		ret = &doc.TypeValue{Type: "object", Fields: []doc.TypeValue{
+			{Name: "data", Type: "array", Items: &row},
+			{Name: "total", Type: "int"},
+			{Name: "page", Type: "int"},
+			{Name: "pagesize", Type: "int"},
+			{Name: "pagecnt", Type: "int"},
+			{Name: "next", Type: "int"},
+			{Name: "prev", Type: "int"},
+		}}
+	}
+	return replaced, ret
+}
+
+// modelRow the row type of the model
+func modelRow(mod *model.Model) doc.TypeValue {
+	row := doc.TypeValue{Type: "object", Name: mod.ID, Desc: mod.MetaData.Name, Fields: []doc.TypeValue{}}
+	for _, column := range mod.MetaData.Columns {
+		desc := column.Label
+		if column.Description != "" {
+			desc = column.Description
+		}
+		row.Fields = append(row.Fields, doc.TypeValue{
+			Name:    column.Name,
+			Type:    columnType(column.Type),
+			Desc:    desc,
+			Example: column.Example,
+		})
+	}
+	return row
+}
+
+// columnType the documented type of the model column type
+func columnType(typ string) string {
+	typ = strings.ToLower(typ)
+	switch {
+	case strings.Contains(typ, "increments") || strings.Contains(typ, "integer") || typ == "year":
+		return "int"
+	case typ == "float" || typ == "double" || strings.Contains(typ, "decimal"):
+		return "number"
+	case typ == "boolean":
+		return "bool"
+	case typ == "json" || typ == "jsonb":
+		return "any"
+	}
+	return "string"
+}
+
+func withDesc(value doc.TypeValue, arg doc.TypeValue) doc.TypeValue {
+	value.Name = arg.Name
+	value.Required = arg.Required
+	if arg.Desc != "" {
+		value.Desc = arg.Desc
+	}
+	return value
+}
+
+// operationID the operation id of the route, e.g. GET /user/{id} -> get_user_id
+func operationID(method string, route string) string {
+	id := strings.ToLower(method) + "_" + strings.Trim(route, "/")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.NewReplacer("{", "", "}", "").Replace(id))
+}
+
+// registerEndpoints registers the paths of the API to the doc registry as openapi entries, named "METHOD /full/path"
+func registerEndpoints(api *API) {
+	for _, path := range api.HTTP.Paths {
+		guard := path.Guard
+		if guard == "" {
+			guard = api.HTTP.Guard
+		}
+
+		method := strings.ToUpper(path.Method)
+		full := buildFullPath(api.HTTP.routeGroup(), path.Path)
+		desc := path.Label
+		if desc == "" {
+			desc = path.Description
+		}
+
+		doc.Register(&doc.Entry{
+			Name:     method + " " + full,
+			Type:     doc.TypeOpenAPI,
+			Group:    api.ID,
+			Desc:     desc,
+			Endpoint: &doc.Endpoint{Method: method, Path: full, Guard: guard, Process: path.Process},
+		})
+	}
+}
+
+// removeEndpoints removes the paths of the API from the doc registry
+func removeEndpoints(api *API) {
+	for _, path := range api.HTTP.Paths {
+		doc.Unregister(doc.TypeOpenAPI, strings.ToUpper(path.Method)+" "+buildFullPath(api.HTTP.routeGroup(), path.Path))
+	}
+}
+
+// openAPIHandler serves the OpenAPI document
+func openAPIHandler(root string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, OpenAPIDocument(OpenAPIOption{Root: root}))
+	}
+}
+
+// setOpenAPIRoute registers the built-in OpenAPI route behind the OpenAPI guards, if the route is enabled
+func setOpenAPIRoute(router *gin.Engine, root string) {
+	if OpenAPIPath == "" {
+		return
+	}
+
+	handlers := []gin.HandlerFunc{}
+	if OpenAPIGuard != "" {
+		HTTP{}.guard(&handlers, OpenAPIGuard, "")
+	}
+	handlers = append(handlers, openAPIHandler(root))
+	router.GET(stdpath.Join(root, OpenAPIPath), handlers...)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/doc"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	prepareOpenAPI(t)
+	defer cleanOpenAPI()
+
+	document := OpenAPIDocument(OpenAPIOption{Title: "Test API"})
+	assert.Equal(t, "3.1.0", document.OpenAPI)
+	assert.Equal(t, "Test API", document.Info.Title)
+	assert.Equal(t, "/api", document.Servers[0].URL)
+
+	// path parameters and request body from the process documentation
+	operation := document.Paths["/openapi/user/{id}"]["post"]
+	if !assert.NotNil(t, operation) {
+		return
+	}
+	assert.Equal(t, "post_openapi_user_id", operation.OperationID)
+	assert.Equal(t, "Update user", operation.Summary)
+	assert.Equal(t, "tests.openapi.update", operation.Process)
+	assert.Equal(t, "bearer-jwt", operation.Guard)
+	assert.Equal(t, []string{"OpenAPI"}, operation.Tags)
+	assert.Equal(t, "id", operation.Parameters[0].Name)
+	assert.Equal(t, "path", operation.Parameters[0].In)
+	assert.Equal(t, "integer", operation.Parameters[0].Schema["type"])
+
+	body := operation.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, "object", body["type"])
+	assert.Equal(t, []string{"name"}, body["required"])
+	assert.Equal(t, Schema{"type": "string", "description": "The user name"}, body["properties"].(map[string]interface{})["name"])
+
+	response := operation.Responses["200"]
+	assert.Equal(t, "The user", response.Description)
+	assert.Equal(t, "object", response.Content["application/json"].Schema["type"])
+
+	// query parameters of an undocumented process
+	operation = document.Paths["/openapi/search"]["get"]
+	if !assert.NotNil(t, operation) {
+		return
+	}
+	assert.Equal(t, "keyword", operation.Parameters[0].Name)
+	assert.Equal(t, "query", operation.Parameters[0].In)
+	assert.Equal(t, "string", operation.Parameters[0].Schema["type"])
+	assert.Nil(t, operation.RequestBody)
+	assert.Equal(t, "", operation.Guard)
+
+	// the endpoints are registered to the doc registry
+	entry, has := doc.Get(doc.TypeOpenAPI, "POST /openapi/user/:id")
+	assert.True(t, has)
+	assert.Equal(t, "tests.openapi.update", entry.Endpoint.Process)
+
+	// process
+	res, err := process.New("api.OpenAPI", map[string]interface{}{"version": "2.0.0"}).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2.0.0", res.(*OpenAPI).Info.Version)
+}
+
+func TestOpenAPIVersions(t *testing.T) {
+	prepareVersion(t)
+	defer cleanVersion()
+
+	// the older version sorts last by the id
+	loadTestAPIs(t, map[string]string{"version.z": `{
+		"name": "Version", "version": "0.9.0", "versioning": "header", "group": "version", "guard": "-",
+		"paths": [{"path": "/ping", "method": "GET", "process": "tests.version.v1", "out": {"status": 200, "type": "text/plain"}}]
+	}`})
+	defer unloadTestAPIs("version.z")
+
+	document := OpenAPIDocument(OpenAPIOption{})
+	operation := document.Paths["/version/ping"]["get"]
+	if !assert.NotNil(t, operation) {
+		return
+	}
+	assert.Equal(t, "tests.version.v2", operation.Process)
+	assert.Equal(t, []string{"2.0.0", "1.0.0", "0.9.0"}, operation.Versions)
+
+	operation = document.Paths["/version/legacy"]["get"]
+	if !assert.NotNil(t, operation) {
+		return
+	}
+	assert.Equal(t, "tests.version.v1", operation.Process)
+	assert.Equal(t, []string{"1.0.0"}, operation.Versions)
+	assert.Empty(t, document.Paths["/v3/version-path/ping"]["get"].Versions)
+
+	// the versions share the tag
+	tags := 0
+	for _, tag := range document.Tags {
+		if tag.Name == "Version" {
+			tags++
+		}
+	}
+	assert.Equal(t, 1, tags)
+}
+
+func TestOpenAPIRoute(t *testing.T) {
+	prepareOpenAPI(t)
+	defer cleanOpenAPI()
+
+	// disabled by default
+	router := gin.New()
+	SetRoutes(router, "/api")
+
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/openapi.json", nil)
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 404, response.Code)
+
+	// enabled behind a guard
+	OpenAPIPath = "/openapi.json"
+	OpenAPIGuard = "tests-openapi-guard"
+	AddGuard("tests-openapi-guard", func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	})
+	defer func() {
+		OpenAPIPath = ""
+		OpenAPIGuard = ""
+		delete(HTTPGuards, "tests-openapi-guard")
+	}()
+
+	router = gin.New()
+	SetRoutes(router, "/api")
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/openapi.json", nil)
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 403, response.Code)
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/openapi.json", nil)
+	req.Header.Set("Authorization", "Bearer test")
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 200, response.Code)
+
+	res := responseMap(response)
+	assert.Equal(t, "3.1.0", res.Get("openapi"))
+	paths, ok := res.Get("paths").(map[string]interface{})
+	if !ok {
+		t.Fatal("the paths of the document is not a map")
+	}
+	assert.Contains(t, paths, "/openapi/search")
+}
+
+func prepareOpenAPI(t *testing.T) {
+	doc.Register(&doc.Entry{
+		Name:  "tests.openapi.update",
+		Type:  doc.TypeProcess,
+		Group: "tests",
+		Args: []doc.TypeValue{
+			{Name: "id", Type: "int", Desc: "The user id", Required: true},
+			{Name: "data", Type: "object", Fields: []doc.TypeValue{
+				{Name: "name", Type: "string", Desc: "The user name", Required: true},
+				{Name: "age", Type: "int"},
+			}},
+		},
+		Return: &doc.TypeValue{Type: "object", Desc: "The user"},
+	})
+
+	loadTestAPIs(t, map[string]string{"openapi": `{
+		"name": "OpenAPI",
+		"version": "1.0.0",
+		"group": "openapi",
+		"guard": "bearer-jwt",
+		"paths": [
+			{"label": "Update user", "path": "/user/:id", "method": "POST", "process": "tests.openapi.update", "in": ["$param.id", ":payload"], "out": {"status": 200, "type": "application/json"}},
+			{"path": "/search", "method": "GET", "guard": "-", "process": "tests.openapi.search", "in": ["$query.keyword"], "out": {"status": 200}}
+		]
+	}`})
+}
+
+func cleanOpenAPI() {
+	unloadTestAPIs("openapi")
+	doc.Unregister(doc.TypeProcess, "tests.openapi.update")
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
 )
 
 func init() {
 	process.RegisterGroup("api", map[string]process.Handler{
-		"list":   processList,
-		"reload": processReload,
+		"list":       processList,
+		"reload":     processReload,
+		"openapi":    processOpenAPI,
+		"clearcache": processClearCache,
+		"versions":   processVersions,
 	})
 }
 
@@ -63,3 +67,51 @@ func processReload(process *process.Process) interface{} {
 		"count":   count,
 	}
 }
+
+// processOpenAPI generates the OpenAPI 3.1 document of the loaded APIs
+// Process: api.OpenAPI, api.OpenAPI {"title": "My API", "version": "1.0.0", "root": "/api"}
+func processOpenAPI(process *process.Process) interface{} {
+	option := OpenAPIOption{}
+	if process.NumOfArgs() > 0 {
+		bytes, err := jsoniter.Marshal(process.Args[0])
+		if err != nil {
+			exception.New("%s", 400, err.Error()).Throw()
+		}
+
+		err = jsoniter.Unmarshal(bytes, &option)
+		if err != nil {
+			exception.New("the option is invalid: %s", 400, err.Error()).Throw()
+		}
+	}
+	return OpenAPIDocument(option)
+}
+
+// processClearCache removes the cached results of the given routes, or all the cached results
+// Process: api.ClearCache, api.ClearCache "GET /user/:id", api.ClearCache ["/user/:id", "/user/search"]
+func processClearCache(process *process.Process) interface{} {
+	routes := []string{}
+	for i, arg := range process.Args {
+		switch value := arg.(type) {
+		case string:
+			routes = append(routes, value)
+		case []interface{}, []string:
+			routes = append(routes, process.ArgsStrings(i)...)
+		}
+	}
+	return ClearCache(routes...)
+}
+
+// processVersions lists the active versions of the given groups, or all the groups
+// Process: api.Versions, api.Versions "user", api.Versions ["user", "order"]
+func processVersions(process *process.Process) interface{} {
+	groups := []string{}
+	for i, arg := range process.Args {
+		switch value := arg.(type) {
+		case string:
+			groups = append(groups, value)
+		case []interface{}, []string:
+			groups = append(groups, process.ArgsStrings(i)...)
+		}
+	}
+	return Versions(groups...)
+}
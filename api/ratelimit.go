@@ -0,0 +1,449 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/gou/store/lru"
+	"github.com/yaoapp/kun/log"
+)
+
+// RateLimitPrefix the key prefix of the rate limit counters in the store
+var RateLimitPrefix = "yao:api:ratelimit:"
+
+// RateLimit the rate limit of a path or a group.
+// The path rate limit takes precedence over the group one, a limit less than 1 disables the rate limit of the path.
+type RateLimit struct {
+	Algorithm string `json:"algorithm,omitempty"` // sliding-window (default), token-bucket
+	Limit     int    `json:"limit"`               // the max requests in the window, the bucket capacity of the token bucket
+	Window    string `json:"window,omitempty"`    // the window duration, e.g. 1s, 1m, 1h, default is 1m. The bucket is refilled in a window.
+	Key       string `json:"key,omitempty"`       // ip (default), user, client, tenant or header:<name>. Fallback to ip if the identity is missing
+	Store     string `json:"store,omitempty"`     // the store of the counters, so the limits hold across instances (exact with the redis store). The counters are kept in memory if empty
+	Message   string `json:"message,omitempty"`   // the message of the 429 response
+}
+
+// rateLimiter the compiled rate limit
+type rateLimiter struct {
+	RateLimit
+	scope  string
+	window time.Duration
+}
+
+// rateLimitStatus the status of a request against the rate limit
+type rateLimitStatus struct {
+	allowed   bool
+	remaining int
+	reset     time.Duration // the duration until the quota is fully available again
+	retry     time.Duration // the duration until the next request is allowed
+}
+
+// scriptStore the store running the Lua scripts atomically, e.g. the redis store
+type scriptStore interface {
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+var (
+	memoryStores   = map[string]store.Store{}
+	memoryStoresMu sync.Mutex
+	rateLimitLocks [256]sync.Mutex // the local locks of the keys, guards the token buckets and the window counters of the stores without scripts
+)
+
+// counterScript creates the window counter with the TTL if not exists
+const counterScript = `
+redis.call('SET', KEYS[1], 0, 'NX', 'PX', ARGV[1])
+return 1
+`
+
+// tokenBucketScript takes a token from the bucket, returns {allowed, tokens}
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tokens = capacity
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+if bucket[1] and bucket[2] then
+	tokens = math.min(capacity, tonumber(bucket[1]) + math.max(0, now - tonumber(bucket[2])) * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`
+
+// RateLimitGuard returns a guard limiting the requests with the given rate limit, the scope names the counters,
+// the guards with the same scope share the counters. e.g. AddGuard("api-quota", RateLimitGuard("quota", RateLimit{Limit: 1000, Window: "1h", Key: "user"}))
+func RateLimitGuard(scope string, limit RateLimit) gin.HandlerFunc {
+	limiter, err := limit.compile(scope)
+	if err != nil {
+		log.Error("[API] rate limit %s %s", scope, err.Error())
+		return func(c *gin.Context) {}
+	}
+	return limiter.handler
+}
+
+// compile validates the rate limit
+func (limit RateLimit) compile(scope string) (*rateLimiter, error) {
+	if limit.Algorithm == "" {
+		limit.Algorithm = "sliding-window"
+	}
+	if limit.Algorithm != "sliding-window" && limit.Algorithm != "token-bucket" {
+		return nil, fmt.Errorf("ratelimit.algorithm %s is not supported, should be sliding-window or token-bucket", limit.Algorithm)
+	}
+
+	if limit.Window == "" {
+		limit.Window = "1m"
+	}
+	window, err := time.ParseDuration(limit.Window)
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("ratelimit.window %s is not a valid duration", limit.Window)
+	}
+
+	if limit.Key == "" {
+		limit.Key = "ip"
+	}
+	switch {
+	case limit.Key == "ip", limit.Key == "user", limit.Key == "client", limit.Key == "tenant":
+	case strings.HasPrefix(limit.Key, "header:") && len(limit.Key) > len("header:"):
+	default:
+		return nil, fmt.Errorf("ratelimit.key %s is not supported, should be ip, user, client, tenant or header:<name>", limit.Key)
+	}
+
+	return &rateLimiter{RateLimit: limit, scope: scope, window: window}, nil
+}
+
+// rateLimitHandler the rate limit handler of the path, nil if the path is not limited
+func (http HTTP) rateLimitHandler(path Path) gin.HandlerFunc {
+	limit := http.RateLimit
+	scope := "group:" + http.routeGroup()
+	if path.RateLimit != nil {
+		limit = path.RateLimit
+		scope = fmt.Sprintf("%s %s", path.Method, buildFullPath(http.routeGroup(), path.Path))
+	}
+
+	if limit == nil || limit.Limit < 1 {
+		return nil
+	}
+
+	limiter, err := limit.compile(scope)
+	if err != nil {
+		log.Error("[Path] %s %s %s", path.Method, path.Path, err.Error())
+		return nil
+	}
+	return limiter.handler
+}
+
+// handler checks the request against the rate limit, responses 429 if the limit is exceeded
+func (limiter *rateLimiter) handler(c *gin.Context) {
+	stor, err := limiter.store()
+	if err != nil {
+		// Let the request pass, the API should not be unavailable because of the counters
+		log.Error("[API] rate limit %s %s", limiter.scope, err.Error())
+		return
+	}
+
+	key := RateLimitPrefix + limiter.scope + ":" + limiter.identity(c)
+	var status rateLimitStatus
+	if limiter.Algorithm == "token-bucket" {
+		status, err = limiter.tokenBucket(stor, key, time.Now())
+	} else {
+		status, err = limiter.slidingWindow(stor, key, time.Now())
+	}
+	if err != nil {
+		log.Error("[API] rate limit %s %s", limiter.scope, err.Error())
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(limiter.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(status.remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(seconds(status.reset)))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limiter.Limit, seconds(limiter.window)))
+	if status.allowed {
+		return
+	}
+
+	message := limiter.Message
+	if message == "" {
+		message = "too many requests"
+	}
+	header.Set("Retry-After", strconv.Itoa(seconds(status.retry)))
+	c.JSON(http.StatusTooManyRequests, gin.H{"code": http.StatusTooManyRequests, "message": message})
+	c.Abort()
+}
+
+// slidingWindow the sliding window counter, the count is the weighted sum of the previous and the current fixed windows.
+// The request is counted first and decided with the returned count, so the concurrent requests never pass the limit together.
+func (limiter *rateLimiter) slidingWindow(stor store.Store, key string, now time.Time) (rateLimitStatus, error) {
+	window := int64(limiter.window)
+	index := now.UnixNano() / window
+	elapsed := float64(now.UnixNano()%window) / float64(window)
+
+	previous := int64(0)
+	if value, ok := stor.Get(fmt.Sprintf("%s:%d", key, index-1)); ok {
+		previous = toInt(value)
+	}
+
+	// the counters are created with the TTL before counting, the next one ahead of its window,
+	// so a counter being incremented is never reset by another request, or by another instance with the redis store.
+	currentKey := fmt.Sprintf("%s:%d", key, index)
+	if err := limiter.counter(stor, currentKey); err != nil {
+		return rateLimitStatus{}, err
+	}
+	if err := limiter.counter(stor, fmt.Sprintf("%s:%d", key, index+1)); err != nil {
+		return rateLimitStatus{}, err
+	}
+
+	count, err := stor.Incr(currentKey, 1)
+	if err != nil {
+		return rateLimitStatus{}, err
+	}
+
+	weight := int(math.Floor(float64(previous) * (1 - elapsed)))
+	if weight+int(count) > limiter.Limit {
+		// the rejected request is not counted
+		if _, err := stor.Decr(currentKey, 1); err != nil {
+			return rateLimitStatus{}, err
+		}
+		current := count - 1
+		return rateLimitStatus{allowed: false, remaining: 0, reset: limiter.reset(current, elapsed), retry: limiter.retry(previous, current, elapsed)}, nil
+	}
+
+	remaining := limiter.Limit - weight - int(count)
+	return rateLimitStatus{allowed: true, remaining: remaining, reset: limiter.reset(count, elapsed)}, nil
+}
+
+// counter creates the window counter with the TTL if not exists, the counter expires after the next window.
+// The counter is created atomically by the stores running scripts, the other stores are exact on a single instance only.
+func (limiter *rateLimiter) counter(stor store.Store, key string) error {
+	if scripts, ok := stor.(scriptStore); ok {
+		_, err := scripts.Eval(counterScript, []string{key}, milliseconds(3*limiter.window))
+		return err
+	}
+
+	if stor.Has(key) {
+		return nil
+	}
+
+	mu := keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+	if !stor.Has(key) {
+		return stor.Set(key, int64(0), 3*limiter.window)
+	}
+	return nil
+}
+
+// reset the duration until the requests of the current window are out of the sliding window
+func (limiter *rateLimiter) reset(current int64, elapsed float64) time.Duration {
+	reset := time.Duration((1 - elapsed) * float64(limiter.window))
+	if current > 0 {
+		reset += limiter.window
+	}
+	return reset
+}
+
+// retry the duration until the weighted count of the sliding window is less than the limit
+func (limiter *rateLimiter) retry(previous, current int64, elapsed float64) time.Duration {
+	window := float64(limiter.window)
+	if previous > 0 && int(current) < limiter.Limit {
+		// previous * (1 - t) + current < limit
+		t := 1 - float64(int64(limiter.Limit)-current)/float64(previous)
+		if t > elapsed && t <= 1 {
+			return time.Duration((t - elapsed) * window)
+		}
+	}
+
+	// the current window becomes the previous one
+	next := time.Duration((1 - elapsed) * window)
+	if current > 0 {
+		t := 1 - float64(limiter.Limit)/float64(current)
+		if t > 0 {
+			next += time.Duration(t * window)
+		}
+	}
+	return next
+}
+
+// tokenBucket the token bucket, the bucket holds Limit tokens and is refilled in a window
+func (limiter *rateLimiter) tokenBucket(stor store.Store, key string, now time.Time) (rateLimitStatus, error) {
+	capacity := float64(limiter.Limit)
+	rate := capacity / float64(limiter.window) // tokens per nanosecond
+
+	var allowed bool
+	var tokens float64
+	var err error
+	if scripts, ok := stor.(scriptStore); ok {
+		allowed, tokens, err = limiter.takeScript(scripts, key, now)
+	} else {
+		allowed, tokens, err = limiter.take(stor, key, now)
+	}
+	if err != nil {
+		return rateLimitStatus{}, err
+	}
+
+	status := rateLimitStatus{allowed: allowed, remaining: int(math.Floor(tokens))}
+	if !allowed {
+		status.retry = time.Duration((1 - tokens) / rate)
+	}
+	status.reset = time.Duration((capacity - tokens) / rate)
+	return status, nil
+}
+
+// take takes a token from the bucket under the local lock of the key, it is exact on a single instance only
+func (limiter *rateLimiter) take(stor store.Store, key string, now time.Time) (bool, float64, error) {
+	mu := keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	capacity := float64(limiter.Limit)
+	rate := capacity / float64(limiter.window)
+	tokens := capacity
+	if value, ok := stor.Get(key); ok {
+		if bucket, ok := value.(map[string]interface{}); ok {
+			updated := toInt(bucket["updated"])
+			tokens = math.Min(capacity, toFloat(bucket["tokens"])+float64(now.UnixNano()-updated)*rate)
+		}
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	bucket := map[string]interface{}{"tokens": tokens, "updated": now.UnixNano()}
+	if err := stor.Set(key, bucket, limiter.window); err != nil {
+		return false, 0, err
+	}
+	return allowed, tokens, nil
+}
+
+// takeScript takes a token from the bucket atomically by the script, the time is in milliseconds
+func (limiter *rateLimiter) takeScript(stor scriptStore, key string, now time.Time) (bool, float64, error) {
+	rate := float64(limiter.Limit) / (float64(limiter.window) / float64(time.Millisecond)) // tokens per millisecond
+	res, err := stor.Eval(tokenBucketScript, []string{key}, limiter.Limit, rate, now.UnixMilli(), milliseconds(limiter.window))
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("the token bucket script returns %v", res)
+	}
+
+	tokens, err := strconv.ParseFloat(fmt.Sprintf("%v", values[1]), 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("the token bucket script returns %v", res)
+	}
+	return toInt(values[0]) == 1, tokens, nil
+}
+
+// keyLock the local lock of the key
+func keyLock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &rateLimitLocks[h.Sum32()%uint32(len(rateLimitLocks))]
+}
+
+// identity the identity of the requester
+func (limiter *rateLimiter) identity(c *gin.Context) string {
+	var id string
+	switch {
+	case limiter.Key == "ip":
+		return "ip:" + c.ClientIP()
+
+	case strings.HasPrefix(limiter.Key, "header:"):
+		id = c.GetHeader(strings.TrimPrefix(limiter.Key, "header:"))
+
+	default:
+		authorized := getAuthorizedInfo(c)
+		field := map[string]string{"user": "user_id", "client": "client_id", "tenant": "tenant_id"}[limiter.Key]
+		if authorized != nil && authorized[field] != nil {
+			id = fmt.Sprintf("%v", authorized[field])
+		}
+	}
+
+	if id == "" {
+		return "ip:" + c.ClientIP()
+	}
+	return limiter.Key + ":" + id
+}
+
+// store the store of the counters
+func (limiter *rateLimiter) store() (store.Store, error) {
+	if limiter.Store != "" {
+		return store.Get(limiter.Store)
+	}
+
+	return memoryStore("ratelimit")
+}
+
+// memoryStore the in-memory store of the given usage, used when no store is configured
+func memoryStore(name string) (store.Store, error) {
+	memoryStoresMu.Lock()
+	defer memoryStoresMu.Unlock()
+	if stor, has := memoryStores[name]; has {
+		return stor, nil
+	}
+
+	cache, err := lru.New(10240)
+	if err != nil {
+		return nil, fmt.Errorf("the memory store is not available: %s", err.Error())
+	}
+	memoryStores[name] = cache
+	return cache, nil
+}
+
+// seconds the duration in seconds, rounded up
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// milliseconds the duration in milliseconds, rounded up
+func milliseconds(d time.Duration) int64 {
+	return int64(math.Ceil(float64(d) / float64(time.Millisecond)))
+}
+
+func toInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/store"
+)
+
+func TestRateLimit(t *testing.T) {
+	router := prepareRateLimit(t)
+	defer cleanRateLimit()
+
+	// path rate limit, sliding window
+	for i := 0; i < 2; i++ {
+		response := testRequest(router, "GET", "/api/ratelimit/path", "", nil)
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, []string{"1", "0"}[i], response.Header().Get("RateLimit-Remaining"))
+	}
+
+	response := testRequest(router, "GET", "/api/ratelimit/path", "", nil)
+	assert.Equal(t, 429, response.Code)
+	assert.Equal(t, "slow down", responseMap(response).Get("message"))
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+
+	// the counters are kept for each user
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/ratelimit/path", "", map[string]string{"X-User": "u1"}).Code)
+
+	// group rate limit, token bucket
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/ratelimit/group", "", nil).Code)
+	response = testRequest(router, "GET", "/api/ratelimit/group", "", nil)
+	assert.Equal(t, 429, response.Code)
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+
+	// disabled on the path
+	for i := 0; i < 3; i++ {
+		response = testRequest(router, "GET", "/api/ratelimit/free", "", nil)
+		assert.Equal(t, 200, response.Code)
+		assert.Empty(t, response.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitConcurrent(t *testing.T) {
+	defer cleanRateLimit()
+	router := gin.New()
+	router.GET("/concurrent", RateLimitGuard("unit-test-concurrent", RateLimit{Limit: 10, Window: "1h"}), func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+
+	assert.Equal(t, int64(10), concurrentAllowed(router, "/concurrent", 50))
+}
+
+func TestRateLimitRedis(t *testing.T) {
+	stor := prepareRateLimitRedis(t)
+	defer stor.Del(RateLimitPrefix + "*")
+
+	// the counters and the buckets are updated atomically by the scripts
+	for _, algorithm := range []string{"sliding-window", "token-bucket"} {
+		router := gin.New()
+		limit := RateLimit{Algorithm: algorithm, Limit: 10, Window: "1h", Store: "unit-test-ratelimit"}
+		router.GET("/redis", RateLimitGuard("unit-test-redis-"+algorithm, limit), func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		assert.Equal(t, int64(10), concurrentAllowed(router, "/redis", 50), algorithm)
+
+		response := testRequest(router, "GET", "/redis", "", nil)
+		assert.Equal(t, 429, response.Code, algorithm)
+		assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"), algorithm)
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	_, err := parseSource("/apis/ratelimit.http.yao", []byte(`{
+		"name": "RateLimit",
+		"ratelimit": {"limit": 10, "window": "forever"},
+		"paths": [{"path": "/", "method": "GET", "process": "tests.ratelimit.echo"}]
+	}`), "ratelimit")
+	assert.Error(t, err)
+}
+
+func prepareRateLimit(t *testing.T) *gin.Engine {
+	process.Register("tests.ratelimit.echo", func(process *process.Process) interface{} {
+		return "ok"
+	})
+
+	return loadTestAPIs(t, map[string]string{"ratelimit": `{
+		"name": "RateLimit",
+		"version": "1.0.0",
+		"group": "ratelimit",
+		"guard": "-",
+		"ratelimit": {"algorithm": "token-bucket", "limit": 1, "window": "1h"},
+		"paths": [
+			{"path": "/path", "method": "GET", "process": "tests.ratelimit.echo", "out": {"status": 200}, "ratelimit": {"limit": 2, "window": "1h", "key": "user", "message": "slow down"}},
+			{"path": "/group", "method": "GET", "process": "tests.ratelimit.echo", "out": {"status": 200}},
+			{"path": "/free", "method": "GET", "process": "tests.ratelimit.echo", "out": {"status": 200}, "ratelimit": {"limit": 0}}
+		]
+	}`}, func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("__user_id", user)
+		}
+	})
+}
+
+func prepareRateLimitRedis(t *testing.T) store.Store {
+	app, err := application.OpenFromDisk(os.Getenv("GOU_TEST_APPLICATION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	application.Load(app)
+
+	_, err = connector.Load(filepath.Join("connectors", "redis.conn.yao"), "redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stor, err := store.LoadSource([]byte(`{"name": "RateLimit", "connector": "redis"}`), "unit-test-ratelimit", "/stores/unit-test-ratelimit.redis.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stor.Del(RateLimitPrefix + "*")
+	return stor
+}
+
+// concurrentAllowed sends the requests concurrently, returns the number of the allowed requests
+func concurrentAllowed(router *gin.Engine, url string, n int) int64 {
+	var allowed int64
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if testRequest(router, "GET", url, "", nil).Code == 200 {
+				atomic.AddInt64(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	return allowed
+}
+
+func cleanRateLimit() {
+	unloadTestAPIs("ratelimit")
+	if stor, err := memoryStore("ratelimit"); err == nil {
+		stor.Del(RateLimitPrefix + "*")
+	}
+}
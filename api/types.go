@@ -16,12 +16,15 @@ type API struct {
 
 // HTTP http 协议服务
 type HTTP struct {
-	Name        string `json:"name"`
-	Version     string `json:"version"`
-	Description string `json:"description,omitempty"`
-	Group       string `json:"group,omitempty"`
-	Guard       string `json:"guard,omitempty"`
-	Paths       []Path `json:"paths,omitempty"`
+	Name        string       `json:"name"`
+	Version     string       `json:"version"`
+	Versioning  string       `json:"versioning,omitempty"` // path or header, the version is informational only if empty
+	Description string       `json:"description,omitempty"`
+	Group       string       `json:"group,omitempty"`
+	Guard       string       `json:"guard,omitempty"`
+	RateLimit   *RateLimit   `json:"ratelimit,omitempty"`
+	Deprecated  *Deprecation `json:"deprecated,omitempty"`
+	Paths       []Path       `json:"paths,omitempty"`
 }
 
 // Path HTTP Path
@@ -34,6 +37,10 @@ type Path struct {
 	Guard          string        `json:"guard,omitempty"`
 	In             []interface{} `json:"in,omitempty"`
 	Out            Out           `json:"out,omitempty"`
+	Validate       *Validate     `json:"validate,omitempty"`
+	RateLimit      *RateLimit    `json:"ratelimit,omitempty"`
+	Cache          *Cache        `json:"cache,omitempty"`
+	Deprecated     *Deprecation  `json:"deprecated,omitempty"`
 	ProcessHandler bool          `json:"processHandler,omitempty"`
 }
 
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kaptinlin/jsonschema"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/kun/log"
+)
+
+// Validate the request validation of a path. Each part is a JSON Schema,
+// or a model reference "model:<id>" validating against the model columns.
+type Validate struct {
+	Query  interface{} `json:"query,omitempty"`
+	Body   interface{} `json:"body,omitempty"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// ValidationError the field error of a request validation
+type ValidationError struct {
+	Field   string `json:"field"` // e.g. body.user.name, query.page
+	Message string `json:"message"`
+}
+
+// requestValidator the compiled schemas of a path
+type requestValidator struct {
+	query  *jsonschema.Schema
+	body   *jsonschema.Schema
+	params *jsonschema.Schema
+	types  map[string]map[string]string // the declared property types of each part, used to convert the strings
+}
+
+var missingProperties = regexp.MustCompile(`'([^']+)'`)
+
+// compile the schemas of the validation
+func (v *Validate) compile() (*requestValidator, error) {
+	validator := &requestValidator{types: map[string]map[string]string{}}
+	parts := []struct {
+		name   string
+		schema interface{}
+		target **jsonschema.Schema
+	}{
+		{"query", v.Query, &validator.query},
+		{"body", v.Body, &validator.body},
+		{"params", v.Params, &validator.params},
+	}
+
+	compiler := jsonschema.NewCompiler()
+	for _, part := range parts {
+		if part.schema == nil {
+			continue
+		}
+
+		schema, err := validateSchema(part.schema)
+		if err != nil {
+			return nil, fmt.Errorf("validate.%s %s", part.name, err.Error())
+		}
+
+		source, err := jsoniter.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("validate.%s %s", part.name, err.Error())
+		}
+
+		compiled, err := compiler.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("validate.%s invalid JSON Schema: %s", part.name, err.Error())
+		}
+		*part.target = compiled
+		validator.types[part.name] = propertyTypes(schema)
+	}
+	return validator, nil
+}
+
+// validateSchema the JSON Schema of the validation part
+func validateSchema(value interface{}) (map[string]interface{}, error) {
+	switch schema := value.(type) {
+	case string:
+		if !strings.HasPrefix(schema, "model:") {
+			return nil, fmt.Errorf("%s should be a JSON Schema or a model reference model:<id>", schema)
+		}
+		return modelSchema(strings.TrimPrefix(schema, "model:"))
+
+	case map[string]interface{}:
+		return schema, nil
+
+	case Schema:
+		return schema, nil
+	}
+	return nil, fmt.Errorf("should be a JSON Schema or a model reference model:<id>")
+}
+
+// modelSchema the JSON Schema of the model row. The columns not nullable, without default value and not
+// generated are required, the nullable columns accept null.
+func modelSchema(id string) (map[string]interface{}, error) {
+	mod, err := model.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, column := range mod.MetaData.Columns {
+		typ := columnType(column.Type)
+		property := typeSchema(typ)
+		if len(column.Option) > 0 {
+			enum := []interface{}{}
+			for _, option := range column.Option {
+				enum = append(enum, option)
+			}
+			property["enum"] = enum
+		}
+
+		if column.Nullable {
+			if t, ok := property["type"].(string); ok {
+				property["type"] = []string{t, "null"}
+			}
+			if enum, ok := property["enum"].([]interface{}); ok {
+				property["enum"] = append(enum, nil)
+			}
+		}
+
+		if column.Label != "" {
+			property["description"] = column.Label
+		}
+		properties[column.Name] = map[string]interface{}(property)
+
+		generated := column.Primary || column.Generate != "" || strings.Contains(strings.ToLower(column.Type), "increments")
+		if !column.Nullable && column.Default == nil && column.DefaultRaw == "" && !generated {
+			required = append(required, column.Name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// propertyTypes the declared types of the schema properties
+func propertyTypes(schema map[string]interface{}) map[string]string {
+	types := map[string]string{}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return types
+	}
+
+	for name, value := range properties {
+		property, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch typ := property["type"].(type) {
+		case string:
+			types[name] = typ
+		case []string:
+			types[name] = typ[0]
+		case []interface{}:
+			if len(typ) > 0 {
+				types[name], _ = typ[0].(string)
+			}
+		}
+	}
+	return types
+}
+
+// validateHandler the handler validating the request before calling the process, nil if the path has no validation
+func (path Path) validateHandler() gin.HandlerFunc {
+	if path.Validate == nil {
+		return nil
+	}
+
+	validator, err := path.Validate.compile()
+	if err != nil {
+		log.Error("[Path] %s %s %s", path.Method, path.Path, err.Error())
+		return nil
+	}
+
+	return func(c *gin.Context) {
+		errors := []ValidationError{}
+
+		if validator.params != nil {
+			params := map[string]interface{}{}
+			for _, param := range c.Params {
+				params[param.Key] = convert(param.Value, validator.types["params"][param.Key])
+			}
+			errors = append(errors, validationErrors("params", validator.params.Validate(params))...)
+		}
+
+		if validator.query != nil {
+			query := map[string]interface{}{}
+			for name, values := range c.Request.URL.Query() {
+				query[name] = convertValues(values, validator.types["query"][name])
+			}
+			errors = append(errors, validationErrors("query", validator.query.Validate(query))...)
+		}
+
+		if validator.body != nil {
+			body, err := requestBody(c, validator.types["body"])
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": err.Error()})
+				c.Abort()
+				return
+			}
+			errors = append(errors, validationErrors("body", validator.body.Validate(body))...)
+		}
+
+		if len(errors) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"code":    http.StatusUnprocessableEntity,
+				"message": "validation failed",
+				"errors":  errors,
+			})
+			c.Abort()
+		}
+	}
+}
+
+// requestBody the request body, the JSON body is decoded and the form body is converted with the declared types.
+// The body is reset so the process handler can read it again.
+func requestBody(c *gin.Context, types map[string]string) (interface{}, error) {
+	contentType := strings.ToLower(c.GetHeader("Content-Type"))
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") || strings.HasPrefix(contentType, "multipart/form-data") {
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			return nil, fmt.Errorf("invalid form body: %s", err.Error())
+		}
+
+		form := map[string]interface{}{}
+		for name, values := range c.Request.PostForm {
+			form[name] = convertValues(values, types[name])
+		}
+		return form, nil
+	}
+
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body: %s", err.Error())
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var body interface{}
+	if err := jsoniter.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %s", err.Error())
+	}
+	return body, nil
+}
+
+// validationErrors the field errors of the evaluation result, the fields are prefixed with the part name
+func validationErrors(part string, result *jsonschema.EvaluationResult) []ValidationError {
+	errors := []ValidationError{}
+	if result == nil || result.IsValid() {
+		return errors
+	}
+
+	// the instance location of the details is relative to the parent result
+	var walk func(result *jsonschema.EvaluationResult, field string)
+	walk = func(result *jsonschema.EvaluationResult, field string) {
+		field = field + strings.ReplaceAll(result.InstanceLocation, "/", ".")
+		keywords := make([]string, 0, len(result.Errors))
+		for keyword := range result.Errors {
+			keywords = append(keywords, keyword)
+		}
+		sort.Strings(keywords)
+
+		missing := map[string]bool{}
+		for _, keyword := range keywords {
+			err := result.Errors[keyword]
+			if keyword == "required" {
+				for _, match := range missingProperties.FindAllStringSubmatch(err.Error(), -1) {
+					missing["/"+match[1]] = true
+					errors = append(errors, ValidationError{Field: field + "." + match[1], Message: "is required"})
+				}
+				continue
+			}
+
+			// properties, items ... the errors of the children are reported by the details
+			if len(result.Details) > 0 && (keyword == "properties" || keyword == "items" || keyword == "prefixItems" || keyword == "additionalProperties") {
+				continue
+			}
+			errors = append(errors, ValidationError{Field: field, Message: err.Error()})
+		}
+
+		for _, detail := range result.Details {
+			// the missing properties are evaluated as null, they are already reported as required
+			if !detail.IsValid() && !missing[detail.InstanceLocation] {
+				walk(detail, field)
+			}
+		}
+	}
+	walk(result, part)
+	return errors
+}
+
+// convertValues convert the query or form values with the declared type, a single value is not wrapped as an array
+func convertValues(values []string, typ string) interface{} {
+	if typ == "array" {
+		items := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			items = append(items, value)
+		}
+		return items
+	}
+
+	if len(values) == 1 {
+		return convert(values[0], typ)
+	}
+
+	items := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		items = append(items, convert(value, typ))
+	}
+	return items
+}
+
+// convert the string value with the declared type, the value is kept as a string if the conversion fails
+func convert(value string, typ string) interface{} {
+	switch typ {
+	case "integer":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestValidateRequest(t *testing.T) {
+	router := prepareValidate(t)
+	defer cleanValidate()
+
+	// valid request
+	response := testRequest(router, "POST", "/api/validate/user/1?page=2", `{"name":"Alice","tags":["a"]}`, nil)
+	assert.Equal(t, 200, response.Code)
+	res := responseMap(response)
+	assert.Equal(t, "Alice", res.Get("name"))
+
+	// schema errors with the field paths
+	response = testRequest(router, "POST", "/api/validate/user/1?page=0", `{"name":"A","tags":["a",1]}`, nil)
+	assert.Equal(t, 422, response.Code)
+	res = responseMap(response)
+	assert.Equal(t, "validation failed", res.Get("message"))
+	fields := []string{}
+	for _, err := range res.Get("errors").([]interface{}) {
+		fields = append(fields, err.(map[string]interface{})["field"].(string))
+	}
+	assert.ElementsMatch(t, []string{"query.page", "body.name", "body.tags.1"}, fields)
+
+	// missing required properties
+	response = testRequest(router, "POST", "/api/validate/user/1", `{}`, nil)
+	assert.Equal(t, 422, response.Code)
+	err := validateFirstError(response)
+	assert.Equal(t, "body.name", err["field"])
+	assert.Equal(t, "is required", err["message"])
+
+	// path parameters are converted with the declared types
+	response = testRequest(router, "POST", "/api/validate/user/abc", `{"name":"Alice"}`, nil)
+	assert.Equal(t, 422, response.Code)
+	assert.Equal(t, "params.id", validateFirstError(response)["field"])
+
+	// malformed JSON
+	response = testRequest(router, "POST", "/api/validate/user/1", `{"name":`, nil)
+	assert.Equal(t, 400, response.Code)
+	assert.Contains(t, responseMap(response).Get("message"), "invalid JSON body")
+}
+
+func TestValidateFindHandler(t *testing.T) {
+	prepareValidate(t)
+	defer cleanValidate()
+	BuildRouteTable()
+
+	_, _, handler, params, err := FindHandler("POST", "/validate/user/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler is built once for the route entry
+	entry, _ := routeTable.find("POST", "/validate/user/1")
+	assert.NotNil(t, entry.handler)
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request = httptest.NewRequest("POST", "/validate/user/1", strings.NewReader(`{"name":"A"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	for key, value := range params {
+		c.Params = append(c.Params, gin.Param{Key: key, Value: value})
+	}
+	handler(c)
+	assert.Equal(t, 422, response.Code)
+	assert.Equal(t, "body.name", validateFirstError(response)["field"])
+}
+
+func TestValidateInvalidSchema(t *testing.T) {
+	_, err := parseSource("/apis/validate.http.yao", []byte(`{
+		"name": "Validate",
+		"paths": [{"path": "/", "method": "POST", "process": "tests.validate.echo", "validate": {"body": "users"}}]
+	}`), "validate")
+	assert.Error(t, err)
+}
+
+func validateFirstError(response *httptest.ResponseRecorder) map[string]interface{} {
+	errors, ok := responseMap(response).Get("errors").([]interface{})
+	if !ok || len(errors) == 0 {
+		return map[string]interface{}{}
+	}
+	return errors[0].(map[string]interface{})
+}
+
+func prepareValidate(t *testing.T) *gin.Engine {
+	process.Register("tests.validate.echo", func(process *process.Process) interface{} {
+		return process.Args[0]
+	})
+
+	return loadTestAPIs(t, map[string]string{"validate": `{
+		"name": "Validate",
+		"version": "1.0.0",
+		"group": "validate",
+		"guard": "-",
+		"paths": [{
+			"path": "/user/:id", "method": "POST", "process": "tests.validate.echo", "in": [":payload"], "out": {"status": 200, "type": "application/json"},
+			"validate": {
+				"params": {"type": "object", "properties": {"id": {"type": "integer"}}},
+				"query": {"type": "object", "properties": {"page": {"type": "integer", "minimum": 1}}},
+				"body": {
+					"type": "object",
+					"required": ["name"],
+					"properties": {"name": {"type": "string", "minLength": 2}, "tags": {"type": "array", "items": {"type": "string"}}}
+				}
+			}
+		}]
+	}`})
+}
+
+func cleanValidate() {
+	unloadTestAPIs("validate")
+}
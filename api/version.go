@@ -0,0 +1,394 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+)
+
+// Versioning the ways to serve the versions of a group side by side
+const (
+	VersioningPath   = "path"   // the routes are prefixed with the major version, e.g. /api/v2/user/:id
+	VersioningHeader = "header" // the routes are shared, the version is selected by the Accept-Version header, the latest version by default
+)
+
+// Deprecation the deprecation of an API version or a path
+type Deprecation struct {
+	Since  string `json:"since,omitempty"`  // the deprecation date, 2006-01-02 or RFC 3339
+	Sunset string `json:"sunset,omitempty"` // the removal date, the path responses 410 Gone after the date
+	Link   string `json:"link,omitempty"`   // the migration guide
+}
+
+// VersionInfo the active version of a group
+type VersionInfo struct {
+	Group      string   `json:"group"`
+	Version    string   `json:"version"`
+	Versioning string   `json:"versioning,omitempty"`
+	Prefix     string   `json:"prefix"` // the route group, e.g. v2/user
+	APIs       []string `json:"apis"`
+	Latest     bool     `json:"latest"`
+	Deprecated bool     `json:"deprecated"`
+	Since      string   `json:"since,omitempty"`
+	Sunset     string   `json:"sunset,omitempty"`
+}
+
+// versionRoute the handlers of a version of a shared route
+type versionRoute struct {
+	version  string
+	handlers []gin.HandlerFunc
+}
+
+// routeGroup the group of the routes, prefixed with the major version if the API is versioned by path, e.g. v2/user
+func (http HTTP) routeGroup() string {
+	if http.Versioning != VersioningPath {
+		return http.Group
+	}
+
+	prefix := majorVersion(http.Version)
+	if http.Group == "" {
+		return prefix
+	}
+	return prefix + "/" + strings.TrimPrefix(http.Group, "/")
+}
+
+// checkVersion validates the versioning and the deprecations of the API
+func (http HTTP) checkVersion() error {
+	switch http.Versioning {
+	case "":
+	case VersioningPath, VersioningHeader:
+		if len(versionParts(http.Version)) == 0 {
+			return fmt.Errorf("version %q is invalid, the versioning %s requires a version like 1.0.0", http.Version, http.Versioning)
+		}
+	default:
+		return fmt.Errorf("versioning %s is not supported, should be path or header", http.Versioning)
+	}
+
+	if err := http.Deprecated.check(); err != nil {
+		return err
+	}
+	for _, path := range http.Paths {
+		if err := path.Deprecated.check(); err != nil {
+			return fmt.Errorf("%s %s %s", path.Method, path.Path, err.Error())
+		}
+	}
+	return nil
+}
+
+// deprecation the deprecation of the path, the path deprecation takes precedence over the API one
+func (http HTTP) deprecation(path Path) *Deprecation {
+	if path.Deprecated != nil {
+		return path.Deprecated
+	}
+	return http.Deprecated
+}
+
+// versionHandler sets the API-Version and the deprecation headers, responses 410 if the path is removed.
+// nil if the API is not versioned and the path is not deprecated.
+func (http HTTP) versionHandler(path Path) gin.HandlerFunc {
+	deprecated := http.deprecation(path)
+	if http.Versioning == "" && deprecated == nil {
+		return nil
+	}
+
+	var since, sunset time.Time
+	if deprecated != nil {
+		since, _ = parseDate(deprecated.Since)
+		sunset, _ = parseDate(deprecated.Sunset)
+	}
+
+	version := http.Version
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if http.Versioning != "" {
+			header.Set("API-Version", version)
+		}
+
+		if deprecated == nil {
+			return
+		}
+
+		// RFC 9745, the date is unknown if since is not given
+		if since.IsZero() {
+			header.Set("Deprecation", "true")
+		} else {
+			header.Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+		}
+
+		if !sunset.IsZero() {
+			header.Set("Sunset", httpDate(sunset))
+		}
+
+		if deprecated.Link != "" {
+			header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, deprecated.Link))
+		}
+
+		if !sunset.IsZero() && time.Now().After(sunset) {
+			c.JSON(410, gin.H{"code": 410, "message": fmt.Sprintf("%s %s was removed on %s", path.Method, path.Path, sunset.Format("2006-01-02"))})
+			c.Abort()
+		}
+	}
+}
+
+// httpDate formats the time as an HTTP date, e.g. Sun, 06 Nov 1994 08:49:37 GMT
+func httpDate(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// routeVersions registers the shared routes of the API versions versioned by header
+func routeVersions(router *gin.Engine, root string, apis []*API, allows ...string) {
+	sortVersions(apis)
+	latest := apis[0].HTTP
+	if prefix := latest.routeGroup(); prefix != "" {
+		root = strings.TrimSuffix(root, "/") + "/" + strings.TrimPrefix(prefix, "/")
+	}
+	group := router.Group(root)
+
+	keys := []string{}
+	routes := map[string][]versionRoute{}
+	for _, api := range apis {
+		// the CORS option routes are registered once for the versions
+		http := api.HTTP
+		http.Name = latest.Name
+		for _, path := range http.Paths {
+			path.Method = strings.ToUpper(path.Method)
+			key := path.Method + " " + path.Path
+			if _, has := routes[key]; !has {
+				keys = append(keys, key)
+			}
+			routes[key] = append(routes[key], versionRoute{version: http.Version, handlers: http.handlers(group, path, allows...)})
+		}
+	}
+
+	for _, key := range keys {
+		parts := strings.SplitN(key, " ", 2)
+		latest.method(parts[0], parts[1], group, versionDispatcher(routes[key]))
+	}
+	registeredOptions = map[string]bool{}
+}
+
+// versions the entries of the versions sharing the route of the entry, sorted from the latest
+func (rt *RouteTable) versions(method string, entry *RouteEntry) []*RouteEntry {
+	entries := []*RouteEntry{}
+	for _, e := range rt.routes[strings.ToUpper(method)] {
+		if e.Pattern == entry.Pattern && e.API.HTTP.Versioning == VersioningHeader && e.API.HTTP.Group == entry.API.HTTP.Group {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareVersion(entries[i].API.HTTP.Version, entries[j].API.HTTP.Version) > 0
+	})
+	return entries
+}
+
+// versionDispatcher the handler selecting the version by the Accept-Version header, the versions are sorted from the latest
+func versionDispatcher(routes []versionRoute) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handlers := routes[0].handlers
+		if requested := c.GetHeader("Accept-Version"); requested != "" {
+			handlers = nil
+			for _, route := range routes {
+				if matchVersion(requested, route.version) {
+					handlers = route.handlers
+					break
+				}
+			}
+		}
+
+		if handlers == nil {
+			versions := []string{}
+			for _, route := range routes {
+				versions = append(versions, route.version)
+			}
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": fmt.Sprintf("version %s is not available, available versions: %s", c.GetHeader("Accept-Version"), strings.Join(versions, ", ")),
+			})
+			c.Abort()
+			return
+		}
+
+		for _, handler := range handlers {
+			handler(c)
+			if c.IsAborted() {
+				return
+			}
+		}
+	}
+}
+
+// Versions lists the active versions of the given groups, or all the groups. The versions removed
+// by the sunset date are not listed. The versions of a group are sorted from the latest.
+func Versions(groups ...string) []VersionInfo {
+	filter := map[string]bool{}
+	for _, group := range groups {
+		filter[group] = true
+	}
+
+	apisMu.RLock()
+	byGroup := map[string][]*API{}
+	for _, api := range APIs {
+		if api.HTTP.Version == "" || (len(filter) > 0 && !filter[api.HTTP.Group]) {
+			continue
+		}
+		byGroup[api.HTTP.Group] = append(byGroup[api.HTTP.Group], api)
+	}
+	apisMu.RUnlock()
+
+	names := make([]string, 0, len(byGroup))
+	for name := range byGroup {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	versions := []VersionInfo{}
+	for _, name := range names {
+		apis := byGroup[name]
+		sortVersions(apis)
+
+		latest := true
+		index := map[string]int{}
+		for _, api := range apis {
+			info := VersionInfo{
+				Group:      api.HTTP.Group,
+				Version:    api.HTTP.Version,
+				Versioning: api.HTTP.Versioning,
+				Prefix:     api.HTTP.routeGroup(),
+				APIs:       []string{api.ID},
+			}
+
+			if deprecated := api.HTTP.Deprecated; deprecated != nil {
+				if sunset, err := parseDate(deprecated.Sunset); err == nil && !sunset.IsZero() && time.Now().After(sunset) {
+					continue
+				}
+				info.Deprecated = true
+				info.Since = deprecated.Since
+				info.Sunset = deprecated.Sunset
+			}
+
+			// several APIs could share a version of the group
+			if i, has := index[info.Version]; has {
+				versions[i].APIs = append(versions[i].APIs, api.ID)
+				continue
+			}
+
+			info.Latest = latest
+			latest = false
+			index[info.Version] = len(versions)
+			versions = append(versions, info)
+		}
+	}
+	return versions
+}
+
+// sortVersions sorts the APIs from the latest version, the APIs of the same version by ID
+func sortVersions(apis []*API) {
+	sort.SliceStable(apis, func(i, j int) bool {
+		if cmp := compareVersion(apis[i].HTTP.Version, apis[j].HTTP.Version); cmp != 0 {
+			return cmp > 0
+		}
+		return apis[i].ID < apis[j].ID
+	})
+}
+
+// check validates the dates of the deprecation
+func (deprecated *Deprecation) check() error {
+	if deprecated == nil {
+		return nil
+	}
+	if _, err := parseDate(deprecated.Since); err != nil {
+		return fmt.Errorf("deprecated.since %s", err.Error())
+	}
+	if _, err := parseDate(deprecated.Sunset); err != nil {
+		return fmt.Errorf("deprecated.sunset %s", err.Error())
+	}
+	return nil
+}
+
+// parseDate parses the date 2006-01-02 or RFC 3339, zero time if empty
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a valid date, should be 2006-01-02 or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// matchVersion the version matches the requested one, the requested version could be partial, e.g. 1, v1, 1.2
+func matchVersion(requested string, version string) bool {
+	want := versionParts(requested)
+	have := versionParts(version)
+	if len(want) == 0 || len(want) > len(have) {
+		return false
+	}
+	for i := range want {
+		if want[i] != have[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// compareVersion compares the versions part by part, the missing parts are zero
+func compareVersion(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// majorVersion the path prefix of the version, e.g. 2.1.0 -> v2
+func majorVersion(version string) string {
+	parts := versionParts(version)
+	if len(parts) == 0 {
+		log.Warn("[API] version %q is invalid", version)
+		return "v0"
+	}
+	return "v" + strconv.Itoa(parts[0])
+}
+
+// versionParts the numeric parts of the version, e.g. v1.2.0-beta -> [1, 2, 0]
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil
+	}
+
+	parts := []int{}
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestVersionHeader(t *testing.T) {
+	router := prepareVersion(t)
+	defer cleanVersion()
+
+	// the latest version by default
+	response := testRequest(router, "GET", "/api/version/ping", "", nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v2", response.Body.String())
+	assert.Equal(t, "2.0.0", response.Header().Get("API-Version"))
+	assert.Empty(t, response.Header().Get("Deprecation"))
+
+	// the deprecated version
+	response = testRequest(router, "GET", "/api/version/ping", "", map[string]string{"Accept-Version": "1"})
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v1", response.Body.String())
+	assert.Equal(t, "1.0.0", response.Header().Get("API-Version"))
+	assert.Equal(t, "@1767225600", response.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 31 Dec 2099 00:00:00 GMT", response.Header().Get("Sunset"))
+	assert.Contains(t, response.Header().Get("Link"), `rel="deprecation"`)
+
+	// the path only in the old version
+	response = testRequest(router, "GET", "/api/version/legacy", "", nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v1", response.Body.String())
+
+	// unknown version
+	response = testRequest(router, "GET", "/api/version/ping", "", map[string]string{"Accept-Version": "3"})
+	assert.Equal(t, 400, response.Code)
+	assert.Contains(t, responseMap(response).Get("message"), "2.0.0, 1.0.0")
+
+	// the dynamic routes
+	BuildRouteTable()
+	api, _, handler, _, err := FindHandler("GET", "/version/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2.0.0", api.HTTP.Version)
+
+	response = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest("GET", "/version/ping", nil)
+	c.Request.Header.Set("Accept-Version", "v1")
+	handler(c)
+	assert.Equal(t, "v1", response.Body.String())
+}
+
+func TestVersionPath(t *testing.T) {
+	router := prepareVersion(t)
+	defer cleanVersion()
+
+	response := testRequest(router, "GET", "/api/v3/version-path/ping", "", nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v3", response.Body.String())
+	assert.Equal(t, "3.1.0", response.Header().Get("API-Version"))
+
+	// removed after the sunset date
+	response = testRequest(router, "GET", "/api/v3/version-path/removed", "", nil)
+	assert.Equal(t, 410, response.Code)
+	assert.NotEmpty(t, response.Header().Get("Sunset"))
+}
+
+func TestVersions(t *testing.T) {
+	prepareVersion(t)
+	defer cleanVersion()
+
+	versions := Versions("version", "version-path")
+	if !assert.Len(t, versions, 3) {
+		return
+	}
+
+	assert.Equal(t, "2.0.0", versions[0].Version)
+	assert.True(t, versions[0].Latest)
+	assert.False(t, versions[0].Deprecated)
+	assert.Equal(t, "1.0.0", versions[1].Version)
+	assert.False(t, versions[1].Latest)
+	assert.True(t, versions[1].Deprecated)
+	assert.Equal(t, "2099-12-31", versions[1].Sunset)
+	assert.Equal(t, "v3/version-path", versions[2].Prefix)
+
+	res, err := process.New("api.Versions", "version").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, res, 2)
+}
+
+func TestVersionInvalid(t *testing.T) {
+	_, err := parseSource("/apis/version.http.yao", []byte(`{
+		"name": "Version", "version": "latest", "versioning": "path",
+		"paths": [{"path": "/ping", "method": "GET", "process": "tests.version.v1"}]
+	}`), "version")
+	assert.Error(t, err)
+
+	_, err = parseSource("/apis/version.http.yao", []byte(`{
+		"name": "Version", "version": "1.0.0", "versioning": "query",
+		"paths": [{"path": "/ping", "method": "GET", "process": "tests.version.v1"}]
+	}`), "version")
+	assert.Error(t, err)
+
+	_, err = parseSource("/apis/version.http.yao", []byte(`{
+		"name": "Version", "version": "1.0.0",
+		"paths": [{"path": "/ping", "method": "GET", "process": "tests.version.v1", "deprecated": {"sunset": "someday"}}]
+	}`), "version")
+	assert.Error(t, err)
+}
+
+func prepareVersion(t *testing.T) *gin.Engine {
+	for _, name := range []string{"v1", "v2", "v3"} {
+		value := name
+		process.Register("tests.version."+name, func(process *process.Process) interface{} { return value })
+	}
+
+	sources := map[string]string{
+		"version.v1": `{
+			"name": "Version", "version": "1.0.0", "versioning": "header", "group": "version", "guard": "-",
+			"deprecated": {"since": "2026-01-01", "sunset": "2099-12-31", "link": "https://example.com/migrate"},
+			"paths": [
+				{"path": "/ping", "method": "GET", "process": "tests.version.v1", "out": {"status": 200, "type": "text/plain"}},
+				{"path": "/legacy", "method": "GET", "process": "tests.version.v1", "out": {"status": 200, "type": "text/plain"}}
+			]
+		}`,
+		"version.v2": `{
+			"name": "Version", "version": "2.0.0", "versioning": "header", "group": "version", "guard": "-",
+			"paths": [{"path": "/ping", "method": "GET", "process": "tests.version.v2", "out": {"status": 200, "type": "text/plain"}}]
+		}`,
+		"version.path": `{
+			"name": "Version Path", "version": "3.1.0", "versioning": "path", "group": "version-path", "guard": "-",
+			"paths": [
+				{"path": "/ping", "method": "GET", "process": "tests.version.v3", "out": {"status": 200, "type": "text/plain"}},
+				{"path": "/removed", "method": "GET", "process": "tests.version.v3", "out": {"status": 200, "type": "text/plain"}, "deprecated": {"sunset": "2020-01-01"}}
+			]
+		}`,
+	}
+
+	return loadTestAPIs(t, sources)
+}
+
+func cleanVersion() {
+	unloadTestAPIs("version.v1", "version.v2", "version.path")
+	BuildRouteTable()
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector/anthropic"
 	"github.com/yaoapp/gou/connector/database"
+	"github.com/yaoapp/gou/connector/elastic"
 	"github.com/yaoapp/gou/connector/fastembed"
 	"github.com/yaoapp/gou/connector/moapi"
 	mongo "github.com/yaoapp/gou/connector/mongo"
@@ -23,6 +24,14 @@ var AIConnectors = []Option{}
 
 var rwlock sync.RWMutex // Use RWMutex for better concurrency
 
+// loadHandlers the handlers called after a connector is loaded
+var loadHandlers = []func(id string, c Connector){}
+
+// OnLoad registers a handler called after a connector is loaded, e.g. the query engines of the mongo and elasticsearch connectors
+func OnLoad(handler func(id string, c Connector)) {
+	loadHandlers = append(loadHandlers, handler)
+}
+
 // LoadSync load connector sync
 func LoadSync(file string, id string) (Connector, error) {
 	rwlock.Lock()
@@ -80,6 +89,7 @@ func LoadSource(source []byte, id string, file string) (Connector, error) {
 	}
 
 	Connectors[id] = c
+	loaded(id, c)
 	return Connectors[id], nil
 }
 
@@ -97,9 +107,17 @@ func New(typ string, id string, dsl []byte) (Connector, error) {
 	}
 
 	Connectors[id] = c
+	loaded(id, c)
 	return Connectors[id], nil
 }
 
+// loaded calls the load handlers
+func loaded(id string, c Connector) {
+	for _, handler := range loadHandlers {
+		handler(id, c)
+	}
+}
+
 // BuildAPIURL builds the complete API URL from host and endpoint.
 //
 // If host ends with "/" it is used as-is (the user has specified a full base
@@ -186,6 +204,10 @@ func makeConnector(typ string) (Connector, error) {
 		c := &mongo.Connector{}
 		return c, nil
 
+	case ELASTICSEARCH:
+		c := &elastic.Connector{}
+		return c, nil
+
 	case OPENAI:
 		c := &openai.Connector{}
 		return c, nil
@@ -2,8 +2,11 @@ package connector
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"sync"
@@ -12,6 +15,7 @@ import (
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector/anthropic"
 	"github.com/yaoapp/gou/connector/database"
+	"github.com/yaoapp/gou/connector/elastic"
 	"github.com/yaoapp/gou/connector/fastembed"
 	mongo "github.com/yaoapp/gou/connector/mongo"
 	"github.com/yaoapp/gou/connector/openai"
@@ -107,6 +111,63 @@ func TestLoadMongoDB(t *testing.T) {
 	assert.Equal(t, "mongo", Connectors["mongo"].ID())
 }
 
+func TestLoadElasticsearch(t *testing.T) {
+	var auth, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		path = r.URL.Path
+		if r.URL.Path == "/missing/_search" {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error":"index_not_found_exception"}`))
+			return
+		}
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"1","_source":{"name":"Alice"}}]}}`))
+	}))
+	defer server.Close()
+
+	os.Setenv("GOU_TEST_ES_PASS", "secret")
+	defer os.Unsetenv("GOU_TEST_ES_PASS")
+
+	src := []byte(fmt.Sprintf(`{
+		"type": "elasticsearch",
+		"name": "Elasticsearch Test",
+		"options": {"hosts": ["%s"], "user": "elastic", "pass": "$ENV.GOU_TEST_ES_PASS"}
+	}`, server.URL))
+	conn, err := LoadSource(src, "elastic", "elastic.conn.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Connectors, "elastic")
+
+	if !Connectors["elastic"].Is(ELASTICSEARCH) {
+		t.Fatal("the elastic connector is not an ELASTICSEARCH")
+	}
+
+	es, ok := conn.(*elastic.Connector)
+	if !ok {
+		t.Fatal("the connector is not a *elastic.Connector")
+	}
+	assert.Equal(t, "elastic", es.ID())
+	assert.Equal(t, "secret", es.Options.Pass)
+	assert.Equal(t, 5, es.Setting()["timeout"])
+
+	res, err := es.Search("users", map[string]interface{}{"size": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/users/_search", path)
+	assert.True(t, strings.HasPrefix(auth, "Basic "))
+	assert.NotNil(t, res["hits"])
+
+	_, err = es.Search("missing", nil)
+	if _, ok := err.(*elastic.ResponseError); !ok {
+		t.Fatalf("the error should be a *elastic.ResponseError, got %v", err)
+	}
+
+	_, err = LoadSource([]byte(`{"type": "elasticsearch", "options": {"hosts": ["127.0.0.1:9200"]}}`), "elastic-invalid", "elastic-invalid.conn.yao")
+	assert.Error(t, err)
+}
+
 func TestLoadOpenAI(t *testing.T) {
 	file := prepare(t, "openai")
 	_, err := Load(file, "openai")
@@ -0,0 +1,219 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// Connector the elasticsearch connector
+type Connector struct {
+	id      string
+	file    string
+	Name    string       `json:"name,omitempty"`
+	Version string       `json:"version,omitempty"`
+	Options Options      `json:"options"`
+	Client  *http.Client `json:"-"`
+	types.MetaInfo
+}
+
+// Options the elasticsearch connector option
+type Options struct {
+	Hosts   []string `json:"hosts"`
+	User    string   `json:"user,omitempty"`
+	Pass    string   `json:"pass,omitempty"`
+	Key     string   `json:"key,omitempty"` // API key, takes precedence over user/pass
+	Timeout int      `json:"timeout,omitempty"`
+}
+
+// Register the connections from dsl
+func (es *Connector) Register(file string, id string, dsl []byte) error {
+
+	es.id = id
+	es.file = file
+
+	err := application.Parse(file, dsl, es)
+	if err != nil {
+		return err
+	}
+
+	err = es.setDefaults()
+	if err != nil {
+		return err
+	}
+
+	return es.makeConnection()
+}
+
+// Is the connections from dsl
+func (es *Connector) Is(typ int) bool {
+	return 4 == typ
+}
+
+// ID get connector id
+func (es *Connector) ID() string {
+	return es.id
+}
+
+// Query get connector query interface
+func (es *Connector) Query() (query.Query, error) {
+	return nil, nil
+}
+
+// Schema get connector schema interface
+func (es *Connector) Schema() (schema.Schema, error) {
+	return nil, nil
+}
+
+// Close connections
+func (es *Connector) Close() error {
+	if es.Client != nil {
+		es.Client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Setting get the connection setting
+func (es *Connector) Setting() map[string]interface{} {
+	return map[string]interface{}{
+		"hosts":   es.Options.Hosts,
+		"user":    es.Options.User,
+		"pass":    es.Options.Pass,
+		"key":     es.Options.Key,
+		"timeout": es.Options.Timeout,
+	}
+}
+
+// GetMetaInfo returns the meta information
+func (es *Connector) GetMetaInfo() types.MetaInfo {
+	return es.MetaInfo
+}
+
+// Search send a search request to the index and return the decoded response
+func (es *Connector) Search(index string, body map[string]interface{}) (map[string]interface{}, error) {
+	return es.Request(http.MethodPost, fmt.Sprintf("/%s/_search", index), body)
+}
+
+// Request send a request to the cluster, the hosts are tried in order until one responds
+func (es *Connector) Request(method string, path string, body interface{}) (map[string]interface{}, error) {
+
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = jsoniter.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, host := range es.Options.Hosts {
+		res, err := es.request(host, method, path, payload)
+		if err == nil {
+			return res, nil
+		}
+
+		if _, ok := err.(*ResponseError); ok {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%s: %v", es.id, lastErr)
+}
+
+// ResponseError the error returned by the cluster
+type ResponseError struct {
+	Status int
+	Body   string
+}
+
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("elasticsearch response %d: %s", err.Status, err.Body)
+}
+
+func (es *Connector) request(host string, method string, path string, payload []byte) (map[string]interface{}, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(es.Options.Timeout)*time.Second)
+	defer cancel()
+
+	url := strings.TrimSuffix(host, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if es.Options.Key != "" {
+		req.Header.Set("Authorization", "ApiKey "+es.Options.Key)
+	} else if es.Options.User != "" {
+		req.SetBasicAuth(es.Options.User, es.Options.Pass)
+	}
+
+	resp, err := es.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, &ResponseError{Status: resp.StatusCode, Body: string(data)}
+	}
+
+	res := map[string]interface{}{}
+	if len(data) == 0 {
+		return res, nil
+	}
+
+	err = jsoniter.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (es *Connector) setDefaults() error {
+	es.Options.User = helper.EnvString(es.Options.User)
+	es.Options.Pass = helper.EnvString(es.Options.Pass)
+	es.Options.Key = helper.EnvString(es.Options.Key)
+	es.Options.Timeout = helper.EnvInt(es.Options.Timeout, 5)
+	if es.Options.Timeout == 0 {
+		es.Options.Timeout = 5
+	}
+
+	for i := range es.Options.Hosts {
+		es.Options.Hosts[i] = helper.EnvString(es.Options.Hosts[i])
+	}
+	return nil
+}
+
+func (es *Connector) makeConnection() error {
+	if len(es.Options.Hosts) == 0 {
+		return fmt.Errorf("%s options.hosts is required", es.id)
+	}
+
+	for i, host := range es.Options.Hosts {
+		if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+			return fmt.Errorf("%s hosts.%d should start with http:// or https://", es.id, i)
+		}
+	}
+
+	es.Client = &http.Client{Timeout: time.Duration(es.Options.Timeout) * time.Second}
+	return nil
+}
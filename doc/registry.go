@@ -21,6 +21,13 @@ func Register(entry *Entry) {
 	entries[entryKey(entry.Type, entry.Name)] = entry
 }
 
+// Unregister removes a single entry by type and name (case-insensitive).
+func Unregister(t EntryType, name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(entries, entryKey(t, name))
+}
+
 // Get retrieves a single entry by type and name (case-insensitive).
 // For process entries it also resolves callable names like "models.user.find"
 // to the handler key "models.find".
@@ -38,11 +38,12 @@ type Method struct {
 	Return *TypeValue  `json:"return,omitempty"  yaml:"return,omitempty"`
 }
 
-// Endpoint describes an HTTP API endpoint (reserved for OpenAPI).
+// Endpoint describes an HTTP API endpoint, registered for each loaded API path.
 type Endpoint struct {
-	Method string `json:"method"            yaml:"method"`
-	Path   string `json:"path"              yaml:"path"`
-	Guard  string `json:"guard,omitempty"   yaml:"guard,omitempty"`
+	Method  string `json:"method"            yaml:"method"`
+	Path    string `json:"path"              yaml:"path"`
+	Guard   string `json:"guard,omitempty"   yaml:"guard,omitempty"`
+	Process string `json:"process,omitempty" yaml:"process,omitempty"`
 }
 
 // Entry is a single documentation item.
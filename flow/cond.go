@@ -0,0 +1,390 @@
+package flow
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Eval 计算条件表达式
+//
+//	true / false
+//	"?:$res.user"                                  绑定数值是否为真
+//	"?:$res.count > 0 && ?:$in.0 != 'guest'"       比较运算 == != > >= < <=, 逻辑运算 && || !, 括号
+//	"?:$in.0.type in ['vip', 'admin']"             包含
+//	["?:$res.user", "?:$res.count > 0"]            全部成立
+func Eval(expr interface{}, data maps.Map) (bool, error) {
+	switch value := expr.(type) {
+	case nil:
+		return false, nil
+
+	case bool:
+		return value, nil
+
+	case string:
+		tokens, err := tokenize(value)
+		if err != nil {
+			return false, fmt.Errorf("%s %s", value, err.Error())
+		}
+
+		parser := &condParser{tokens: tokens, data: data}
+		res, err := parser.or()
+		if err != nil {
+			return false, fmt.Errorf("%s %s", value, err.Error())
+		}
+
+		if parser.pos < len(parser.tokens) {
+			return false, fmt.Errorf("%s unexpected %s", value, parser.tokens[parser.pos])
+		}
+		return truthy(res), nil
+
+	case []interface{}:
+		for _, item := range value {
+			ok, err := Eval(item, data)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	return truthy(helper.Bind(expr, data)), nil
+}
+
+// condParser 条件表达式解析器
+type condParser struct {
+	tokens []string
+	pos    int
+	data   maps.Map
+}
+
+func (p *condParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *condParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// or := and ( "||" and )*
+func (p *condParser) or() (interface{}, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = truthy(left) || truthy(right)
+	}
+	return left, nil
+}
+
+// and := not ( "&&" not )*
+func (p *condParser) and() (interface{}, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = truthy(left) && truthy(right)
+	}
+	return left, nil
+}
+
+// not := "!" not | compare
+func (p *condParser) not() (interface{}, error) {
+	if p.peek() == "!" {
+		p.next()
+		value, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(value), nil
+	}
+	return p.compare()
+}
+
+// compare := operand ( op operand )?
+func (p *condParser) compare() (interface{}, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch op {
+	case "==", "!=", ">", ">=", "<", "<=", "in":
+		p.next()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return compare(op, left, right), nil
+	}
+	return left, nil
+}
+
+// operand := "(" or ")" | "[" operand ( "," operand )* "]" | value
+func (p *condParser) operand() (interface{}, error) {
+	token := p.next()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected end")
+
+	case "(":
+		value, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return value, nil
+
+	case "[":
+		values := []interface{}{}
+		if p.peek() == "]" {
+			p.next()
+			return values, nil
+		}
+		for {
+			value, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			switch p.next() {
+			case ",":
+				continue
+			case "]":
+				return values, nil
+			}
+			return nil, fmt.Errorf("missing ]")
+		}
+
+	case ")", "]", ",", "&&", "||", "==", "!=", ">", ">=", "<", "<=":
+		return nil, fmt.Errorf("unexpected %s", token)
+	}
+
+	return p.value(token), nil
+}
+
+// value 读取数值: 绑定变量, 字符串, 数字, true, false, null
+func (p *condParser) value(token string) interface{} {
+	if strings.HasPrefix(token, "?:") || strings.HasPrefix(token, "{{") {
+		return helper.Bind(token, p.data)
+	}
+
+	if len(token) >= 2 && (token[0] == '\'' || token[0] == '"') && token[len(token)-1] == token[0] {
+		return token[1 : len(token)-1]
+	}
+
+	switch token {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "nil":
+		return nil
+	}
+
+	if n, err := strconv.ParseFloat(token, 64); err == nil {
+		return n
+	}
+	return token
+}
+
+// tokenize 拆分条件表达式
+func tokenize(input string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(input[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, input[i:i+end+2])
+			i += end + 2
+
+		case strings.HasPrefix(input[i:], "{{"):
+			end := strings.Index(input[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated {{")
+			}
+			tokens = append(tokens, input[i:i+end+2])
+			i += end + 2
+
+		case strings.HasPrefix(input[i:], "&&"), strings.HasPrefix(input[i:], "||"),
+			strings.HasPrefix(input[i:], "=="), strings.HasPrefix(input[i:], "!="),
+			strings.HasPrefix(input[i:], ">="), strings.HasPrefix(input[i:], "<="):
+			tokens = append(tokens, input[i:i+2])
+			i += 2
+
+		case strings.IndexByte("()[],!<>", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+
+		default:
+			start := i
+			for i < len(input) && strings.IndexByte(" \t\n\r()[],!<>=&|'\"", input[i]) < 0 {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected %c", c)
+			}
+			tokens = append(tokens, input[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+// compare 比较两个数值
+func compare(op string, left, right interface{}) bool {
+	if op == "in" {
+		values := reflect.ValueOf(right)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return false
+		}
+		for i := 0; i < values.Len(); i++ {
+			if compare("==", left, values.Index(i).Interface()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	x, xok := number(left)
+	y, yok := number(right)
+	if xok && yok {
+		switch op {
+		case "==":
+			return x == y
+		case "!=":
+			return x != y
+		case ">":
+			return x > y
+		case ">=":
+			return x >= y
+		case "<":
+			return x < y
+		case "<=":
+			return x <= y
+		}
+		return false
+	}
+
+	switch op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	if left == nil || right == nil {
+		return false
+	}
+
+	res := strings.Compare(fmt.Sprintf("%v", left), fmt.Sprintf("%v", right))
+	switch op {
+	case ">":
+		return res > 0
+	case ">=":
+		return res >= 0
+	case "<":
+		return res < 0
+	case "<=":
+		return res <= 0
+	}
+	return false
+}
+
+func equal(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	return fmt.Sprintf("%v", left) == fmt.Sprintf("%v", right)
+}
+
+// number 转换为数字 (数字字符串同样转换)
+func number(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int:
+		return float64(value), true
+	case int8:
+		return float64(value), true
+	case int16:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint:
+		return float64(value), true
+	case uint8:
+		return float64(value), true
+	case uint16:
+		return float64(value), true
+	case uint32:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float32:
+		return float64(value), true
+	case float64:
+		return value, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// truthy 数值是否为真 (nil, false, 0, "", "false", "0", 空数组及空对象为假)
+func truthy(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	case string:
+		return value != "" && value != "false" && value != "0"
+	}
+
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/maps"
+)
+
+func TestEval(t *testing.T) {
+	data := maps.Map{
+		"$in":     []interface{}{map[string]interface{}{"type": "vip"}},
+		"$res":    map[string]interface{}{"count": 3, "name": "gou", "empty": []interface{}{}},
+		"$global": map[string]interface{}{"debug": true},
+	}.Dot()
+
+	cases := map[string]bool{
+		"?:$res.count":                               true,
+		"?:$res.empty":                               false,
+		"?:$res.missing":                             false,
+		"!?:$res.missing":                            true,
+		"?:$res.count > 2":                           true,
+		"?:$res.count >= 4":                          false,
+		"?:$res.count == '3'":                        true,
+		"?:$res.name != 'gou'":                       false,
+		"?:$in.0.type in ['vip', 'admin']":           true,
+		"?:$in.0.type in []":                         false,
+		"?:$res.count > 5 || ?:$global.debug":        true,
+		"?:$res.count > 1 && (false || ?:$res.name)": true,
+		"{{ $res.name }} == gou":                     true,
+		"?:$res.missing == null":                     true,
+		"?:$res.name < 'hello'":                      true,
+	}
+
+	for expr, expect := range cases {
+		ok, err := Eval(expr, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expect, ok, expr)
+	}
+
+	ok, err := Eval([]interface{}{"?:$res.count", true}, data)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = Eval(false, data)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	for _, expr := range []string{"?:$res.count >", "(?:$res.count", "'unterminated", "?:$res.count 1"} {
+		_, err = Eval(expr, data)
+		assert.NotNil(t, err, expr)
+	}
+}
@@ -0,0 +1,220 @@
+package flow
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+)
+
+// reResRef 节点结果引用, 如 {{$res.users}}, ?:$res.users.0.id ($res 引用全部结果)
+var reResRef = regexp.MustCompile(`\$res(\.([^\s.\[\]{}()%'",=!<>&|\\]+))?`)
+
+// Dependencies 返回各节点依赖的节点下标 (显式声明的 deps 及 $res 绑定推断的依赖)
+// 推断的依赖仅包含前序节点, 引用 $res 全部结果时依赖所有前序节点
+func (flow *Flow) Dependencies() ([][]int, error) {
+	deps := make([][]int, len(flow.Nodes))
+	for i, node := range flow.Nodes {
+		if node.Switch != nil {
+			return nil, fmt.Errorf("flows.%s node %s switch is not supported when concurrency > 1", flow.Name, node.Name)
+		}
+
+		if node.OnError != nil && node.OnError.action() == "goto" {
+			return nil, fmt.Errorf("flows.%s node %s onError goto is not supported when concurrency > 1", flow.Name, node.Name)
+		}
+
+		set := map[int]bool{}
+		for _, name := range node.Deps {
+			j := flow.index(name)
+			if j < 0 {
+				return nil, fmt.Errorf("flows.%s node %s deps %s not found", flow.Name, node.Name, name)
+			}
+			if j == i {
+				return nil, fmt.Errorf("flows.%s node %s depends on itself", flow.Name, node.Name)
+			}
+			set[j] = true
+		}
+
+		names, all := node.references()
+		for j := 0; j < i; j++ {
+			if all || (flow.Nodes[j].Name != "" && names[flow.Nodes[j].Name]) {
+				set[j] = true
+			}
+		}
+
+		for j := range set {
+			deps[i] = append(deps[i], j)
+		}
+		sort.Ints(deps[i])
+	}
+
+	if cycle := cycleOf(deps); len(cycle) > 0 {
+		names := []string{}
+		for _, i := range cycle {
+			names = append(names, flow.Nodes[i].Name)
+		}
+		return nil, fmt.Errorf("flows.%s nodes have circular dependencies: %s", flow.Name, strings.Join(names, " -> "))
+	}
+
+	return deps, nil
+}
+
+// references 节点绑定中引用的节点名称, all 为 true 时引用了 $res 全部结果
+func (node *Node) references() (names map[string]bool, all bool) {
+	names = map[string]bool{}
+	bindings := []interface{}{node.Args, node.Outs, node.If, node.Unless}
+	if node.Each != nil {
+		bindings = append(bindings, node.Each.In)
+	}
+	if _, ok := node.Query.(string); !ok {
+		bindings = append(bindings, node.Query)
+	}
+
+	bytes, err := jsoniter.Marshal(bindings)
+	if err != nil {
+		return names, true
+	}
+
+	source := string(bytes)
+	for _, match := range reResRef.FindAllStringSubmatchIndex(source, -1) {
+		if match[4] >= 0 {
+			names[source[match[4]:match[5]]] = true
+			continue
+		}
+
+		// $resource 等非 $res 引用
+		if end := match[1]; end < len(source) && isWord(source[end]) {
+			continue
+		}
+		all = true
+	}
+	return names, all
+}
+
+func isWord(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// cycleOf 检查循环依赖, 返回环上的节点下标
+func cycleOf(deps [][]int) []int {
+	const (
+		white = iota
+		gray
+		black
+	)
+
+	color := make([]int, len(deps))
+	stack := []int{}
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		color[i] = gray
+		stack = append(stack, i)
+		for _, j := range deps[i] {
+			switch color[j] {
+			case gray:
+				for k, n := range stack {
+					if n == j {
+						return append(append([]int{}, stack[k:]...), j)
+					}
+				}
+			case white:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		color[i] = black
+		return nil
+	}
+
+	for i := range deps {
+		if color[i] == white {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// execGraph 按依赖关系并行执行节点, 最多同时执行 flow.Concurrency 个节点
+// 任一节点出错时取消上下文, 不再启动新节点, 等待执行中的节点结束后返回第一个错误
+func (flow *Flow) execGraph(ctx *Context) error {
+	deps, err := flow.Dependencies()
+	if err != nil {
+		return err
+	}
+
+	type result struct {
+		index int
+		err   error
+	}
+
+	pending := make([]int, len(deps))
+	children := make([][]int, len(deps))
+	queue := []int{}
+	for i, items := range deps {
+		pending[i] = len(items)
+		for _, j := range items {
+			children[j] = append(children[j], i)
+		}
+		if pending[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	done := make(chan result, len(deps))
+	running := 0
+	var first error
+	for {
+		for first == nil && running < flow.Concurrency && len(queue) > 0 {
+			if err := (*ctx.Context).Err(); err != nil {
+				first = err
+				break
+			}
+
+			i := queue[0]
+			queue = queue[1:]
+			running++
+			go func(i int) {
+				var err error
+				defer func() {
+					if r := recover(); r != nil {
+						err = exception.Catch(r)
+					}
+					done <- result{index: i, err: err}
+				}()
+				node := flow.Nodes[i]
+				_, err = flow.ExecNode(&node, ctx, i-1)
+			}(i)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+		if res.err != nil {
+			if first == nil {
+				first = res.err
+				ctx.Cancel()
+			}
+			continue
+		}
+
+		for _, child := range children[res.index] {
+			pending[child]--
+			if pending[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+		sort.Ints(queue)
+	}
+
+	return first
+}
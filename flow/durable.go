@@ -0,0 +1,447 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/exception"
+)
+
+// 工作流实例状态
+const (
+	StatusRunning   = "running"
+	StatusWaiting   = "waiting"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+// InstancePrefix 工作流实例存储键前缀
+var InstancePrefix = "flow:instance:"
+
+// instances 本进程中执行的工作流实例
+var instances = struct {
+	sync.Mutex
+	locks   map[string]*sync.Mutex
+	cancels map[string]context.CancelFunc
+}{locks: map[string]*sync.Mutex{}, cancels: map[string]context.CancelFunc{}}
+
+// Durable 持久化工作流配置, 节点按顺序执行, 每个节点执行后保存实例状态, 可等待外部信号后恢复执行
+type Durable struct {
+	Store string `json:"store"`         // 存储名称 (stores/*.yao), 使用 xun 类型存储时保存至数据库
+	TTL   int    `json:"ttl,omitempty"` // 实例保留时长 (秒), 0 为永久保留
+}
+
+// Instance 持久化工作流实例
+type Instance struct {
+	ID        string                 `json:"id"`
+	Flow      string                 `json:"flow"`
+	Status    string                 `json:"status"`
+	In        []interface{}          `json:"in"`
+	Res       map[string]interface{} `json:"res"`
+	Errors    map[string]interface{} `json:"errors,omitempty"`
+	Global    map[string]interface{} `json:"global,omitempty"`
+	Sid       string                 `json:"sid,omitempty"`
+	Current   int                    `json:"current"`           // 下一个执行的节点下标
+	Waiting   string                 `json:"waiting,omitempty"` // 等待的信号名称
+	Signals   map[string]interface{} `json:"signals,omitempty"` // 已收到尚未处理的信号
+	Steps     []Step                 `json:"steps"`
+	Output    interface{}            `json:"output,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	CreatedAt int64                  `json:"created_at"`
+	UpdatedAt int64                  `json:"updated_at"`
+}
+
+// Step 节点执行记录
+type Step struct {
+	Node      string        `json:"node"`
+	Args      []interface{} `json:"args,omitempty"`
+	Output    interface{}   `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	StartedAt int64         `json:"started_at"`
+	EndedAt   int64         `json:"ended_at"`
+}
+
+// Start 启动持久化工作流实例, 执行至完成、失败或等待外部信号
+func (flow *Flow) Start(args ...interface{}) (*Instance, error) {
+	stor, err := flow.store()
+	if err != nil {
+		return nil, err
+	}
+
+	flowProcess := "flows." + flow.Name
+	for _, node := range flow.Nodes {
+		if strings.HasPrefix(node.Process, flowProcess) {
+			return nil, fmt.Errorf("cannot call self flow(%s)", node.Process)
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	inst := &Instance{
+		ID:        uuid.NewString(),
+		Flow:      flow.ID,
+		Status:    StatusRunning,
+		In:        args,
+		Res:       map[string]interface{}{},
+		Global:    flow.Global,
+		Sid:       flow.Sid,
+		Steps:     []Step{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := flow.save(stor, inst); err != nil {
+		return nil, err
+	}
+	return flow.resume(stor, inst)
+}
+
+// Signal 发送外部信号, 实例正在等待该信号时恢复执行
+func (flow *Flow) Signal(id string, signal string, payload interface{}) (*Instance, error) {
+	stor, err := flow.store()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := instanceLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	inst, err := flow.load(stor, id)
+	defer func() { releaseLock(id, inst) }()
+	if err != nil {
+		return nil, err
+	}
+
+	if inst.Status != StatusWaiting && inst.Status != StatusRunning {
+		return nil, fmt.Errorf("flows.%s instance %s is %s", flow.ID, id, inst.Status)
+	}
+
+	if inst.Signals == nil {
+		inst.Signals = map[string]interface{}{}
+	}
+	inst.Signals[signal] = payload
+
+	if inst.Status != StatusWaiting || inst.Waiting != signal {
+		return inst, flow.save(stor, inst)
+	}
+	return flow.proceed(stor, inst)
+}
+
+// Query 查询工作流实例
+func (flow *Flow) Query(id string) (*Instance, error) {
+	stor, err := flow.store()
+	if err != nil {
+		return nil, err
+	}
+	return flow.load(stor, id)
+}
+
+// Cancel 取消工作流实例, 正在本进程中执行的实例将被中断
+func (flow *Flow) Cancel(id string) (*Instance, error) {
+	stor, err := flow.store()
+	if err != nil {
+		return nil, err
+	}
+
+	// 先中断本进程中的执行, 执行中断后释放实例锁
+	instances.Lock()
+	cancel, running := instances.cancels[id]
+	instances.Unlock()
+	if running {
+		cancel()
+	}
+
+	lock := instanceLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	inst, err := flow.load(stor, id)
+	defer func() { releaseLock(id, inst) }()
+	if err != nil {
+		return nil, err
+	}
+
+	if inst.finished() {
+		return inst, nil
+	}
+
+	inst.Status = StatusCanceled
+	inst.Waiting = ""
+	return inst, flow.save(stor, inst)
+}
+
+// resume 加锁后执行实例
+func (flow *Flow) resume(stor store.Store, inst *Instance) (*Instance, error) {
+	lock := instanceLock(inst.ID)
+	lock.Lock()
+	defer lock.Unlock()
+	defer releaseLock(inst.ID, inst)
+	return flow.proceed(stor, inst)
+}
+
+// proceed 从 inst.Current 开始顺序执行节点, 每个节点执行后保存实例
+func (flow *Flow) proceed(stor store.Store, inst *Instance) (*Instance, error) {
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	instances.Lock()
+	instances.cancels[inst.ID] = cancel
+	instances.Unlock()
+	defer func() {
+		instances.Lock()
+		delete(instances.cancels, inst.ID)
+		instances.Unlock()
+	}()
+
+	if inst.Res == nil {
+		inst.Res = map[string]interface{}{}
+	}
+
+	flow.Global = inst.Global
+	flow.Sid = inst.Sid
+	ctx := &Context{Context: &c, Cancel: cancel, In: inst.In, Res: inst.Res, Errors: inst.Errors}
+	inst.Status = StatusRunning
+	inst.Waiting = ""
+
+	steps := 0
+	for i := inst.Current; i < len(flow.Nodes); {
+		if flow.canceled(stor, inst, c) {
+			return inst, nil
+		}
+
+		steps++
+		if steps > MaxSteps {
+			return flow.failed(stor, inst, ctx, fmt.Errorf("flows.%s exceeds the max steps %d", flow.ID, MaxSteps))
+		}
+
+		node := flow.Nodes[i]
+		inst.Current = i
+
+		// 等待外部信号, 信号数据作为节点结果
+		if node.Wait != "" {
+			run, err := flow.should(&node, flow.data(ctx))
+			if err != nil {
+				return flow.failed(stor, inst, ctx, err)
+			}
+
+			if run {
+				payload, has := inst.Signals[node.Wait]
+				if !has {
+					inst.Status = StatusWaiting
+					inst.Waiting = node.Wait
+					_, err := flow.persist(stor, inst)
+					return inst, err
+				}
+
+				delete(inst.Signals, node.Wait)
+				now := time.Now().UnixMilli()
+				ctx.set(node.Name, payload)
+				inst.Steps = append(inst.Steps, Step{Node: node.Name, Output: payload, StartedAt: now, EndedAt: now})
+			}
+
+			inst.Current = i + 1
+			if canceled, err := flow.persist(stor, inst); canceled || err != nil {
+				return inst, err
+			}
+			i++
+			continue
+		}
+
+		step := Step{Node: node.Name, StartedAt: time.Now().UnixMilli()}
+		data := flow.data(ctx)
+		for _, arg := range node.Args {
+			step.Args = append(step.Args, helper.Bind(arg, data))
+		}
+
+		err := flow.step(&node, ctx, i)
+		step.EndedAt = time.Now().UnixMilli()
+		step.Output = ctx.Res[node.Name]
+		if err != nil {
+			step.Error = err.Error()
+			inst.Steps = append(inst.Steps, step)
+			if c.Err() != nil {
+				flow.canceled(stor, inst, c)
+				return inst, nil
+			}
+			return flow.failed(stor, inst, ctx, err)
+		}
+		inst.Steps = append(inst.Steps, step)
+
+		next := i + 1
+		if ctx.Goto != "" {
+			next = flow.index(ctx.Goto)
+			if next < 0 {
+				return flow.failed(stor, inst, ctx, fmt.Errorf("flows.%s node %s goto %s not found", flow.ID, node.Name, ctx.Goto))
+			}
+			ctx.Goto = ""
+		}
+
+		inst.Current = next
+		inst.Errors = ctx.Errors
+		inst.Sid = flow.Sid
+		if canceled, err := flow.persist(stor, inst); canceled || err != nil {
+			return inst, err
+		}
+		i = next
+	}
+
+	if flow.Finally != nil {
+		if err := flow.execFinally(ctx, nil); err != nil {
+			return flow.failed(stor, inst, ctx, err)
+		}
+	}
+
+	output, err := flow.FormatResult(ctx)
+	if err != nil {
+		return flow.failed(stor, inst, ctx, err)
+	}
+
+	inst.Status = StatusCompleted
+	inst.Current = len(flow.Nodes)
+	inst.Errors = ctx.Errors
+	inst.Output = output
+	_, err = flow.persist(stor, inst)
+	return inst, err
+}
+
+// step 执行节点, 处理器异常转换为错误
+func (flow *Flow) step(node *Node, ctx *Context, i int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+	_, err = flow.ExecNode(node, ctx, i-1)
+	return err
+}
+
+// failed 保存执行失败的实例 (执行 finally 节点)
+func (flow *Flow) failed(stor store.Store, inst *Instance, ctx *Context, err error) (*Instance, error) {
+	if flow.Finally != nil {
+		flow.execFinally(ctx, err)
+	}
+
+	inst.Status = StatusFailed
+	inst.Error = err.Error()
+	inst.Errors = ctx.Errors
+	canceled, serr := flow.persist(stor, inst)
+	if serr != nil {
+		return inst, serr
+	}
+	if canceled {
+		return inst, nil
+	}
+	return inst, err
+}
+
+// canceled 检查实例是否已取消 (本进程取消或其他进程修改了存储中的状态)
+func (flow *Flow) canceled(stor store.Store, inst *Instance, c context.Context) bool {
+	if c.Err() == nil {
+		saved, err := flow.load(stor, inst.ID)
+		if err != nil || saved.Status != StatusCanceled {
+			return false
+		}
+	}
+
+	inst.Status = StatusCanceled
+	inst.Waiting = ""
+	flow.save(stor, inst)
+	return true
+}
+
+// persist 保存执行中的实例, 保存前重新读取存储中的状态.
+// 实例已被其他进程取消时不覆盖存储中的实例, 返回 true
+func (flow *Flow) persist(stor store.Store, inst *Instance) (bool, error) {
+	if saved, err := flow.load(stor, inst.ID); err == nil && saved.Status == StatusCanceled {
+		inst.Status = StatusCanceled
+		inst.Waiting = ""
+		return true, nil
+	}
+	return false, flow.save(stor, inst)
+}
+
+// finished 实例是否已进入终止状态 (完成, 失败, 取消)
+func (inst *Instance) finished() bool {
+	return inst.Status == StatusCompleted || inst.Status == StatusFailed || inst.Status == StatusCanceled
+}
+
+// store 持久化存储
+func (flow *Flow) store() (store.Store, error) {
+	if flow.Durable == nil || flow.Durable.Store == "" {
+		return nil, fmt.Errorf("flows.%s is not durable", flow.ID)
+	}
+	return store.Get(flow.Durable.Store)
+}
+
+// save 保存实例
+func (flow *Flow) save(stor store.Store, inst *Instance) error {
+	inst.UpdatedAt = time.Now().UnixMilli()
+	bytes, err := jsoniter.Marshal(inst)
+	if err != nil {
+		return fmt.Errorf("flows.%s instance %s %s", flow.ID, inst.ID, err.Error())
+	}
+	return stor.Set(InstancePrefix+inst.ID, string(bytes), time.Duration(flow.Durable.TTL)*time.Second)
+}
+
+// load 读取实例
+func (flow *Flow) load(stor store.Store, id string) (*Instance, error) {
+	value, has := stor.Get(InstancePrefix + id)
+	if !has {
+		return nil, fmt.Errorf("flows.%s instance %s not found", flow.ID, id)
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		var err error
+		bytes, err = jsoniter.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("flows.%s instance %s %s", flow.ID, id, err.Error())
+		}
+	}
+
+	inst := &Instance{}
+	if err := jsoniter.Unmarshal(bytes, inst); err != nil {
+		return nil, fmt.Errorf("flows.%s instance %s %s", flow.ID, id, err.Error())
+	}
+
+	if inst.Flow != flow.ID {
+		return nil, fmt.Errorf("flows.%s instance %s belongs to flows.%s", flow.ID, id, inst.Flow)
+	}
+	return inst, nil
+}
+
+// instanceLock 实例执行锁
+func instanceLock(id string) *sync.Mutex {
+	instances.Lock()
+	defer instances.Unlock()
+	lock, has := instances.locks[id]
+	if !has {
+		lock = &sync.Mutex{}
+		instances.locks[id] = lock
+	}
+	return lock
+}
+
+// releaseLock 实例已进入终止状态或不存在时删除实例执行锁, 需持有实例锁时调用.
+// 等待同一把锁的调用读取实例后不再执行, 不会与新建的锁并发执行实例
+func releaseLock(id string, inst *Instance) {
+	if inst != nil && !inst.finished() {
+		return
+	}
+	instances.Lock()
+	delete(instances.locks, id)
+	instances.Unlock()
+}
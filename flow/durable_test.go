@@ -0,0 +1,187 @@
+package flow
+
+import (
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/store"
+)
+
+func TestDurable(t *testing.T) {
+	prepareDurable(t)
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+
+	flow := &Flow{ID: "approval", Name: "approval", Durable: &Durable{Store: "flow-test"}, Nodes: []Node{
+		{Name: "order", Process: "flowtest.echo", Args: []interface{}{"?:$in.0"}},
+		{Name: "approval", Wait: "approve"},
+		{Name: "result", Process: "flowtest.echo", Args: []interface{}{"?:$res.approval.by"}},
+	}}
+	Flows["approval"] = flow
+	defer delete(Flows, "approval")
+
+	inst, err := flow.Start("order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusWaiting, inst.Status)
+	assert.Equal(t, "approve", inst.Waiting)
+	assert.Equal(t, 1, inst.Current)
+	assert.Equal(t, "order-1", inst.Res["order"])
+	assert.Len(t, inst.Steps, 1)
+	assert.Equal(t, []interface{}{"order-1"}, inst.Steps[0].Args)
+
+	// Other signals are kept until the instance waits for them
+	inst, err = flow.Signal(inst.ID, "other", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusWaiting, inst.Status)
+
+	inst, err = execDurable("flow.Signal", "approval", inst.ID, "approve", map[string]interface{}{"by": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusCompleted, inst.Status)
+	assert.Equal(t, "admin", inst.Res["result"])
+	assert.Len(t, inst.Steps, 3)
+
+	saved, err := execDurable("flow.Query", "approval", inst.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusCompleted, saved.Status)
+	assert.Equal(t, "admin", saved.Res["result"])
+
+	_, err = flow.Signal(inst.ID, "approve", nil)
+	assert.NotNil(t, err)
+
+	_, err = flow.Query("not-found")
+	assert.NotNil(t, err)
+}
+
+func TestDurableCancel(t *testing.T) {
+	prepareDurable(t)
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+
+	flow := &Flow{ID: "cancel", Name: "cancel", Durable: &Durable{Store: "flow-test"}, Nodes: []Node{
+		{Name: "approval", Wait: "approve"},
+		{Name: "result", Process: "flowtest.echo", Args: []interface{}{"done"}},
+	}}
+	Flows["cancel"] = flow
+	defer delete(Flows, "cancel")
+
+	inst, err := execDurable("flow.Start", "cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusWaiting, inst.Status)
+
+	inst, err = execDurable("flow.Cancel", "cancel", inst.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusCanceled, inst.Status)
+
+	_, err = flow.Signal(inst.ID, "approve", nil)
+	assert.NotNil(t, err)
+
+	instances.Lock()
+	_, has := instances.locks[inst.ID]
+	instances.Unlock()
+	assert.False(t, has)
+
+	_, err = (&Flow{ID: "memory"}).Start()
+	assert.NotNil(t, err)
+}
+
+func TestDurableCanceledByOthers(t *testing.T) {
+	prepareDurable(t)
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+
+	// another process cancels the instance while the node is running
+	process.Register("flowtest.cancelothers", func(p *process.Process) interface{} {
+		stor := store.Pools["flow-test"]
+		for _, key := range stor.Keys() {
+			if !strings.HasPrefix(key, InstancePrefix) {
+				continue
+			}
+			value, _ := stor.Get(key)
+			saved := &Instance{}
+			jsoniter.Unmarshal([]byte(value.(string)), saved)
+			saved.Status = StatusCanceled
+			bytes, _ := jsoniter.Marshal(saved)
+			stor.Set(key, string(bytes), 0)
+		}
+		return "canceled"
+	})
+
+	flow := &Flow{ID: "others", Name: "others", Durable: &Durable{Store: "flow-test"}, Nodes: []Node{
+		{Name: "cancel", Process: "flowtest.cancelothers"},
+		{Name: "result", Process: "flowtest.echo", Args: []interface{}{"done"}},
+	}}
+
+	inst, err := flow.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusCanceled, inst.Status)
+	assert.Nil(t, inst.Res["result"])
+
+	saved, err := flow.Query(inst.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusCanceled, saved.Status)
+	assert.Empty(t, saved.Steps)
+
+	// the locks of the finished instances are removed
+	instances.Lock()
+	_, has := instances.locks[inst.ID]
+	instances.Unlock()
+	assert.False(t, has)
+}
+
+func TestDurableFailed(t *testing.T) {
+	prepareDurable(t)
+	process.Register("flowtest.error", func(p *process.Process) interface{} { panic("node failed") })
+
+	flow := &Flow{ID: "failed", Name: "failed", Durable: &Durable{Store: "flow-test"}, Nodes: []Node{
+		{Name: "error", Process: "flowtest.error"},
+	}}
+
+	inst, err := flow.Start()
+	assert.NotNil(t, err)
+	assert.Equal(t, StatusFailed, inst.Status)
+	assert.Contains(t, inst.Error, "node failed")
+
+	saved, err := flow.Query(inst.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusFailed, saved.Status)
+	assert.Contains(t, saved.Steps[0].Error, "node failed")
+}
+
+func prepareDurable(t *testing.T) {
+	stor, err := store.New(nil, store.Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Pools["flow-test"] = stor
+}
+
+func execDurable(name string, args ...interface{}) (*Instance, error) {
+	p, err := process.Of(name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.Exec()
+	if err != nil {
+		return nil, err
+	}
+	return res.(*Instance), nil
+}
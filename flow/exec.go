@@ -3,42 +3,140 @@ package flow
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/query"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/maps"
 )
 
+// MaxSteps 单次执行最多运行的节点数量 (防止 switch 跳转形成死循环)
+var MaxSteps = 10000
+
 // Exec execute flow
 func (flow *Flow) Exec(args ...interface{}) (interface{}, error) {
+	return flow.ExecContext(context.Background(), args...)
+}
+
+// ExecContext execute flow with the parent context (开启 debug 时记录执行跟踪)
+func (flow *Flow) ExecContext(parent context.Context, args ...interface{}) (interface{}, error) {
+	var trace *Trace
+	if flow.Debug {
+		trace = &Trace{Flow: flow.Name, Args: args, StartedAt: time.Now(), Nodes: []TraceNode{}}
+	}
+	return flow.exec(parent, trace, args...)
+}
+
+func (flow *Flow) exec(parent context.Context, trace *Trace, args ...interface{}) (output interface{}, err error) {
+
+	parent, end := flow.startTrace(parent, trace)
+	defer func() {
+		// 节点异常时同样记录跟踪信息及 Span 错误
+		if r := recover(); r != nil {
+			end(nil, exception.Catch(r))
+			panic(r)
+		}
+		end(output, err)
+	}()
 
 	res := map[string]interface{}{} // 结果集
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
 
 	flowCtx := &Context{
 		Context: &ctx,
 		Cancel:  cancel,
 		Res:     res,
 		In:      args,
+		Trace:   trace,
+		span:    parent,
 	}
 
 	flowProcess := "flows." + flow.Name
-	for i, node := range flow.Nodes {
-
+	nodes := flow.Nodes
+	if flow.Finally != nil {
+		nodes = append([]Node{*flow.Finally}, nodes...)
+	}
+	for _, node := range nodes {
 		if strings.HasPrefix(node.Process, flowProcess) {
 			return nil, fmt.Errorf("cannot call self flow(%s)", node.Process)
 		}
+	}
 
-		_, err := flow.ExecNode(&node, flowCtx, i-1)
-		if err != nil {
-			return nil, err
+	if flow.Finally != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				flow.execFinally(flowCtx, exception.Catch(r))
+				panic(r)
+			}
+		}()
+	}
+
+	if flow.Concurrency > 1 {
+		err = flow.execGraph(flowCtx)
+	} else {
+		err = flow.execSequence(flowCtx)
+	}
+
+	if flow.Finally != nil {
+		if ferr := flow.execFinally(flowCtx, err); err == nil {
+			err = ferr
 		}
 	}
 
+	if err != nil {
+		return nil, err
+	}
+
 	return flow.FormatResult(flowCtx)
 }
 
+// execSequence 按顺序执行节点 (支持 switch 跳转)
+func (flow *Flow) execSequence(ctx *Context) error {
+	steps := 0
+	for i := 0; i < len(flow.Nodes); {
+		steps++
+		if steps > MaxSteps {
+			return fmt.Errorf("flows.%s exceeds the max steps %d", flow.Name, MaxSteps)
+		}
+
+		node := flow.Nodes[i]
+		_, err := flow.ExecNode(&node, ctx, i-1)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Goto == "" {
+			i++
+			continue
+		}
+
+		next := flow.index(ctx.Goto)
+		if next < 0 {
+			return fmt.Errorf("flows.%s node %s goto %s not found", flow.Name, node.Name, ctx.Goto)
+		}
+		ctx.Goto = ""
+		i = next
+	}
+	return nil
+}
+
+// index 按名称查找节点下标, 未找到返回 -1
+func (flow *Flow) index(name string) int {
+	for i, node := range flow.Nodes {
+		if node.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // ExtendIn Extend params
 func (ctx *Context) ExtendIn(data maps.Map) maps.Map {
 	if len(ctx.In) < 1 {
@@ -67,79 +165,320 @@ func (flow *Flow) FormatResult(ctx *Context) (interface{}, error) {
 
 // ExecNode Execute node
 func (flow *Flow) ExecNode(node *Node, ctx *Context, prev int) ([]interface{}, error) {
-	data := maps.Map{"$in": ctx.In, "$res": ctx.Res, "$global": flow.Global}
-	data = ctx.ExtendIn(data).Dot()
+	if ctx.Trace != nil || tracer != nil {
+		return flow.traceNode(node, ctx, prev)
+	}
+	return flow.execNode(*ctx.Context, node, ctx, prev)
+}
+
+// execNode 执行节点, c 为节点处理器的上下文 (开启链路追踪时携带节点 Span)
+func (flow *Flow) execNode(c context.Context, node *Node, ctx *Context, prev int) ([]interface{}, error) {
+	data := flow.data(ctx)
 	var outs = []interface{}{}
 	var err error
 
-	if node.DSL != nil {
-		_, outs, err = flow.RunQuery(node, ctx, data)
+	run, err := flow.should(node, data)
+	if err != nil || !run {
+		return outs, err
+	}
+
+	if node.Timeout > 0 || node.Retry != nil || node.OnError != nil {
+		outs, err = flow.guard(c, node, ctx, data)
+	} else {
+		_, outs, err = flow.run(c, node, ctx, data)
+	}
+
+	if err != nil || node.Switch == nil || ctx.Goto != "" {
 		return outs, err
 	}
 
-	_, outs, err = flow.RunProcess(node, ctx, data)
+	ctx.Goto, err = flow.route(node, flow.data(ctx))
 	return outs, err
 }
 
-// RunQuery execute Query DSL
-func (flow *Flow) RunQuery(node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+// run 执行节点并保存节点结果
+func (flow *Flow) run(c context.Context, node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+	if node.Each != nil {
+		return flow.runEach(c, node, ctx, data)
+	}
 
-	var res interface{}
-	outs := []interface{}{}
-	resp := node.DSL.Run(data)
+	resp, err := flow.call(c, node, ctx, data)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if node.Outs == nil || len(node.Outs) == 0 {
-		res = resp
+	// 已超时或已取消的节点不再保存结果
+	if err := c.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	res, outs := flow.output(node, data, resp)
+	ctx.set(node.Name, res)
+	return resp, outs, nil
+}
+
+// data 节点绑定数据, 并发执行时其他节点会写入结果, 使用结果集的副本绑定
+func (flow *Flow) data(ctx *Context) maps.Map {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	data := maps.Map{"$in": ctx.In, "$res": copyOf(ctx.Res), "$global": flow.Global, "$errors": copyOf(ctx.Errors)}
+	if ctx.Error != nil {
+		data["$error"] = ctx.Error.Error()
+	}
+	return ctx.ExtendIn(data).Dot()
+}
+
+// should 计算 if/unless 条件, 返回是否执行节点
+func (flow *Flow) should(node *Node, data maps.Map) (bool, error) {
+	if node.If != nil {
+		ok, err := Eval(node.If, data)
+		if err != nil {
+			return false, fmt.Errorf("flows.%s node %s if: %s", flow.Name, node.Name, err.Error())
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	if node.Unless != nil {
+		ok, err := Eval(node.Unless, data)
+		if err != nil {
+			return false, fmt.Errorf("flows.%s node %s unless: %s", flow.Name, node.Name, err.Error())
+		}
+		if ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// route 依次匹配 switch cases, 返回跳转的节点名称
+func (flow *Flow) route(node *Node, data maps.Map) (string, error) {
+	value := helper.Bind(node.Switch.Value, data)
+	for i, c := range node.Switch.Cases {
+		if c.If != nil {
+			ok, err := Eval(c.If, data)
+			if err != nil {
+				return "", fmt.Errorf("flows.%s node %s switch cases[%d]: %s", flow.Name, node.Name, i, err.Error())
+			}
+			if ok {
+				return c.Goto, nil
+			}
+			continue
+		}
+
+		if compare("==", value, helper.Bind(c.Value, data)) {
+			return c.Goto, nil
+		}
+	}
+	return node.Switch.Default, nil
+}
+
+// RunEach 遍历数组执行节点, 结果按下标顺序返回
+func (flow *Flow) RunEach(node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+	return flow.runEach(*ctx.Context, node, ctx, data)
+}
+
+func (flow *Flow) runEach(c context.Context, node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+
+	items := []interface{}{}
+	if in := helper.Bind(node.Each.In, data); in != nil {
+		values := reflect.ValueOf(in)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return nil, nil, fmt.Errorf("flows.%s node %s each.in should be an array, got %T", flow.Name, node.Name, in)
+		}
+		for i := 0; i < values.Len(); i++ {
+			items = append(items, values.Index(i).Interface())
+		}
+	}
+
+	resp := make([]interface{}, len(items))
+	errs := make([]error, len(items))
+	exec := func(i int) {
+		defer func() {
+			if r := recover(); r != nil {
+				errs[i] = exception.Catch(r)
+			}
+		}()
+
+		item := maps.Map{}
+		for key, value := range data {
+			item[key] = value
+		}
+		item["$item"] = items[i]
+		item["$index"] = i
+		item = item.Dot()
+
+		value, err := flow.call(c, node, ctx, item)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		res, _ := flow.output(node, item, value)
+		resp[i] = res
+	}
+
+	if node.Each.Concurrency < 2 {
+		for i := range items {
+			if exec(i); errs[i] != nil {
+				return nil, nil, errs[i]
+			}
+		}
 	} else {
-		data["$out"] = resp
-		data = data.Dot()
-		for _, value := range node.Outs {
-			outs = append(outs, helper.Bind(value, data))
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, node.Each.Concurrency)
+		for i := range items {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(i int) {
+				defer func() { <-sem; wg.Done() }()
+				exec(i)
+			}(i)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return nil, nil, err
+			}
 		}
-		res = outs
 	}
 
-	if node.Name != "" {
-		ctx.Res[node.Name] = res
+	if err := c.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx.set(node.Name, resp)
+	return resp, []interface{}{}, nil
+}
+
+// RunQuery execute Query DSL
+func (flow *Flow) RunQuery(node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+	resp := node.DSL.Run(data)
+	res, outs := flow.output(node, data, resp)
+	ctx.set(node.Name, res)
+	return resp, outs, nil
+}
+
+// RunNamedQuery execute the named query, the first argument is the query params
+func (flow *Flow) RunNamedQuery(node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+	resp, err := flow.runNamedQuery(node, data)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	res, outs := flow.output(node, data, resp)
+	ctx.set(node.Name, res)
 	return resp, outs, nil
 }
 
 // RunProcess exec process
 func (flow *Flow) RunProcess(node *Node, ctx *Context, data maps.Map) (interface{}, []interface{}, error) {
+	resp := flow.runProcess(*ctx.Context, node, ctx, data)
+	res, outs := flow.output(node, data, resp)
+	ctx.set(node.Name, res)
+	return resp, outs, nil
+}
+
+// call 执行节点 (Query DSL, 命名查询或处理器), 返回执行结果
+func (flow *Flow) call(c context.Context, node *Node, ctx *Context, data maps.Map) (interface{}, error) {
+	if node.DSL != nil {
+		data[share.ProcessKey] = "flows." + flow.Name
+		return node.DSL.Run(data), nil
+	}
+
+	if name, ok := node.Query.(string); ok && name != "" {
+		return flow.runNamedQuery(node, data)
+	}
+
+	return flow.runProcess(c, node, ctx, data), nil
+}
+
+func (flow *Flow) runNamedQuery(node *Node, data maps.Map) (interface{}, error) {
+	named, err := query.SelectQuery(node.Query.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{}
+	if len(node.Args) > 0 {
+		if values, ok := helper.Bind(node.Args[0], data).(map[string]interface{}); ok {
+			params = values
+		}
+	}
+	return named.Run(params)
+}
+
+func (flow *Flow) runProcess(c context.Context, node *Node, ctx *Context, data maps.Map) interface{} {
+	if node.Process == "" {
+		return nil
+	}
 
 	args := []interface{}{}
-	outs := []interface{}{}
-	var resp interface{}
-	var res interface{}
 	for _, arg := range node.Args {
 		args = append(args, helper.Bind(arg, data))
 	}
 
-	if node.Process != "" {
-		process := process.New(node.Process, args...).WithGlobal(flow.Global).WithSID(flow.Sid)
-		resp = process.Run()
+	process := process.NewWithContext(c, node.Process, args...).WithGlobal(flow.Global).WithSID(flow.Sid)
+	resp := process.Run()
 
-		// 当使用 Session start 设置SID时
-		// 设置SID (这个逻辑需要优化)
-		if flow.Sid == "" && process.Sid != "" {
-			flow.WithSID(process.Sid)
-		}
+	// 当使用 Session start 设置SID时
+	// 设置SID (这个逻辑需要优化)
+	ctx.mutex.Lock()
+	if flow.Sid == "" && process.Sid != "" {
+		flow.WithSID(process.Sid)
 	}
+	ctx.mutex.Unlock()
+	return resp
+}
 
+// output 使用 outs 处理执行结果, 返回节点结果
+func (flow *Flow) output(node *Node, data maps.Map, resp interface{}) (interface{}, []interface{}) {
+	outs := []interface{}{}
 	if node.Outs == nil || len(node.Outs) == 0 {
-		res = resp
-	} else {
-		data["$out"] = resp
-		data = data.Dot()
-		for _, value := range node.Outs {
-			outs = append(outs, helper.Bind(value, data))
-		}
-		res = outs
+		return resp, outs
 	}
 
-	if node.Name != "" {
-		ctx.Res[node.Name] = res
+	data["$out"] = resp
+	data = data.Dot()
+	for _, value := range node.Outs {
+		outs = append(outs, helper.Bind(value, data))
 	}
-	return resp, outs, nil
+	return outs, outs
+}
+
+// setError 保存已处理的节点错误信息
+func (ctx *Context) setError(name string, err error) {
+	if name == "" {
+		return
+	}
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	if ctx.Errors == nil {
+		ctx.Errors = map[string]interface{}{}
+	}
+	ctx.Errors[name] = err.Error()
+}
+
+// copyOf 复制结果集 (浅拷贝)
+func copyOf(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		res[key] = value
+	}
+	return res
+}
+
+// set 保存节点结果
+func (ctx *Context) set(name string, value interface{}) {
+	if name == "" {
+		return
+	}
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	ctx.Res[name] = value
 }
@@ -1,11 +1,16 @@
 package flow
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/query"
+	"github.com/yaoapp/gou/query/share"
 	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/maps"
 )
 
 func TestExec(t *testing.T) {
@@ -72,3 +77,244 @@ func TestExec(t *testing.T) {
 // 	assert.Equal(t, float64(1), res.Get("脚本数据.session.id"))
 // 	assert.Equal(t, "admin", res.Get("脚本数据.session.type"))
 // }
+
+// echoEngine returns the binding data as the only record
+type echoEngine struct{}
+
+func (engine *echoEngine) Load(data interface{}) (share.DSL, error) { return engine, nil }
+func (engine *echoEngine) Run(data maps.Map) interface{}            { return engine.Get(data) }
+func (engine *echoEngine) Get(data maps.Map) []share.Record {
+	return []share.Record{{"status": data["status"], "limit": data["limit"]}}
+}
+func (engine *echoEngine) Paginate(data maps.Map) share.Paginate {
+	return share.Paginate{Items: engine.Get(data)}
+}
+func (engine *echoEngine) First(data maps.Map) share.Record { return engine.Get(data)[0] }
+
+func TestExecNamedQuery(t *testing.T) {
+	query.Register("flow-echo", &echoEngine{})
+	defer query.Unregister("flow-echo")
+
+	_, err := query.LoadSource([]byte(`{
+		"engine": "flow-echo",
+		"params": {"status": {"type": "string"}, "limit": {"type": "integer", "default": 10}},
+		"query": {"select": ["id"], "from": "user", "wheres": [{"field": "status", "=": "?:status"}], "limit": "?:limit"}
+	}`), "queries/flow/users.query.json", "flow.users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer query.UnloadQuery("flow.users")
+
+	flow := &Flow{Name: "named", Nodes: []Node{
+		{Name: "users", Query: "flow.users", Args: []interface{}{map[string]interface{}{"status": "?:$in.0"}}},
+		{Name: "first", Query: "flow.users", Args: []interface{}{map[string]interface{}{"status": "off", "limit": "2"}}},
+	}}
+	flow.prepare()
+
+	res, err := flow.Exec("on")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := any.Of(res).MapStr().Dot()
+	assert.Equal(t, "on", r.Get("users[0].status"))
+	assert.Equal(t, 10, r.Get("users[0].limit"))
+	assert.Equal(t, "off", r.Get("first[0].status"))
+	assert.Equal(t, 2, r.Get("first[0].limit"))
+
+	flow.Nodes[0].Query = "not.found"
+	_, err = flow.Exec("on")
+	assert.NotNil(t, err)
+}
+
+func TestExecIfUnless(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+
+	flow := &Flow{Name: "cond", Nodes: []Node{
+		{Name: "user", Process: "flowtest.echo", Args: []interface{}{"?:$in.0"}},
+		{Name: "vip", Process: "flowtest.echo", Args: []interface{}{"yes"}, If: "?:$res.user.type == 'vip'"},
+		{Name: "guest", Process: "flowtest.echo", Args: []interface{}{"yes"}, Unless: "?:$res.user.id"},
+		{Name: "adult", Process: "flowtest.echo", Args: []interface{}{"yes"}, If: []interface{}{"?:$res.user.age >= 18", "?:$res.user.type in ['vip', 'member']"}},
+	}}
+
+	res, err := flow.Exec(map[string]interface{}{"id": 1, "type": "vip", "age": 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := res.(map[string]interface{})
+	assert.Equal(t, "yes", r["vip"])
+	assert.Equal(t, "yes", r["adult"])
+	assert.NotContains(t, r, "guest")
+
+	res, err = flow.Exec(map[string]interface{}{"type": "member", "age": 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = res.(map[string]interface{})
+	assert.NotContains(t, r, "vip")
+	assert.NotContains(t, r, "adult")
+	assert.Equal(t, "yes", r["guest"])
+
+	flow.Nodes[1].If = "?:$res.user.type =="
+	_, err = flow.Exec(map[string]interface{}{"type": "vip"})
+	assert.NotNil(t, err)
+}
+
+func TestExecSwitch(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+
+	flow := &Flow{Name: "switch", Nodes: []Node{
+		{Name: "type", Process: "flowtest.echo", Args: []interface{}{"?:$in.0"}, Switch: &Switch{
+			Value:   "?:$res.type",
+			Cases:   []Case{{Value: "admin", Goto: "admin"}, {If: "?:$res.type == 'root'", Goto: "root"}},
+			Default: "guest",
+		}},
+		{Name: "admin", Process: "flowtest.echo", Args: []interface{}{"admin"}, Switch: &Switch{Default: "done"}},
+		{Name: "root", Process: "flowtest.echo", Args: []interface{}{"root"}, Switch: &Switch{Default: "done"}},
+		{Name: "guest", Process: "flowtest.echo", Args: []interface{}{"guest"}},
+		{Name: "done", Process: "flowtest.echo", Args: []interface{}{true}},
+	}}
+	flow.prepare()
+
+	for input, expect := range map[string]string{"admin": "admin", "root": "root", "other": "guest"} {
+		res, err := flow.Exec(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := res.(map[string]interface{})
+		assert.Equal(t, expect, r[expect])
+		assert.Equal(t, true, r["done"])
+		for _, name := range []string{"admin", "root", "guest"} {
+			if name != expect {
+				assert.NotContains(t, r, name)
+			}
+		}
+	}
+
+	flow.Nodes[0].Switch.Default = "not-found"
+	_, err := flow.Exec("other")
+	assert.NotNil(t, err)
+
+	loop := &Flow{Name: "loop", Nodes: []Node{
+		{Name: "again", Process: "flowtest.echo", Args: []interface{}{1}, Switch: &Switch{Default: "again"}},
+	}}
+	_, err = loop.Exec()
+	assert.NotNil(t, err)
+}
+
+func TestExecEach(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+	process.Register("flowtest.fail", func(p *process.Process) interface{} {
+		if p.Args[0] == "bad" {
+			panic("bad item")
+		}
+		return p.Args[0]
+	})
+
+	items := []interface{}{
+		map[string]interface{}{"name": "a"},
+		map[string]interface{}{"name": "b"},
+		map[string]interface{}{"name": "c"},
+	}
+
+	for _, concurrency := range []int{0, 2} {
+		flow := &Flow{Name: "each", Nodes: []Node{
+			{Name: "names", Outs: []interface{}{"?:$item.name", "?:$index"}, Each: &Each{In: "?:$in.0", Concurrency: concurrency}},
+			{Name: "outs", Process: "flowtest.echo", Args: []interface{}{"?:$item"}, Outs: []interface{}{"?:$out.name"}, Each: &Each{In: "?:$in.0", Concurrency: concurrency}},
+		}}
+
+		res, err := flow.Exec(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := res.(map[string]interface{})
+		assert.Equal(t, []interface{}{[]interface{}{"a", 0}, []interface{}{"b", 1}, []interface{}{"c", 2}}, r["names"])
+		assert.Equal(t, []interface{}{[]interface{}{"a"}, []interface{}{"b"}, []interface{}{"c"}}, r["outs"])
+
+		flow.Nodes = []Node{{Name: "fail", Process: "flowtest.fail", Args: []interface{}{"?:$item"}, Each: &Each{In: "?:$in.0", Concurrency: concurrency}}}
+		_, err = flow.Exec([]interface{}{"ok", "bad", "ok"})
+		assert.NotNil(t, err)
+
+		_, err = flow.Exec("not-an-array")
+		assert.NotNil(t, err)
+	}
+}
+
+func TestExecGraph(t *testing.T) {
+	process.Register("flowtest.sleep", func(p *process.Process) interface{} {
+		time.Sleep(100 * time.Millisecond)
+		if p.Args[0] == "fail" {
+			panic("node failed")
+		}
+		return p.Args[0]
+	})
+
+	flow := &Flow{Name: "graph", Concurrency: 3, Nodes: []Node{
+		{Name: "a", Process: "flowtest.sleep", Args: []interface{}{"?:$in.0"}},
+		{Name: "b", Process: "flowtest.sleep", Args: []interface{}{"b"}},
+		{Name: "c", Process: "flowtest.sleep", Args: []interface{}{"c"}},
+		{Name: "sum", Process: "flowtest.sleep", Args: []interface{}{[]interface{}{"{{$res.a}}", "?:$res.b", "?:$res.c"}}},
+		{Name: "last", Process: "flowtest.sleep", Args: []interface{}{"last"}, Deps: []string{"sum"}},
+	}}
+
+	deps, err := flow.Dependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{0, 1, 2}, deps[3])
+	assert.Equal(t, []int{3}, deps[4])
+
+	start := time.Now()
+	res, err := flow.Exec("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Less(t, time.Since(start), 450*time.Millisecond)
+
+	r := res.(map[string]interface{})
+	assert.Equal(t, []interface{}{"a", "b", "c"}, r["sum"])
+	assert.Equal(t, "last", r["last"])
+
+	_, err = flow.Exec("fail")
+	assert.NotNil(t, err)
+
+	flow.Nodes[0].Deps = []string{"last"}
+	_, err = flow.Exec("a")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "circular")
+
+	flow.Nodes[0].Deps = []string{"not-found"}
+	_, err = flow.Exec("a")
+	assert.NotNil(t, err)
+}
+
+// TestExecGraphRace the sibling nodes read $res while the others write their results (go test -race)
+func TestExecGraphRace(t *testing.T) {
+	process.Register("flowtest.race", func(p *process.Process) interface{} {
+		time.Sleep(time.Millisecond)
+		return p.Args[0]
+	})
+
+	nodes := []Node{{Name: "root", Process: "flowtest.race", Args: []interface{}{"root"}}}
+	for i := 0; i < 16; i++ {
+		nodes = append(nodes, Node{
+			Name:    fmt.Sprintf("sibling%d", i),
+			Process: "flowtest.race",
+			Args:    []interface{}{i},
+			Outs:    []interface{}{"?:$res.root", "?:$out"},
+		})
+	}
+
+	flow := &Flow{Name: "race", Concurrency: 8, Nodes: nodes}
+	for n := 0; n < 10; n++ {
+		res, err := flow.Exec()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := res.(map[string]interface{})
+		for i := 0; i < 16; i++ {
+			assert.Equal(t, []interface{}{"root", i}, r[fmt.Sprintf("sibling%d", i)])
+		}
+	}
+}
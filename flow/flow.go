@@ -35,10 +35,17 @@ func Load(file string, id string) (*Flow, error) {
 func (flow *Flow) prepare() {
 
 	for i, node := range flow.Nodes {
+		flow.prepareGoto(node)
+
 		if node.Query == nil {
 			continue
 		}
 
+		// 命名查询 (queries/*.query.yao), 执行时按名称选择
+		if _, ok := node.Query.(string); ok {
+			continue
+		}
+
 		if node.Engine == "" {
 			log.Error("Node %s: 未指定数据查询分析引擎", node.Name)
 			continue
@@ -56,6 +63,27 @@ func (flow *Flow) prepare() {
 	}
 }
 
+// prepareGoto 检查 switch 及 onError 跳转节点是否存在
+func (flow *Flow) prepareGoto(node Node) {
+	targets := []string{}
+	if node.Switch != nil {
+		targets = append(targets, node.Switch.Default)
+		for _, c := range node.Switch.Cases {
+			targets = append(targets, c.Goto)
+		}
+	}
+
+	if node.OnError != nil {
+		targets = append(targets, node.OnError.Goto)
+	}
+
+	for _, name := range targets {
+		if name != "" && flow.index(name) < 0 {
+			log.Error("Node %s: 跳转节点 %s 不存在", node.Name, name)
+		}
+	}
+}
+
 // Reload 重新载入API
 func (flow *Flow) Reload() (*Flow, error) {
 	new, err := Load(flow.File, flow.Name)
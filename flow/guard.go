@@ -0,0 +1,184 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+)
+
+// guard 按节点 timeout, retry, onError 配置执行节点
+func (flow *Flow) guard(parent context.Context, node *Node, ctx *Context, data maps.Map) ([]interface{}, error) {
+	attempts := 1
+	if node.Retry != nil && node.Retry.Count > 0 {
+		attempts += node.Retry.Count
+	}
+
+	var outs []interface{}
+	var err error
+	var running <-chan struct{}
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			// 超时的执行仍在运行, 等待结束后再重试, 避免多次执行重叠
+			if running != nil {
+				select {
+				case <-parent.Done():
+					return flow.fail(node, ctx, data, err)
+				case <-running:
+				}
+			}
+
+			timer := time.NewTimer(node.Retry.delay(i))
+			select {
+			case <-parent.Done():
+				timer.Stop()
+				return flow.fail(node, ctx, data, err)
+			case <-timer.C:
+			}
+		}
+
+		outs, running, err = flow.attempt(parent, node, ctx, data)
+		if err == nil {
+			return outs, nil
+		}
+
+		// 工作流已取消, 不再重试
+		if parent.Err() != nil {
+			break
+		}
+	}
+
+	return flow.fail(node, ctx, data, err)
+}
+
+// attempt 执行一次节点, 超时后返回错误及仍在运行的执行 (结束时关闭).
+// 超时仅停止等待, 不会终止处理器 (节点处理器可通过 process.Context 感知取消)
+func (flow *Flow) attempt(parent context.Context, node *Node, ctx *Context, data maps.Map) ([]interface{}, <-chan struct{}, error) {
+	c, cancel := parent, context.CancelFunc(func() {})
+	if node.Timeout > 0 {
+		c, cancel = context.WithTimeout(parent, time.Duration(node.Timeout)*time.Second)
+	}
+	defer cancel()
+
+	// 每次执行使用独立的绑定数据, 超时未结束的执行不影响重试
+	input := maps.Map{}
+	for key, value := range data {
+		input[key] = value
+	}
+
+	type result struct {
+		outs []interface{}
+		err  error
+	}
+
+	done := make(chan result, 1)
+	finished := make(chan struct{})
+	go func() {
+		var res result
+		defer func() {
+			if r := recover(); r != nil {
+				res.err = exception.Catch(r)
+			}
+			done <- res
+			close(finished)
+		}()
+		_, res.outs, res.err = flow.run(c, node, ctx, input)
+	}()
+
+	select {
+	case res := <-done:
+		return res.outs, nil, res.err
+	case <-c.Done():
+		if c.Err() == context.DeadlineExceeded {
+			return nil, finished, fmt.Errorf("flows.%s node %s timeout (%ds)", flow.Name, node.Name, node.Timeout)
+		}
+		return nil, finished, c.Err()
+	}
+}
+
+// fail 按 onError 配置处理节点错误, 未配置时返回错误
+func (flow *Flow) fail(node *Node, ctx *Context, data maps.Map, err error) ([]interface{}, error) {
+	if node.OnError == nil || err == nil {
+		return nil, err
+	}
+
+	switch node.OnError.action() {
+	case "continue":
+		ctx.setError(node.Name, err)
+		return []interface{}{}, nil
+
+	case "fallback":
+		ctx.setError(node.Name, err)
+		data["$error"] = err.Error()
+		value := helper.Bind(node.OnError.Fallback, data.Dot())
+		ctx.set(node.Name, value)
+		return []interface{}{}, nil
+
+	case "goto":
+		if flow.index(node.OnError.Goto) < 0 {
+			return nil, fmt.Errorf("flows.%s node %s onError goto %s not found (%s)", flow.Name, node.Name, node.OnError.Goto, err.Error())
+		}
+		ctx.setError(node.Name, err)
+		ctx.Goto = node.OnError.Goto
+		return []interface{}{}, nil
+	}
+
+	return nil, err
+}
+
+// execFinally 执行 finally 节点, 节点中可通过 $error 引用工作流执行错误
+func (flow *Flow) execFinally(ctx *Context, err error) error {
+	// 工作流已结束或已取消, 使用新的上下文执行 (保留工作流 Span)
+	parent := context.Background()
+	if ctx.span != nil {
+		parent = context.WithoutCancel(ctx.span)
+	}
+	c, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	ctx.Context = &c
+	ctx.Cancel = cancel
+	ctx.Error = err
+	ctx.Goto = ""
+
+	node := *flow.Finally
+	_, ferr := flow.ExecNode(&node, ctx, len(flow.Nodes)-1)
+	ctx.Goto = ""
+	return ferr
+}
+
+// action 失败处理方式, 未指定时按 goto, fallback 推断
+func (onError *OnError) action() string {
+	if onError.Action != "" {
+		return onError.Action
+	}
+	if onError.Goto != "" {
+		return "goto"
+	}
+	if onError.Fallback != nil {
+		return "fallback"
+	}
+	return "continue"
+}
+
+// delay 第 n 次重试前的等待时间
+func (retry *Retry) delay(n int) time.Duration {
+	delay := time.Duration(retry.Delay) * time.Millisecond
+	switch retry.Backoff {
+	case "linear":
+		delay = delay * time.Duration(n)
+	case "exponential":
+		if n > 30 {
+			n = 30
+		}
+		delay = delay * time.Duration(1<<uint(n-1))
+	}
+
+	if retry.MaxDelay > 0 && delay > time.Duration(retry.MaxDelay)*time.Millisecond {
+		delay = time.Duration(retry.MaxDelay) * time.Millisecond
+	}
+	return delay
+}
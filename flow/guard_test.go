@@ -0,0 +1,139 @@
+package flow
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestExecRetry(t *testing.T) {
+	var calls int32
+	process.Register("flowtest.flaky", func(p *process.Process) interface{} {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			panic("flaky")
+		}
+		return "ok"
+	})
+
+	flow := &Flow{Name: "retry", Nodes: []Node{
+		{Name: "flaky", Process: "flowtest.flaky", Retry: &Retry{Count: 2, Delay: 10, Backoff: "exponential"}},
+	}}
+
+	res, err := flow.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "ok", res.(map[string]interface{})["flaky"])
+	assert.Equal(t, int32(3), calls)
+
+	atomic.StoreInt32(&calls, 0)
+	flow.Nodes[0].Retry.Count = 1
+	_, err = flow.Exec()
+	assert.NotNil(t, err)
+
+	retry := &Retry{Delay: 100, Backoff: "exponential", MaxDelay: 300}
+	assert.Equal(t, 100*time.Millisecond, retry.delay(1))
+	assert.Equal(t, 200*time.Millisecond, retry.delay(2))
+	assert.Equal(t, 300*time.Millisecond, retry.delay(3))
+}
+
+func TestExecTimeout(t *testing.T) {
+	process.Register("flowtest.slow", func(p *process.Process) interface{} {
+		select {
+		case <-time.After(3 * time.Second):
+		case <-p.Context.Done():
+		}
+		return "slow"
+	})
+
+	flow := &Flow{Name: "timeout", Nodes: []Node{
+		{Name: "slow", Process: "flowtest.slow", Timeout: 1},
+	}}
+
+	start := time.Now()
+	_, err := flow.Exec()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestExecTimeoutRetry(t *testing.T) {
+	var running, overlaps, calls int32
+	process.Register("flowtest.stubborn", func(p *process.Process) interface{} {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		defer atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(1500 * time.Millisecond) // ignores the process context
+		return "done"
+	})
+
+	flow := &Flow{Name: "timeout-retry", Nodes: []Node{
+		{Name: "stubborn", Process: "flowtest.stubborn", Timeout: 1, Retry: &Retry{Count: 1, Delay: 10}},
+	}}
+
+	_, err := flow.Exec()
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&overlaps)) // the retry waits for the timed out attempt
+}
+
+func TestExecOnError(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+	process.Register("flowtest.error", func(p *process.Process) interface{} { panic("node failed") })
+
+	flow := &Flow{Name: "onerror", Nodes: []Node{
+		{Name: "continue", Process: "flowtest.error", OnError: &OnError{Action: "continue"}},
+		{Name: "fallback", Process: "flowtest.error", OnError: &OnError{Fallback: map[string]interface{}{"ok": false, "message": "?:$error"}}},
+		{Name: "pay", Process: "flowtest.error", OnError: &OnError{Goto: "refund"}},
+		{Name: "ship", Process: "flowtest.echo", Args: []interface{}{"shipped"}},
+		{Name: "refund", Process: "flowtest.echo", Args: []interface{}{"?:$errors.pay"}},
+	}}
+	flow.prepare()
+
+	res, err := flow.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.(map[string]interface{})
+	assert.NotContains(t, r, "continue")
+	assert.NotContains(t, r, "ship")
+	assert.Equal(t, false, r["fallback"].(map[string]interface{})["ok"])
+	assert.Contains(t, r["fallback"].(map[string]interface{})["message"], "node failed")
+	assert.Contains(t, r["refund"], "node failed")
+}
+
+func TestExecFinally(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+	process.Register("flowtest.error", func(p *process.Process) interface{} { panic("node failed") })
+
+	var cleaned interface{}
+	process.Register("flowtest.cleanup", func(p *process.Process) interface{} {
+		cleaned = p.Args[0]
+		return "cleaned"
+	})
+
+	flow := &Flow{Name: "finally",
+		Nodes: []Node{
+			{Name: "work", Process: "flowtest.echo", Args: []interface{}{"done"}, OnError: &OnError{Action: "abort"}},
+		},
+		Finally: &Node{Name: "cleanup", Process: "flowtest.cleanup", Args: []interface{}{"?:$error"}},
+	}
+
+	res, err := flow.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "cleaned", res.(map[string]interface{})["cleanup"])
+	assert.Nil(t, cleaned)
+
+	flow.Nodes[0].Process = "flowtest.error"
+	_, err = flow.Exec()
+	assert.NotNil(t, err)
+	assert.Contains(t, cleaned, "node failed")
+}
@@ -1,12 +1,24 @@
 package flow
 
 import (
+	"context"
+
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
 )
 
+// FlowHandlers 持久化工作流及执行跟踪处理器
+var FlowHandlers = map[string]process.Handler{
+	"start":  processFlowStart,
+	"signal": processFlowSignal,
+	"query":  processFlowQuery,
+	"cancel": processFlowCancel,
+	"trace":  processFlowTrace,
+}
+
 func init() {
 	process.Register("flows", processFlows)
+	process.RegisterGroup("flow", FlowHandlers)
 }
 
 // processScripts
@@ -20,10 +32,99 @@ func processFlows(process *process.Process) interface{} {
 
 	flow.WithGlobal(process.Global).WithSID(process.Sid)
 
-	res, err := flow.Exec(process.Args...)
+	parent := process.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	res, err := flow.ExecContext(parent, process.Args...)
 	if err != nil {
 		exception.New(err.Error(), 500).Throw()
 	}
 
 	return res
 }
+
+// processFlowTrace flow.Trace 执行工作流并返回执行跟踪
+// args[0] 工作流名称, args[1:] 工作流参数
+func processFlowTrace(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	flow, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New("%s", 404, err.Error()).Throw()
+	}
+
+	flow.WithGlobal(process.Global).WithSID(process.Sid)
+	trace, _ := flow.Trace(process.Args[1:]...)
+	return trace
+}
+
+// processFlowStart flow.Start 启动持久化工作流实例
+// args[0] 工作流名称, args[1:] 工作流参数
+func processFlowStart(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	flow := selectDurable(process.ArgsString(0))
+	flow.WithGlobal(process.Global).WithSID(process.Sid)
+
+	inst, err := flow.Start(process.Args[1:]...)
+	if err != nil {
+		exception.New("%s", 500, err.Error()).Throw()
+	}
+	return inst
+}
+
+// processFlowSignal flow.Signal 发送外部信号
+// args[0] 工作流名称, args[1] 实例ID, args[2] 信号名称, args[3] 信号数据
+func processFlowSignal(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	flow := selectDurable(process.ArgsString(0))
+
+	var payload interface{}
+	if process.NumOfArgs() > 3 {
+		payload = process.Args[3]
+	}
+
+	inst, err := flow.Signal(process.ArgsString(1), process.ArgsString(2), payload)
+	if err != nil {
+		exception.New("%s", 500, err.Error()).Throw()
+	}
+	return inst
+}
+
+// processFlowQuery flow.Query 查询工作流实例
+// args[0] 工作流名称, args[1] 实例ID
+func processFlowQuery(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	flow := selectDurable(process.ArgsString(0))
+
+	inst, err := flow.Query(process.ArgsString(1))
+	if err != nil {
+		exception.New("%s", 404, err.Error()).Throw()
+	}
+	return inst
+}
+
+// processFlowCancel flow.Cancel 取消工作流实例
+// args[0] 工作流名称, args[1] 实例ID
+func processFlowCancel(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	flow := selectDurable(process.ArgsString(0))
+
+	inst, err := flow.Cancel(process.ArgsString(1))
+	if err != nil {
+		exception.New("%s", 500, err.Error()).Throw()
+	}
+	return inst
+}
+
+func selectDurable(name string) *Flow {
+	flow, err := Select(name)
+	if err != nil {
+		exception.New("%s", 404, err.Error()).Throw()
+	}
+
+	if flow.Durable == nil {
+		exception.New("flows.%s is not durable", 400, name).Throw()
+	}
+	return flow
+}
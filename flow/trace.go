@@ -0,0 +1,155 @@
+package flow
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+)
+
+// Tracer OpenTelemetry 风格的链路追踪接口 (可使用 OpenTelemetry SDK 适配实现)
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span)
+}
+
+// Span 链路追踪 Span
+type Span interface {
+	SetAttributes(attributes map[string]interface{})
+	RecordError(err error)
+	End()
+}
+
+// Trace 工作流执行跟踪
+type Trace struct {
+	Flow      string        `json:"flow"`
+	Args      []interface{} `json:"args,omitempty"`
+	Output    interface{}   `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  int64         `json:"duration"` // 执行时长 (毫秒)
+	Nodes     []TraceNode   `json:"nodes"`
+	mutex     sync.Mutex
+}
+
+// TraceNode 节点执行跟踪
+type TraceNode struct {
+	Node      string        `json:"node"`
+	Process   string        `json:"process,omitempty"`
+	Args      []interface{} `json:"args,omitempty"` // 绑定后的参数
+	Output    interface{}   `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Skipped   bool          `json:"skipped,omitempty"` // if/unless 条件不成立
+	StartedAt time.Time     `json:"started_at"`
+	Duration  int64         `json:"duration"` // 执行时长 (毫秒)
+}
+
+var tracer Tracer
+
+// SetTracer 设置链路追踪, 设置后工作流及节点执行将创建 Span
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+// Trace 执行工作流并返回执行跟踪, 节点异常时返回已执行部分的跟踪及错误
+func (flow *Flow) Trace(args ...interface{}) (trace *Trace, err error) {
+	trace = &Trace{Flow: flow.Name, Args: args, StartedAt: time.Now(), Nodes: []TraceNode{}}
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+	_, err = flow.exec(context.Background(), trace, args...)
+	return trace, err
+}
+
+// traceNode 执行节点并记录跟踪信息, 节点 Span 通过上下文传递给节点处理器.
+// 节点异常时记录错误后继续抛出, 与未开启跟踪时的执行一致
+func (flow *Flow) traceNode(node *Node, ctx *Context, prev int) (outs []interface{}, err error) {
+	item := TraceNode{Node: node.Name, Process: node.Process, StartedAt: time.Now()}
+	data := flow.data(ctx)
+	for _, arg := range node.Args {
+		item.Args = append(item.Args, helper.Bind(arg, data))
+	}
+
+	c := *ctx.Context
+	var span Span
+	if tracer != nil {
+		c, span = tracer.Start(c, "flows."+flow.Name+"."+node.Name, map[string]interface{}{
+			"flow.name":    flow.Name,
+			"flow.node":    node.Name,
+			"flow.process": node.Process,
+		})
+		defer span.End()
+	}
+
+	run, err := flow.should(node, data)
+	item.Skipped = err == nil && !run
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = exception.Catch(r)
+		}
+
+		item.Duration = time.Since(item.StartedAt).Milliseconds()
+		if err != nil {
+			item.Error = err.Error()
+		}
+		if node.Name != "" && !item.Skipped {
+			ctx.mutex.Lock()
+			item.Output = ctx.Res[node.Name]
+			ctx.mutex.Unlock()
+		}
+
+		if span != nil {
+			span.SetAttributes(map[string]interface{}{"flow.skipped": item.Skipped, "flow.duration": item.Duration})
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+
+		if ctx.Trace != nil {
+			ctx.Trace.mutex.Lock()
+			ctx.Trace.Nodes = append(ctx.Trace.Nodes, item)
+			ctx.Trace.mutex.Unlock()
+		}
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	return flow.execNode(c, node, ctx, prev)
+}
+
+// startTrace 创建工作流 Span
+func (flow *Flow) startTrace(parent context.Context, trace *Trace) (context.Context, func(output interface{}, err error)) {
+	var span Span
+	if tracer != nil {
+		parent, span = tracer.Start(parent, "flows."+flow.Name, map[string]interface{}{"flow.name": flow.Name})
+	}
+
+	return parent, func(output interface{}, err error) {
+		if trace != nil {
+			trace.Output = output
+			trace.Duration = time.Since(trace.StartedAt).Milliseconds()
+			if err != nil {
+				trace.Error = err.Error()
+			}
+
+			if flow.Debug {
+				log.With(log.F{"trace": trace}).Debug("flows.%s %dms", flow.Name, trace.Duration)
+			}
+		}
+
+		if span != nil {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.End()
+		}
+	}
+}
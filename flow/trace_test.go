@@ -0,0 +1,147 @@
+package flow
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+// testTracer records the span names and errors
+type testTracer struct {
+	mutex  sync.Mutex
+	spans  []string
+	errors []string
+	ended  int
+}
+
+type testSpan struct {
+	tracer *testTracer
+}
+
+// testSpanKey the context key of the current span name
+type testSpanKey struct{}
+
+func (tracer *testTracer) Start(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	tracer.spans = append(tracer.spans, name)
+	return context.WithValue(ctx, testSpanKey{}, name), &testSpan{tracer: tracer}
+}
+
+func (span *testSpan) SetAttributes(attributes map[string]interface{}) {}
+
+func (span *testSpan) RecordError(err error) {
+	span.tracer.mutex.Lock()
+	defer span.tracer.mutex.Unlock()
+	span.tracer.errors = append(span.tracer.errors, err.Error())
+}
+
+func (span *testSpan) End() {
+	span.tracer.mutex.Lock()
+	defer span.tracer.mutex.Unlock()
+	span.tracer.ended++
+}
+
+func TestTrace(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+	process.Register("flowtest.error", func(p *process.Process) interface{} { panic("node failed") })
+
+	flow := &Flow{Name: "trace", Nodes: []Node{
+		{Name: "user", Process: "flowtest.echo", Args: []interface{}{"?:$in.0"}},
+		{Name: "skipped", Process: "flowtest.echo", Args: []interface{}{1}, If: false},
+		{Name: "name", Process: "flowtest.echo", Args: []interface{}{"?:$res.user.name"}},
+	}}
+	Flows["trace"] = flow
+	defer delete(Flows, "trace")
+
+	trace, err := flow.Trace(map[string]interface{}{"name": "gou"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, trace.Nodes, 3)
+	assert.Equal(t, []interface{}{"gou"}, trace.Nodes[2].Args)
+	assert.Equal(t, "gou", trace.Nodes[2].Output)
+	assert.True(t, trace.Nodes[1].Skipped)
+	assert.Equal(t, "gou", trace.Output.(map[string]interface{})["name"])
+
+	p, err := process.Of("flow.Trace", "trace", map[string]interface{}{"name": "yao"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "yao", res.(*Trace).Nodes[2].Output)
+
+	// the node error is returned (the guarded node recovers the panic)
+	flow.Nodes[2] = Node{Name: "error", Process: "flowtest.error", Timeout: 10}
+	trace, err = flow.Trace(map[string]interface{}{"name": "gou"})
+	assert.NotNil(t, err)
+	assert.Contains(t, trace.Error, "node failed")
+	assert.Contains(t, trace.Nodes[2].Error, "node failed")
+
+	// the node panics, the partial trace is returned
+	flow.Nodes[2] = Node{Name: "error", Process: "flowtest.error"}
+	assert.NotPanics(t, func() { trace, err = flow.Trace(map[string]interface{}{"name": "gou"}) })
+	assert.NotNil(t, err)
+	assert.Len(t, trace.Nodes, 3)
+	assert.Contains(t, trace.Error, "node failed")
+	assert.Contains(t, trace.Nodes[2].Error, "node failed")
+
+	// the node error is handled by onError
+	flow.Nodes[2] = Node{Name: "error", Process: "flowtest.error", OnError: &OnError{Action: "continue"}}
+	trace, err = flow.Trace(map[string]interface{}{"name": "gou"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, trace.Nodes, 3)
+	assert.Empty(t, trace.Error)
+}
+
+func TestTracer(t *testing.T) {
+	process.Register("flowtest.echo", func(p *process.Process) interface{} { return p.Args[0] })
+	process.Register("flowtest.error", func(p *process.Process) interface{} { panic("node failed") })
+
+	tracer := &testTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	// the node process runs with the span context of the node
+	process.Register("flowtest.span", func(p *process.Process) interface{} {
+		if p.Context == nil {
+			return nil
+		}
+		return p.Context.Value(testSpanKey{})
+	})
+
+	flow := &Flow{Name: "spans", Nodes: []Node{
+		{Name: "a", Process: "flowtest.span"},
+		{Name: "b", Process: "flowtest.error", Timeout: 10},
+	}}
+
+	res, err := flow.Exec()
+	assert.NotNil(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, []string{"flows.spans", "flows.spans.a", "flows.spans.b"}, tracer.spans)
+	assert.Equal(t, 3, tracer.ended)
+	assert.Len(t, tracer.errors, 2)
+
+	flow.Nodes[1] = Node{Name: "b", Process: "flowtest.echo", Args: []interface{}{1}}
+	res, err = flow.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "flows.spans.a", res.(map[string]interface{})["a"])
+
+	// the panics are recorded on the node and the flow spans
+	tracer = &testTracer{}
+	SetTracer(tracer)
+	flow.Nodes[1] = Node{Name: "b", Process: "flowtest.error"}
+	assert.Panics(t, func() { flow.Exec() })
+	assert.Equal(t, 3, tracer.ended)
+	assert.Len(t, tracer.errors, 2)
+}
@@ -2,6 +2,7 @@ package flow
 
 import (
 	"context"
+	"sync"
 
 	"github.com/yaoapp/gou/query/share"
 )
@@ -15,6 +16,10 @@ type Flow struct {
 	Description string                 `json:"description,omitempty"`
 	Nodes       []Node                 `json:"nodes,omitempty"`
 	Output      interface{}            `json:"output,omitempty"`
+	Concurrency int                    `json:"concurrency,omitempty"` // 最大并行节点数量, 大于 1 时按依赖关系并行执行节点
+	Finally     *Node                  `json:"finally,omitempty"`     // 无论成功或失败, 最后执行的节点 (可通过 $error 引用执行错误)
+	Durable     *Durable               `json:"durable,omitempty"`     // 持久化工作流配置 (flow.Start 启动实例)
+	Debug       bool                   `json:"debug,omitempty"`       // 记录执行跟踪并输出至日志 (Debug 级别)
 	Global      map[string]interface{} // 全局变量
 	Sid         string                 // 会话ID
 }
@@ -24,10 +29,54 @@ type Node struct {
 	Name    string        `json:"name,omitempty"`
 	Process string        `json:"process,omitempty"`
 	Engine  string        `json:"engine,omitempty"` // 数据分析引擎名称
-	Query   interface{}   `json:"query,omitempty"`  // 数据分析语言 Query Source
+	Query   interface{}   `json:"query,omitempty"`  // 数据分析语言 Query Source, 或命名查询名称 (args[0] 为查询参数)
 	DSL     share.DSL     `json:"-"`                // 数据分析语言 Query DSL
 	Args    []interface{} `json:"args,omitempty"`
 	Outs    []interface{} `json:"outs,omitempty"`
+	If      interface{}   `json:"if,omitempty"`      // 执行条件, 成立时执行节点
+	Unless  interface{}   `json:"unless,omitempty"`  // 跳过条件, 成立时跳过节点
+	Switch  *Switch       `json:"switch,omitempty"`  // 执行后跳转至指定节点
+	Each    *Each         `json:"each,omitempty"`    // 遍历数组, 对每一项执行节点
+	Deps    []string      `json:"deps,omitempty"`    // 依赖节点名称 (并行执行时), 同时从 $res 绑定推断
+	Timeout int           `json:"timeout,omitempty"` // 超时时间 (秒), 超时仅停止等待, 不会终止处理器; 重试前等待超时的执行结束
+	Retry   *Retry        `json:"retry,omitempty"`   // 失败重试
+	OnError *OnError      `json:"onError,omitempty"` // 失败处理, 未设置时终止工作流
+	Wait    string        `json:"wait,omitempty"`    // 等待的外部信号名称 (持久化工作流), 信号数据作为节点结果
+}
+
+// Retry 节点失败重试
+type Retry struct {
+	Count    int    `json:"count"`              // 重试次数
+	Delay    int    `json:"delay,omitempty"`    // 重试间隔 (毫秒)
+	Backoff  string `json:"backoff,omitempty"`  // 间隔策略 fixed (默认), linear, exponential
+	MaxDelay int    `json:"maxDelay,omitempty"` // 最大重试间隔 (毫秒)
+}
+
+// OnError 节点失败处理, 错误信息可通过 $errors.<节点名称> 引用
+type OnError struct {
+	Action   string      `json:"action,omitempty"`   // continue 继续执行, fallback 使用默认值作为节点结果, goto 跳转至补偿节点
+	Fallback interface{} `json:"fallback,omitempty"` // 默认值 (可通过 $error 引用错误信息)
+	Goto     string      `json:"goto,omitempty"`     // 补偿节点名称
+}
+
+// Switch 节点路由, 依次匹配 cases, 跳转至匹配的节点
+type Switch struct {
+	Value   interface{} `json:"value,omitempty"`   // 匹配数值, 如 "?:$res.user.type"
+	Cases   []Case      `json:"cases,omitempty"`   // 匹配条件
+	Default string      `json:"default,omitempty"` // 没有匹配时跳转的节点, 为空则继续执行下一个节点
+}
+
+// Case 路由匹配条件
+type Case struct {
+	Value interface{} `json:"value,omitempty"` // 与 switch.value 相等时匹配
+	If    interface{} `json:"if,omitempty"`    // 条件成立时匹配
+	Goto  string      `json:"goto"`            // 跳转节点名称
+}
+
+// Each 遍历数组, 节点中可使用 $item 和 $index 引用当前项及下标
+type Each struct {
+	In          interface{} `json:"in"`                    // 数组, 如 "?:$res.users"
+	Concurrency int         `json:"concurrency,omitempty"` // 最大并发数量, 默认顺序执行
 }
 
 // Context 工作流上下文
@@ -36,4 +85,10 @@ type Context struct {
 	Res     map[string]interface{}
 	Context *context.Context
 	Cancel  context.CancelFunc
+	Goto    string                 // 下一个执行的节点名称 (switch, onError)
+	Errors  map[string]interface{} // 已处理的节点错误信息 (onError)
+	Error   error                  // 工作流执行错误 (finally)
+	Trace   *Trace                 // 执行跟踪 (debug, flow.Trace)
+	span    context.Context        // 工作流 Span 上下文
+	mutex   sync.Mutex
 }
@@ -0,0 +1,129 @@
+package plan
+
+import (
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+)
+
+// CheckpointPrefix the key prefix of the plan checkpoints in the store
+const CheckpointPrefix = "yao:plan:checkpoint:"
+
+// Checkpoint represents the persisted state of a plan
+type Checkpoint struct {
+	ID        string                 `json:"id"`
+	Status    Status                 `json:"status"`
+	Tasks     map[string]Status      `json:"tasks"`
+	Shared    map[string]interface{} `json:"shared,omitempty"` // the shared space contents, if the shared space implements Space
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// WithCheckpoint saves a checkpoint of the plan to the store on every plan and task status change
+func WithCheckpoint(stor store.Store) Option {
+	return func(c *Config) {
+		c.Checkpoint = stor
+	}
+}
+
+// LoadCheckpoint loads the checkpoint of the plan from the store, returns nil if there is no checkpoint
+func LoadCheckpoint(stor store.Store, id string) (*Checkpoint, error) {
+	value, has := stor.Get(CheckpointPrefix + id)
+	if !has {
+		return nil, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("the checkpoint of plan %s is invalid", id)
+	}
+
+	cp := &Checkpoint{}
+	err := jsoniter.UnmarshalFromString(text, cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Snapshot returns the current state of the plan
+func (p *Plan) Snapshot() *Checkpoint {
+	cp := &Checkpoint{ID: p.ID, Status: p.Status, Tasks: make(map[string]Status, len(p.Tasks)), UpdatedAt: time.Now()}
+	for id, task := range p.Tasks {
+		cp.Tasks[id] = task.Status
+	}
+
+	if space, ok := p.SharedSpace.(Space); ok {
+		cp.Shared = space.Snapshot()
+	}
+	return cp
+}
+
+// Checkpoint saves the current state of the plan to the checkpoint store
+func (p *Plan) Checkpoint() error {
+	if p.Config.Checkpoint == nil {
+		return nil
+	}
+
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+
+	text, err := jsoniter.MarshalToString(p.Snapshot())
+	if err != nil {
+		return err
+	}
+	return p.Config.Checkpoint.Set(CheckpointPrefix+p.ID, text, 0)
+}
+
+// Restore reconstructs the plan from the checkpoint saved in the checkpoint store.
+// The tasks must be added before restoring, the completed tasks are skipped by the next Start,
+// the others run again. Returns false if there is no checkpoint.
+func (p *Plan) Restore() (bool, error) {
+	if p.Config.Checkpoint == nil {
+		return false, fmt.Errorf("plan %s has no checkpoint store", p.ID)
+	}
+
+	if p.Status == StatusRunning || p.Status == StatusPaused {
+		return false, fmt.Errorf("plan is running")
+	}
+
+	cp, err := LoadCheckpoint(p.Config.Checkpoint, p.ID)
+	if err != nil || cp == nil {
+		return false, err
+	}
+
+	for id, status := range cp.Tasks {
+		task, exists := p.Tasks[id]
+		if !exists {
+			return false, fmt.Errorf("task with ID %s not found", id)
+		}
+
+		task.Status = StatusCreated
+		if status == StatusCompleted {
+			task.Status = StatusCompleted
+		}
+	}
+
+	if len(cp.Shared) > 0 {
+		space, ok := p.SharedSpace.(Space)
+		if !ok {
+			return false, fmt.Errorf("the shared space of plan %s can not be restored", p.ID)
+		}
+		if err := space.Restore(cp.Shared); err != nil {
+			return false, err
+		}
+	}
+
+	p.Status = StatusCreated
+	p.restored = true
+	return true, nil
+}
+
+// DeleteCheckpoint removes the checkpoint of the plan from the checkpoint store
+func (p *Plan) DeleteCheckpoint() error {
+	if p.Config.Checkpoint == nil {
+		return nil
+	}
+	return p.Config.Checkpoint.Del(CheckpointPrefix + p.ID)
+}
@@ -0,0 +1,86 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/yaoapp/gou/store/lru"
+)
+
+func TestPlanCheckpoint(t *testing.T) {
+	cache, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := map[string]int{}
+	crash := true
+	build := func() *Plan {
+		plan := NewPlan(context.Background(), "checkpoint-plan", NewMemorySharedSpace(), WithCheckpoint(cache))
+		plan.AddTask("fetch", 0, func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+			runs["fetch"]++
+			return shared.Set("fetched", "data")
+		})
+		plan.AddTask("merge", 0, func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+			runs["merge"]++
+			if crash {
+				return fmt.Errorf("crashed")
+			}
+			return nil
+		}, "fetch")
+		plan.AddTask("report", 0, func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+			runs["report"]++
+			return nil
+		}, "merge")
+		return plan
+	}
+
+	plan := build()
+	if err := plan.Start(); err == nil {
+		t.Fatal("Expected the plan to fail")
+	}
+
+	cp, err := LoadCheckpoint(cache, "checkpoint-plan")
+	if err != nil || cp == nil {
+		t.Fatalf("Failed to load the checkpoint: %v", err)
+	}
+	if cp.Status != StatusFailed || cp.Tasks["fetch"] != StatusCompleted || cp.Tasks["merge"] != StatusFailed || cp.Tasks["report"] != StatusCreated {
+		t.Errorf("Unexpected checkpoint %v", cp)
+	}
+	if cp.Shared["fetched"] != "data" {
+		t.Errorf("Expected the shared space in the checkpoint, got %v", cp.Shared)
+	}
+	plan.Stop()
+
+	// Resume the plan in a new process
+	crash = false
+	plan = build()
+	restored, err := plan.Restore()
+	if err != nil || !restored {
+		t.Fatalf("Failed to restore the plan: %v", err)
+	}
+
+	if value, _ := plan.SharedSpace.Get("fetched"); value != "data" {
+		t.Errorf("Expected the shared space to be restored, got %v", value)
+	}
+
+	if err := plan.Start(); err != nil {
+		t.Fatalf("Failed to resume the plan: %v", err)
+	}
+	if runs["fetch"] != 1 || runs["merge"] != 2 || runs["report"] != 1 {
+		t.Errorf("Expected only the incomplete tasks to run again, got %v", runs)
+	}
+
+	// The checkpoint is removed once the completed plan is released
+	plan.Release()
+	if cp, _ := LoadCheckpoint(cache, "checkpoint-plan"); cp != nil {
+		t.Errorf("Expected the checkpoint to be removed, got %v", cp)
+	}
+
+	plan = build()
+	defer plan.Stop()
+	if restored, err := plan.Restore(); err != nil || restored {
+		t.Errorf("Expected no checkpoint to restore, got %v (%v)", restored, err)
+	}
+}
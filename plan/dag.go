@@ -0,0 +1,158 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// graph represents the dependency graph of the tasks
+type graph struct {
+	pending    map[string]int      // the number of the unfinished dependencies of each task
+	dependents map[string][]string // the tasks that depend on each task
+}
+
+// cycle returns the dependency path from the task back to itself if adding the dependencies forms a cycle
+func (p *Plan) cycle(id string, deps []string) []string {
+	visited := map[string]bool{}
+	var walk func(current string, path []string) []string
+	walk = func(current string, path []string) []string {
+		if current == id {
+			return append(path, current)
+		}
+		if visited[current] {
+			return nil
+		}
+		visited[current] = true
+
+		task, exists := p.Tasks[current]
+		if !exists {
+			return nil
+		}
+		for _, dep := range task.Deps {
+			if found := walk(dep, append(path, current)); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	for _, dep := range deps {
+		if found := walk(dep, []string{id}); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// dependencies returns the tasks the task waits for.
+// A task with explicit dependencies waits for them only, a task without dependencies waits for
+// the tasks without dependencies of a lower order, so the plans using Order only keep running in order-groups.
+func (p *Plan) dependencies(task *Task) []string {
+	if len(task.Deps) > 0 {
+		return task.Deps
+	}
+
+	deps := []string{}
+	for id, other := range p.Tasks {
+		if len(other.Deps) == 0 && other.Order < task.Order {
+			deps = append(deps, id)
+		}
+	}
+	return deps
+}
+
+// graph builds the dependency graph, returns an error if a task depends on an unknown task
+func (p *Plan) graph() (*graph, error) {
+	g := &graph{pending: map[string]int{}, dependents: map[string][]string{}}
+	for id, task := range p.Tasks {
+		deps := p.dependencies(task)
+		for _, dep := range deps {
+			if _, exists := p.Tasks[dep]; !exists {
+				return nil, fmt.Errorf("task %s depends on unknown task %s", id, dep)
+			}
+			g.dependents[dep] = append(g.dependents[dep], id)
+		}
+		g.pending[id] = len(deps)
+	}
+
+	if path := p.cycles(); path != nil {
+		return nil, fmt.Errorf("dependency cycle detected: %s", strings.Join(path, " -> "))
+	}
+
+	// Skip the tasks completed before the checkpoint
+	if p.restored {
+		for id, task := range p.Tasks {
+			if task.Status == StatusCompleted {
+				delete(g.pending, id)
+				g.done(id)
+			}
+		}
+	}
+	return g, nil
+}
+
+// cycles returns the first dependency cycle found in the plan
+func (p *Plan) cycles() []string {
+	for id, task := range p.Tasks {
+		if path := p.cycle(id, task.Deps); path != nil {
+			return path
+		}
+	}
+	return nil
+}
+
+// ready returns the tasks whose dependencies are all completed, ordered by order and ID
+func (g *graph) ready(tasks map[string]*Task) []*Task {
+	ready := []*Task{}
+	for id, n := range g.pending {
+		if n == 0 {
+			ready = append(ready, tasks[id])
+			delete(g.pending, id)
+		}
+	}
+
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].Order != ready[j].Order {
+			return ready[i].Order < ready[j].Order
+		}
+		return ready[i].ID < ready[j].ID
+	})
+	return ready
+}
+
+// done marks the task as completed and releases its dependents
+func (g *graph) done(id string) {
+	for _, dependent := range g.dependents[id] {
+		if _, has := g.pending[dependent]; has {
+			g.pending[dependent]--
+		}
+	}
+}
+
+// setTaskStatus changes the status of the task and emits the task event
+func (p *Plan) setTaskStatus(task *Task, status Status, err error) {
+	from := task.Status
+	task.Status = status
+	p.emit(task, from, status, err)
+}
+
+// setStatus changes the status of the plan and saves the checkpoint
+func (p *Plan) setStatus(status Status) {
+	p.Status = status
+	p.Checkpoint()
+}
+
+// emit saves the checkpoint and calls the task event callback if the status changed
+func (p *Plan) emit(task *Task, from Status, to Status, err error) {
+	if from == to {
+		return
+	}
+
+	p.Checkpoint()
+	if p.Config.OnTaskStatus == nil {
+		return
+	}
+	p.Config.OnTaskStatus(TaskEvent{Plan: p.ID, Task: task.ID, From: from, To: to, Error: err, Time: time.Now()})
+}
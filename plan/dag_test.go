@@ -0,0 +1,164 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPlanDependencies(t *testing.T) {
+	checkGoroutineLeaks(t, func() {
+		ctx := context.Background()
+		shared := NewMemorySharedSpace()
+
+		var mu sync.Mutex
+		events := []TaskEvent{}
+		plan := NewPlan(ctx, "dag-plan", shared, WithStatusCheckInterval(5*time.Millisecond), WithTaskEvent(func(event TaskEvent) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}))
+
+		order := make(chan string, 4)
+		task := func(id string, delay time.Duration) TaskFunc {
+			return func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+				time.Sleep(delay)
+				order <- id
+				return shared.Set(id, "completed")
+			}
+		}
+
+		// fetch and load run in parallel, merge waits for both, report waits for merge
+		if err := plan.AddTask("report", 0, task("report", 0), "merge"); err != nil {
+			t.Fatalf("Failed to add report: %v", err)
+		}
+		if err := plan.AddTask("merge", 0, task("merge", 0), "fetch", "load"); err != nil {
+			t.Fatalf("Failed to add merge: %v", err)
+		}
+		if err := plan.AddTask("fetch", 0, task("fetch", 30*time.Millisecond)); err != nil {
+			t.Fatalf("Failed to add fetch: %v", err)
+		}
+		if err := plan.AddTask("load", 0, task("load", 10*time.Millisecond)); err != nil {
+			t.Fatalf("Failed to add load: %v", err)
+		}
+
+		if err := plan.Start(); err != nil {
+			t.Fatalf("Failed to start plan: %v", err)
+		}
+		close(order)
+
+		got := []string{}
+		for id := range order {
+			got = append(got, id)
+		}
+		if strings.Join(got, ",") != "load,fetch,merge,report" {
+			t.Errorf("Unexpected execution order %v", got)
+		}
+
+		// Each task is running then completed
+		if len(events) != 8 {
+			t.Errorf("Expected 8 task events, got %d", len(events))
+		}
+		for _, event := range events {
+			if event.Plan != "dag-plan" {
+				t.Errorf("Unexpected plan of the event %s", event.Plan)
+			}
+			if event.To == StatusCompleted && event.From != StatusRunning {
+				t.Errorf("Unexpected transition of %s: %v -> %v", event.Task, event.From, event.To)
+			}
+		}
+
+		plan.Stop()
+	})
+}
+
+func TestPlanDependencyCycle(t *testing.T) {
+	noop := func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error { return nil }
+	plan := NewPlan(context.Background(), "cycle-plan", NewMemorySharedSpace())
+	defer plan.Stop()
+
+	if err := plan.AddTask("a", 0, noop, "b"); err != nil {
+		t.Fatalf("Failed to add a: %v", err)
+	}
+	if err := plan.AddTask("b", 0, noop, "c"); err != nil {
+		t.Fatalf("Failed to add b: %v", err)
+	}
+
+	err := plan.AddTask("c", 0, noop, "a")
+	if err == nil || !strings.Contains(err.Error(), "c -> a -> b -> c") {
+		t.Errorf("Expected a dependency cycle error, got %v", err)
+	}
+
+	err = plan.AddTask("d", 0, noop, "d")
+	if err == nil {
+		t.Error("Expected a self dependency error")
+	}
+
+	// b depends on the unknown task c
+	err = plan.Start()
+	if err == nil || !strings.Contains(err.Error(), "unknown task c") {
+		t.Errorf("Expected an unknown task error, got %v", err)
+	}
+}
+
+func TestPlanConcurrency(t *testing.T) {
+	checkGoroutineLeaks(t, func() {
+		plan := NewPlan(context.Background(), "concurrency-plan", NewMemorySharedSpace(), WithConcurrency(2))
+
+		var running, max int32
+		for i := 0; i < 6; i++ {
+			plan.AddTask(fmt.Sprintf("task%d", i), 0, func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&max)
+					if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}
+
+		if err := plan.Start(); err != nil {
+			t.Fatalf("Failed to start plan: %v", err)
+		}
+		if max != 2 {
+			t.Errorf("Expected at most 2 tasks running at the same time, got %d", max)
+		}
+
+		plan.Stop()
+	})
+}
+
+func TestPlanDependencyFailure(t *testing.T) {
+	checkGoroutineLeaks(t, func() {
+		plan := NewPlan(context.Background(), "failure-plan", NewMemorySharedSpace())
+		plan.AddTask("fail", 0, func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+			return fmt.Errorf("fetch failed")
+		})
+		plan.AddTask("next", 0, func(ctx context.Context, shared SharedSpace, signals <-chan Signal) error {
+			return nil
+		}, "fail")
+
+		err := plan.Start()
+		if err == nil || err.Error() != "fetch failed" {
+			t.Errorf("Expected the task error, got %v", err)
+		}
+
+		_, statuses := plan.GetStatus()
+		if statuses["fail"] != StatusFailed {
+			t.Errorf("Expected fail to be StatusFailed, got %v", statuses["fail"])
+		}
+		if statuses["next"] != StatusCreated {
+			t.Errorf("Expected next not to run, got %v", statuses["next"])
+		}
+
+		plan.Stop()
+	})
+}
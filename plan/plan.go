@@ -3,6 +3,7 @@ package plan
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -26,16 +27,21 @@ func NewPlan(ctx context.Context, id string, shared SharedSpace, opts ...Option)
 	}
 }
 
-// AddTask adds a new task to the plan
-func (p *Plan) AddTask(id string, order int, fn TaskFunc) error {
+// AddTask adds a new task to the plan, the task starts after the tasks it depends on are completed
+func (p *Plan) AddTask(id string, order int, fn TaskFunc, deps ...string) error {
 	if _, exists := p.Tasks[id]; exists {
 		return fmt.Errorf("task with ID %s already exists", id)
 	}
 
+	if path := p.cycle(id, deps); path != nil {
+		return fmt.Errorf("dependency cycle detected: %s", strings.Join(path, " -> "))
+	}
+
 	taskCtx, cancel := context.WithCancel(p.Context)
 	task := &Task{
 		ID:         id,
 		Order:      order,
+		Deps:       deps,
 		Fn:         fn,
 		Status:     StatusCreated,
 		Context:    taskCtx,
@@ -63,74 +69,83 @@ func (p *Plan) RemoveTask(id string) error {
 	return nil
 }
 
-// Start begins execution of the plan
+// Start begins execution of the plan, the tasks are scheduled as a DAG with the configured concurrency
 func (p *Plan) Start() error {
 	if p.Status == StatusRunning {
 		return fmt.Errorf("plan is already running")
 	}
 
-	p.Status = StatusRunning
+	g, err := p.graph()
+	if err != nil {
+		return err
+	}
+	p.restored = false
+	p.setStatus(StatusRunning)
 
-	// Find max order
-	maxOrder := -1
-	for _, task := range p.Tasks {
-		if task.Order > maxOrder {
-			maxOrder = task.Order
-		}
+	type result struct {
+		task *Task
+		err  error
 	}
 
-	// Execute tasks in order
-	for order := 0; order <= maxOrder; order++ {
-		var tasksAtOrder []*Task
-		for _, task := range p.Tasks {
-			if task.Order == order {
-				tasksAtOrder = append(tasksAtOrder, task)
-			}
-		}
+	results := make(chan result, len(p.Tasks))
+	ready := []*Task{}
+	running := 0
+	var failed error
 
-		if len(tasksAtOrder) == 0 {
-			continue
-		}
+	for {
+		ready = append(ready, g.ready(p.Tasks)...)
 
-		var wg sync.WaitGroup
-		errChan := make(chan error, len(tasksAtOrder))
+		// Launch the ready tasks, no more tasks are launched once a task failed
+		for failed == nil && len(ready) > 0 && p.Status == StatusRunning && p.Context.Err() == nil {
+			if p.Config.Concurrency > 0 && running >= p.Config.Concurrency {
+				break
+			}
 
-		for _, task := range tasksAtOrder {
-			wg.Add(1)
-			t := task // Create new variable for goroutine
+			t := ready[0]
+			ready = ready[1:]
+			running++
+			p.setTaskStatus(t, StatusRunning, nil)
 			go func() {
-				defer wg.Done()
-				t.Status = StatusRunning
-				if err := t.Fn(t.Context, p.SharedSpace, t.SignalChan); err != nil {
-					t.Status = StatusFailed
-					t.Data = err
-					errChan <- err
-				} else {
-					t.Status = StatusCompleted
-				}
+				results <- result{task: t, err: t.Fn(t.Context, p.SharedSpace, t.SignalChan)}
 			}()
 		}
 
-		// Wait for all tasks at this order to complete
-		wg.Wait()
-		close(errChan)
-
-		// Check for errors
-		for err := range errChan {
-			if err != nil {
-				p.Status = StatusFailed
-				return err
+		if running == 0 {
+			// The plan is paused, wait for resume before launching the ready tasks
+			if failed == nil && len(ready) > 0 && p.Status == StatusPaused && p.Context.Err() == nil {
+				time.Sleep(p.Config.StatusCheckInterval)
+				continue
 			}
+			break
 		}
 
-		// Check if context was cancelled
-		if p.Context.Err() != nil {
-			p.Status = StatusFailed
-			return p.Context.Err()
+		r := <-results
+		running--
+		if r.err != nil {
+			r.task.Data = r.err
+			p.setTaskStatus(r.task, StatusFailed, r.err)
+			if failed == nil {
+				failed = r.err
+			}
+			continue
 		}
+
+		p.setTaskStatus(r.task, StatusCompleted, nil)
+		g.done(r.task.ID)
 	}
 
-	p.Status = StatusCompleted
+	if failed != nil {
+		p.setStatus(StatusFailed)
+		return failed
+	}
+
+	// Check if context was cancelled
+	if p.Context.Err() != nil {
+		p.setStatus(StatusFailed)
+		return p.Context.Err()
+	}
+
+	p.setStatus(StatusCompleted)
 	return nil
 }
 
@@ -152,12 +167,13 @@ func (p *Plan) Pause() error {
 				for t.Status == StatusRunning {
 					time.Sleep(p.Config.StatusCheckInterval)
 				}
+				p.emit(t, StatusRunning, t.Status, nil)
 			}(task)
 		}
 	}
 	wg.Wait()
 
-	p.Status = StatusPaused
+	p.setStatus(StatusPaused)
 	return nil
 }
 
@@ -179,12 +195,13 @@ func (p *Plan) Resume() error {
 				for t.Status == StatusPaused {
 					time.Sleep(p.Config.StatusCheckInterval)
 				}
+				p.emit(t, StatusPaused, t.Status, nil)
 			}(task)
 		}
 	}
 	wg.Wait()
 
-	p.Status = StatusRunning
+	p.setStatus(StatusRunning)
 	return nil
 }
 
@@ -218,8 +235,11 @@ func (p *Plan) Stop() error {
 	return nil
 }
 
-// Release releases the plan
+// Release releases the plan, the checkpoint is removed if the plan is completed
 func (p *Plan) Release() {
+	if p.Status == StatusCompleted {
+		p.DeleteCheckpoint()
+	}
 	p.SharedSpace.Clear()
 	p.SharedSpace = nil
 	p.Tasks = nil
@@ -0,0 +1,227 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisSharedSpace implements SharedSpace interface using a redis hash,
+// the changes are published to a redis channel so the subscribers in other processes are notified.
+type RedisSharedSpace struct {
+	rdb         *redis.Client
+	key         string // the hash key of the values
+	channel     string // the pub/sub channel of the changes
+	subscribers map[string][]func(key string, value interface{})
+	subMu       sync.RWMutex
+	pubsub      *redis.PubSub
+	done        chan struct{}
+}
+
+// spaceEvent the change published to the pub/sub channel
+type spaceEvent struct {
+	Op    string      `json:"op"` // set, delete, clear
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// NewRedisSharedSpace creates a new RedisSharedSpace instance, the values are stored in the hash "<prefix>:data"
+// and the changes are published to the channel "<prefix>:events"
+func NewRedisSharedSpace(rdb *redis.Client, prefix string) *RedisSharedSpace {
+	return &RedisSharedSpace{
+		rdb:         rdb,
+		key:         fmt.Sprintf("%s:data", prefix),
+		channel:     fmt.Sprintf("%s:events", prefix),
+		subscribers: make(map[string][]func(key string, value interface{})),
+	}
+}
+
+// Set stores a value in the shared space
+func (r *RedisSharedSpace) Set(key string, value interface{}) error {
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	err = r.rdb.HSet(context.Background(), r.key, key, bytes).Err()
+	if err != nil {
+		return err
+	}
+	return r.publish(spaceEvent{Op: "set", Key: key, Value: value})
+}
+
+// Get retrieves a value from the shared space
+func (r *RedisSharedSpace) Get(key string) (interface{}, error) {
+	bytes, err := r.rdb.HGet(context.Background(), r.key, key).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("key %s not found", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	err = jsoniter.Unmarshal(bytes, &value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete removes a value from the shared space
+func (r *RedisSharedSpace) Delete(key string) error {
+	err := r.rdb.HDel(context.Background(), r.key, key).Err()
+	if err != nil {
+		return err
+	}
+	return r.publish(spaceEvent{Op: "delete", Key: key})
+}
+
+// Clear removes all values from the shared space
+func (r *RedisSharedSpace) Clear() error {
+	return r.rdb.Del(context.Background(), r.key).Err()
+}
+
+// ClearNotify removes all values from the shared space and notifies subscribers
+func (r *RedisSharedSpace) ClearNotify() error {
+	err := r.Clear()
+	if err != nil {
+		return err
+	}
+	return r.publish(spaceEvent{Op: "clear"})
+}
+
+// Subscribe subscribes to changes in the shared space, the callbacks are called when the change
+// is received from the pub/sub channel, including the changes made by the current process.
+func (r *RedisSharedSpace) Subscribe(key string, callback func(key string, value interface{})) error {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	if r.pubsub == nil {
+		pubsub := r.rdb.Subscribe(context.Background(), r.channel)
+
+		// Wait for the subscription confirmation, so the changes made after Subscribe returns are received
+		if _, err := pubsub.Receive(context.Background()); err != nil {
+			pubsub.Close()
+			return err
+		}
+
+		r.pubsub = pubsub
+		r.done = make(chan struct{})
+		go r.listen(pubsub, r.done)
+	}
+
+	r.subscribers[key] = append(r.subscribers[key], callback)
+	return nil
+}
+
+// Unsubscribe removes a subscription, the pub/sub connection is closed when there are no subscriptions
+func (r *RedisSharedSpace) Unsubscribe(key string) error {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	delete(r.subscribers, key)
+	if len(r.subscribers) == 0 {
+		return r.close()
+	}
+	return nil
+}
+
+// Close closes the pub/sub connection and removes all subscriptions
+func (r *RedisSharedSpace) Close() error {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	r.subscribers = make(map[string][]func(key string, value interface{}))
+	return r.close()
+}
+
+// Snapshot returns a copy of all key-value pairs in the shared space
+func (r *RedisSharedSpace) Snapshot() map[string]interface{} {
+	snapshot := map[string]interface{}{}
+	values, err := r.rdb.HGetAll(context.Background(), r.key).Result()
+	if err != nil {
+		return snapshot
+	}
+
+	for k, v := range values {
+		var value interface{}
+		if err := jsoniter.UnmarshalFromString(v, &value); err == nil {
+			snapshot[k] = value
+		}
+	}
+	return snapshot
+}
+
+// Restore sets multiple key-value pairs from a snapshot
+func (r *RedisSharedSpace) Restore(data map[string]interface{}) error {
+	for k, v := range data {
+		if err := r.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RedisSharedSpace) publish(event spaceEvent) error {
+	bytes, err := jsoniter.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Publish(context.Background(), r.channel, bytes).Err()
+}
+
+// listen dispatches the changes received from the pub/sub channel to the subscribers
+func (r *RedisSharedSpace) listen(pubsub *redis.PubSub, done chan struct{}) {
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-done:
+			return
+
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var event spaceEvent
+			if err := jsoniter.UnmarshalFromString(msg.Payload, &event); err != nil {
+				continue
+			}
+			r.notify(event)
+		}
+	}
+}
+
+func (r *RedisSharedSpace) notify(event spaceEvent) {
+	r.subMu.RLock()
+	defer r.subMu.RUnlock()
+
+	if event.Op == "clear" {
+		for key, callbacks := range r.subscribers {
+			for _, callback := range callbacks {
+				callback(key, nil)
+			}
+		}
+		return
+	}
+
+	for _, callback := range r.subscribers[event.Key] {
+		callback(event.Key, event.Value)
+	}
+}
+
+func (r *RedisSharedSpace) close() error {
+	if r.pubsub == nil {
+		return nil
+	}
+
+	close(r.done)
+	err := r.pubsub.Close()
+	r.pubsub = nil
+	r.done = nil
+	return err
+}
@@ -0,0 +1,83 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisSharedSpace(t *testing.T) {
+	host := os.Getenv("GOU_TEST_REDIS_HOST")
+	if host == "" {
+		t.Skip("GOU_TEST_REDIS_HOST is not set")
+	}
+
+	port := os.Getenv("GOU_TEST_REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: fmt.Sprintf("%s:%s", host, port), Password: os.Getenv("GOU_TEST_REDIS_PASSWORD")})
+	defer rdb.Close()
+
+	shared := NewRedisSharedSpace(rdb, "gou:test:plan")
+	other := NewRedisSharedSpace(rdb, "gou:test:plan") // another process sharing the space
+	defer shared.Close()
+	defer shared.Clear()
+
+	notifications := make(chan interface{}, 10)
+	err := shared.Subscribe("key1", func(key string, value interface{}) {
+		notifications <- value
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	if err := other.Set("key1", "value1"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	select {
+	case value := <-notifications:
+		if value != "value1" {
+			t.Errorf("Expected notification with 'value1', got %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive notification in time")
+	}
+
+	value, err := shared.Get("key1")
+	if err != nil || value != "value1" {
+		t.Errorf("Expected 'value1', got %v (%v)", value, err)
+	}
+
+	other.Set("key2", map[string]interface{}{"foo": "bar"})
+	snapshot := shared.Snapshot()
+	if len(snapshot) != 2 || snapshot["key2"].(map[string]interface{})["foo"] != "bar" {
+		t.Errorf("Unexpected snapshot %v", snapshot)
+	}
+
+	if err := other.ClearNotify(); err != nil {
+		t.Fatalf("Failed to clear: %v", err)
+	}
+
+	select {
+	case value := <-notifications:
+		if value != nil {
+			t.Errorf("Expected a nil notification, got %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive the clear notification in time")
+	}
+
+	if _, err := shared.Get("key1"); err == nil {
+		t.Error("Expected key1 to be cleared")
+	}
+
+	if err := shared.Unsubscribe("key1"); err != nil {
+		t.Errorf("Failed to unsubscribe: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+)
+
+// StoreSharedSpace implements SharedSpace interface using a store.Store, the values are stored with the key "<prefix>:<key>".
+// The subscribers are notified of the changes made by the current process, call Watch to be notified
+// of the changes made by other processes sharing the store.
+type StoreSharedSpace struct {
+	store       store.Store
+	prefix      string
+	subscribers map[string][]func(key string, value interface{})
+	seen        map[string]string // the last value notified of each subscribed key, in JSON
+	subMu       sync.RWMutex
+	done        chan struct{}
+}
+
+// NewStoreSharedSpace creates a new StoreSharedSpace instance
+func NewStoreSharedSpace(stor store.Store, prefix string) *StoreSharedSpace {
+	return &StoreSharedSpace{
+		store:       stor,
+		prefix:      fmt.Sprintf("%s:", prefix),
+		subscribers: make(map[string][]func(key string, value interface{})),
+		seen:        make(map[string]string),
+	}
+}
+
+// Set stores a value in the shared space
+func (s *StoreSharedSpace) Set(key string, value interface{}) error {
+	err := s.store.Set(s.prefix+key, value, 0)
+	if err != nil {
+		return err
+	}
+	s.notify(key, value, false)
+	return nil
+}
+
+// Get retrieves a value from the shared space
+func (s *StoreSharedSpace) Get(key string) (interface{}, error) {
+	value, ok := s.store.Get(s.prefix + key)
+	if !ok {
+		return nil, fmt.Errorf("key %s not found", key)
+	}
+	return value, nil
+}
+
+// Delete removes a value from the shared space
+func (s *StoreSharedSpace) Delete(key string) error {
+	err := s.store.Del(s.prefix + key)
+	if err != nil {
+		return err
+	}
+	s.notify(key, nil, false)
+	return nil
+}
+
+// Clear removes all values from the shared space
+func (s *StoreSharedSpace) Clear() error {
+	return s.store.Del(s.prefix + "*")
+}
+
+// ClearNotify removes all values from the shared space and notifies subscribers
+func (s *StoreSharedSpace) ClearNotify() error {
+	err := s.Clear()
+	if err != nil {
+		return err
+	}
+
+	s.subMu.RLock()
+	keys := make([]string, 0, len(s.subscribers))
+	for key := range s.subscribers {
+		keys = append(keys, key)
+	}
+	s.subMu.RUnlock()
+
+	for _, key := range keys {
+		s.notify(key, nil, false)
+	}
+	return nil
+}
+
+// Subscribe subscribes to changes in the shared space
+func (s *StoreSharedSpace) Subscribe(key string, callback func(key string, value interface{})) error {
+	value, _ := s.store.Get(s.prefix + key)
+
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if _, has := s.subscribers[key]; !has {
+		s.seen[key] = fingerprint(value)
+	}
+	s.subscribers[key] = append(s.subscribers[key], callback)
+	return nil
+}
+
+// Unsubscribe removes a subscription
+func (s *StoreSharedSpace) Unsubscribe(key string) error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	delete(s.subscribers, key)
+	delete(s.seen, key)
+	return nil
+}
+
+// Watch polls the subscribed keys with the interval and notifies the subscribers of the changes
+// made by other processes, until Close is called.
+func (s *StoreSharedSpace) Watch(interval time.Duration) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if s.done != nil {
+		return
+	}
+
+	s.done = make(chan struct{})
+	go func(done chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.poll()
+			}
+		}
+	}(s.done)
+}
+
+// Close stops watching the changes and removes all subscriptions
+func (s *StoreSharedSpace) Close() error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	s.subscribers = make(map[string][]func(key string, value interface{}))
+	s.seen = make(map[string]string)
+	return nil
+}
+
+// Snapshot returns a copy of all key-value pairs in the shared space
+func (s *StoreSharedSpace) Snapshot() map[string]interface{} {
+	snapshot := map[string]interface{}{}
+	for _, key := range s.store.Keys(s.prefix + "*") {
+		if value, ok := s.store.Get(key); ok {
+			snapshot[strings.TrimPrefix(key, s.prefix)] = value
+		}
+	}
+	return snapshot
+}
+
+// Restore sets multiple key-value pairs from a snapshot
+func (s *StoreSharedSpace) Restore(data map[string]interface{}) error {
+	for k, v := range data {
+		if err := s.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// poll notifies the subscribers of the keys changed since the last notification
+func (s *StoreSharedSpace) poll() {
+	s.subMu.RLock()
+	keys := make([]string, 0, len(s.subscribers))
+	for key := range s.subscribers {
+		keys = append(keys, key)
+	}
+	s.subMu.RUnlock()
+
+	for _, key := range keys {
+		value, _ := s.store.Get(s.prefix + key)
+		s.notify(key, value, true)
+	}
+}
+
+// notify calls the callbacks of the key, if changed is true the callbacks are called only
+// if the value is changed since the last notification
+func (s *StoreSharedSpace) notify(key string, value interface{}, changed bool) {
+	text := fingerprint(value)
+
+	s.subMu.Lock()
+	callbacks, has := s.subscribers[key]
+	if !has || (changed && s.seen[key] == text) {
+		s.subMu.Unlock()
+		return
+	}
+	s.seen[key] = text
+	s.subMu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(key, value)
+	}
+}
+
+// fingerprint the JSON of the value, used to detect the changes
+func fingerprint(value interface{}) string {
+	text, err := jsoniter.MarshalToString(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return text
+}
@@ -0,0 +1,81 @@
+package plan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yaoapp/gou/store/lru"
+)
+
+func TestStoreSharedSpace(t *testing.T) {
+	cache, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := NewStoreSharedSpace(cache, "plan-store")
+	defer shared.Close()
+
+	notifications := make(chan interface{}, 10)
+	err = shared.Subscribe("key1", func(key string, value interface{}) {
+		notifications <- value
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	if err := shared.Set("key1", "value1"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if value := <-notifications; value != "value1" {
+		t.Errorf("Expected notification with 'value1', got %v", value)
+	}
+
+	value, err := shared.Get("key1")
+	if err != nil || value != "value1" {
+		t.Errorf("Expected 'value1', got %v (%v)", value, err)
+	}
+
+	// Another process sharing the store
+	other := NewStoreSharedSpace(cache, "plan-store")
+	shared.Watch(10 * time.Millisecond)
+	other.Set("key1", "value2")
+	select {
+	case value := <-notifications:
+		if value != "value2" {
+			t.Errorf("Expected notification with 'value2', got %v", value)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Error("Did not receive the change made by another process")
+	}
+
+	// No notification without changes
+	select {
+	case value := <-notifications:
+		t.Errorf("Unexpected notification %v", value)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	shared.Set("key2", 2)
+	snapshot := shared.Snapshot()
+	if len(snapshot) != 2 || snapshot["key1"] != "value2" {
+		t.Errorf("Unexpected snapshot %v", snapshot)
+	}
+
+	if err := shared.ClearNotify(); err != nil {
+		t.Fatalf("Failed to clear: %v", err)
+	}
+	if value := <-notifications; value != nil {
+		t.Errorf("Expected a nil notification, got %v", value)
+	}
+	if _, err := shared.Get("key2"); err == nil {
+		t.Error("Expected key2 to be cleared")
+	}
+
+	if err := shared.Restore(snapshot); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if value, _ := other.Get("key2"); value != 2 {
+		t.Errorf("Expected the restored value 2, got %v", value)
+	}
+}
@@ -2,7 +2,10 @@ package plan
 
 import (
 	"context"
+	"sync"
 	"time"
+
+	"github.com/yaoapp/gou/store"
 )
 
 // Status represents the status of a plan or task
@@ -33,6 +36,15 @@ const (
 type Config struct {
 	// StatusCheckInterval is the interval between status checks
 	StatusCheckInterval time.Duration
+
+	// Concurrency is the max number of tasks running at the same time, 0 means unlimited
+	Concurrency int
+
+	// OnTaskStatus is called when the status of a task changes
+	OnTaskStatus func(event TaskEvent)
+
+	// Checkpoint is the store to save the checkpoints of the plan, nil means no checkpoint
+	Checkpoint store.Store
 }
 
 // DefaultConfig returns the default configuration
@@ -52,10 +64,35 @@ func WithStatusCheckInterval(d time.Duration) Option {
 	}
 }
 
+// WithConcurrency sets the max number of tasks running at the same time
+func WithConcurrency(n int) Option {
+	return func(c *Config) {
+		c.Concurrency = n
+	}
+}
+
+// WithTaskEvent sets the callback called when the status of a task changes
+func WithTaskEvent(fn func(event TaskEvent)) Option {
+	return func(c *Config) {
+		c.OnTaskStatus = fn
+	}
+}
+
+// TaskEvent represents a status transition of a task
+type TaskEvent struct {
+	Plan  string
+	Task  string
+	From  Status
+	To    Status
+	Error error
+	Time  time.Time
+}
+
 // Task represents a unit of work in a plan
 type Task struct {
 	ID         string
 	Order      int
+	Deps       []string // the IDs of the tasks that must complete before this task starts
 	Fn         TaskFunc
 	Status     Status
 	Data       interface{}
@@ -76,6 +113,9 @@ type Plan struct {
 	Context     context.Context
 	Cancel      context.CancelFunc
 	Config      *Config
+
+	restored     bool // the plan is restored from a checkpoint, the completed tasks are skipped
+	checkpointMu sync.Mutex
 }
 
 // SharedSpace represents the interface for shared storage space
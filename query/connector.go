@@ -0,0 +1,79 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/connector"
+	connElastic "github.com/yaoapp/gou/connector/elastic"
+	connMongo "github.com/yaoapp/gou/connector/mongo"
+	"github.com/yaoapp/gou/query/elastic"
+	"github.com/yaoapp/gou/query/mongo"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/log"
+)
+
+func init() {
+	// 加载 mongo, elasticsearch 数据连接器时, 使用连接器名称注册查询引擎
+	connector.OnLoad(func(id string, conn connector.Connector) {
+		if !conn.Is(connector.MONGO) && !conn.Is(connector.ELASTICSEARCH) {
+			return
+		}
+		if err := RegisterConnector(id, id); err != nil {
+			log.Error("[Query] register connector %s %s", id, err.Error())
+		}
+	})
+}
+
+// Mongo 创建 MongoDB 查询引擎 (id 为 mongo 数据连接器名称)
+func Mongo(id string) (*mongo.Query, error) {
+	conn, err := connector.Select(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := conn.(*connMongo.Connector)
+	if !ok || !conn.Is(connector.MONGO) {
+		return nil, fmt.Errorf("connector %s is not a mongo connector", id)
+	}
+	return mongo.New(m.Database), nil
+}
+
+// Elastic 创建 Elasticsearch 查询引擎 (id 为 elasticsearch 数据连接器名称)
+func Elastic(id string) (*elastic.Query, error) {
+	conn, err := connector.Select(id)
+	if err != nil {
+		return nil, err
+	}
+
+	es, ok := conn.(*connElastic.Connector)
+	if !ok || !conn.Is(connector.ELASTICSEARCH) {
+		return nil, fmt.Errorf("connector %s is not an elasticsearch connector", id)
+	}
+	return elastic.New(es), nil
+}
+
+// RegisterConnector 使用数据连接器 (mongo, elasticsearch) 注册查询引擎, 供 flow 查询节点及 JS Query 对象使用
+// 数据连接器加载时自动使用连接器名称注册, 可调用此方法注册其他名称
+func RegisterConnector(name string, id string) error {
+	conn, err := connector.Select(id)
+	if err != nil {
+		return err
+	}
+
+	var engine share.DSL
+	switch {
+	case conn.Is(connector.MONGO):
+		engine, err = Mongo(id)
+	case conn.Is(connector.ELASTICSEARCH):
+		engine, err = Elastic(id)
+	default:
+		return fmt.Errorf("connector %s does not support query engine", id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	Register(name, engine)
+	return nil
+}
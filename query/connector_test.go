@@ -0,0 +1,46 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/query/elastic"
+)
+
+func TestConnectorOnLoad(t *testing.T) {
+	_, err := connector.LoadSource([]byte(`{
+		"type": "elasticsearch",
+		"options": {"hosts": ["http://127.0.0.1:9200"]}
+	}`), "query-test-es", "query-test-es.conn.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(connector.Connectors, "query-test-es")
+	defer Unregister("query-test-es")
+
+	// the engine is registered with the connector name
+	engine, err := Select("query-test-es")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := engine.(*elastic.Query)
+	assert.True(t, ok)
+
+	// register with another name
+	err = RegisterConnector("query-test-es-alias", "query-test-es")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("query-test-es-alias")
+
+	engine, err = Select("query-test-es-alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok = engine.(*elastic.Query)
+	assert.True(t, ok)
+
+	_, err = Elastic("query-test-unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+// Package document 文档数据库查询引擎 (mongo, elastic) 公共方法
+package document
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-errors/errors"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Parse 解析并校验查询条件, validate 为查询引擎的附加校验
+func Parse(data interface{}, validate func(dsl gou.QueryDSL) []error) (gou.QueryDSL, error) {
+	var dsl gou.QueryDSL
+	input, err := jsoniter.Marshal(data)
+	if err != nil {
+		return dsl, errors.Errorf("加载失败 %s", err.Error())
+	}
+
+	err = jsoniter.Unmarshal(input, &dsl)
+	if err != nil {
+		return dsl, errors.Errorf("DSL 解析失败 %s", err.Error())
+	}
+
+	errs := dsl.Validate()
+	errs = append(errs, validate(dsl)...)
+	if len(errs) > 0 {
+		return dsl, errors.Errorf("查询条件错误 %#v", errs)
+	}
+	return dsl, nil
+}
+
+// IntOf 读取 limit/offset/page/pagesize 数值 (支持绑定参数)
+func IntOf(v interface{}, data maps.Map, defaults int) int {
+	switch v.(type) {
+	case float64, float32, int, int64, int32:
+		return any.Of(v).CInt()
+	case string:
+		return any.Of(helper.Bind(v, data)).CInt()
+	}
+	return defaults
+}
+
+// Page 读取页码及每页记录数量
+func Page(page interface{}, pageSize interface{}, data maps.Map) (int, int) {
+	p := IntOf(page, data, 1)
+	size := IntOf(pageSize, data, 20)
+	if p < 1 {
+		p = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+	return p, size
+}
+
+// Paginate 生成分页信息
+func Paginate(page int, pageSize int, total int, items []share.Record) share.Paginate {
+	res := share.Paginate{Items: items, Total: total, Page: page, PageSize: pageSize, Prev: page - 1, Next: page + 1, PageCount: -1}
+	if res.Total > 0 {
+		res.PageCount = int(math.Ceil(float64(res.Total) / float64(pageSize)))
+	}
+
+	if res.Prev == 0 {
+		res.Prev = -1
+	}
+
+	if res.PageCount > 0 && res.Next > res.PageCount {
+		res.Next = -1
+	}
+	return res
+}
+
+// Name 查询结果字段名称, path 为字段表达式对应的文档路径
+func Name(exp gou.Expression, path func(gou.Expression) string) string {
+	if exp.Alias != "" {
+		return exp.Alias
+	}
+	if exp.IsFun {
+		return strings.ToLower(exp.FunName)
+	}
+	if exp.Field == "" {
+		return fmt.Sprintf("%v", exp.Value)
+	}
+	return path(exp)
+}
+
+// Output 聚合查询结果字段名称, 优先使用 select 中的别名 (不能包含 .)
+func Output(selects []gou.Expression, exp gou.Expression, path func(gou.Expression) string) string {
+	for _, sel := range selects {
+		if Same(sel, exp) {
+			return strings.ReplaceAll(Name(sel, path), ".", "_")
+		}
+	}
+	return strings.ReplaceAll(Name(exp, path), ".", "_")
+}
+
+// Same 两个字段表达式是否指向相同字段或函数
+func Same(a, b gou.Expression) bool {
+	if b.Alias != "" && a.Alias == b.Alias {
+		return true
+	}
+
+	if !b.IsFun && b.Alias == "" && a.Alias != "" && a.Alias == b.Field && b.Table == "" {
+		return true
+	}
+
+	a.Alias, b.Alias = "", ""
+	return a.ToString() == b.ToString()
+}
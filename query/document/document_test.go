@@ -0,0 +1,82 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/maps"
+)
+
+func TestParse(t *testing.T) {
+	none := func(dsl gou.QueryDSL) []error { return nil }
+	dsl, err := Parse(map[string]interface{}{"select": []interface{}{"name"}, "from": "users"}, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "users", dsl.From.Name)
+
+	_, err = Parse(map[string]interface{}{"select": []interface{}{"name"}, "from": "users", "sql": map[string]interface{}{"stmt": "SELECT 1"}},
+		func(dsl gou.QueryDSL) []error {
+			if dsl.SQL != nil {
+				return []error{assert.AnError}
+			}
+			return nil
+		})
+	assert.NotNil(t, err)
+}
+
+func TestIntOfAndPage(t *testing.T) {
+	data := maps.Map{"$in.page": 3}
+	assert.Equal(t, 10, IntOf(10, data, 1))
+	assert.Equal(t, 3, IntOf("?:$in.page", data, 1))
+	assert.Equal(t, 1, IntOf(nil, data, 1))
+
+	page, pageSize := Page("?:$in.page", 0, data)
+	assert.Equal(t, 3, page)
+	assert.Equal(t, 20, pageSize)
+
+	page, pageSize = Page(nil, nil, data)
+	assert.Equal(t, 1, page)
+	assert.Equal(t, 20, pageSize)
+}
+
+func TestPaginate(t *testing.T) {
+	res := Paginate(1, 2, 3, []share.Record{{"id": 1}, {"id": 2}})
+	assert.Equal(t, 2, res.PageCount)
+	assert.Equal(t, -1, res.Prev)
+	assert.Equal(t, 2, res.Next)
+	assert.Equal(t, 2, len(res.Items))
+
+	res = Paginate(2, 2, 3, []share.Record{{"id": 3}})
+	assert.Equal(t, 1, res.Prev)
+	assert.Equal(t, -1, res.Next)
+
+	res = Paginate(1, 20, 0, []share.Record{})
+	assert.Equal(t, -1, res.PageCount)
+}
+
+func TestNameAndOutput(t *testing.T) {
+	path := func(exp gou.Expression) string {
+		if exp.Table != "" {
+			return exp.Table + "." + exp.Field
+		}
+		return exp.Field
+	}
+	selects := []gou.Expression{
+		*gou.NewExpression("meta.age as age"),
+		*gou.NewExpression(":COUNT(id) as total"),
+		*gou.NewExpression("city"),
+	}
+
+	assert.Equal(t, "age", Name(selects[0], path))
+	assert.Equal(t, "total", Name(selects[1], path))
+	assert.Equal(t, "city", Name(selects[2], path))
+
+	assert.Equal(t, "total", Output(selects, *gou.NewExpression("total"), path))
+	assert.Equal(t, "age", Output(selects, *gou.NewExpression("meta.age"), path))
+	assert.Equal(t, "meta_name", Output(selects, *gou.NewExpression("meta.name"), path))
+	assert.True(t, Same(selects[1], *gou.NewExpression(":COUNT(id)")))
+	assert.False(t, Same(selects[2], *gou.NewExpression("name")))
+}
@@ -0,0 +1,465 @@
+package elastic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/query/document"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Metrics 支持的聚合函数 (count 读取 doc_count)
+var Metrics = map[string]string{
+	"count": "",
+	"sum":   "sum",
+	"avg":   "avg",
+	"max":   "max",
+	"min":   "min",
+}
+
+// Ranges 范围运算符
+var Ranges = map[string]string{
+	">":  "gt",
+	">=": "gte",
+	"<":  "lt",
+	"<=": "lte",
+}
+
+// Validate 校验 Elasticsearch 不支持的查询条件
+func Validate(dsl gou.QueryDSL) []error {
+	errs := []error{}
+	if dsl.SQL != nil {
+		errs = append(errs, errors.Errorf("Elasticsearch 不支持 sql"))
+	}
+
+	if dsl.From == nil {
+		errs = append(errs, errors.Errorf("缺少 from (索引名称)"))
+	} else if dsl.From.IsModel {
+		errs = append(errs, errors.Errorf("Elasticsearch 不支持数据模型 %s", dsl.From.Name))
+	}
+
+	if len(dsl.Joins) > 0 || len(dsl.Unions) > 0 || dsl.SubQuery != nil || len(dsl.With) > 0 || len(dsl.Windows) > 0 || len(dsl.Havings) > 0 {
+		errs = append(errs, errors.Errorf("Elasticsearch 不支持 joins, unions, query, with, windows, havings"))
+	}
+
+	for _, exp := range dsl.Select {
+		if exp.IsFun {
+			if _, has := Metrics[strings.ToLower(exp.FunName)]; !has {
+				errs = append(errs, errors.Errorf("select %s 函数暂不支持", exp.ToString()))
+			}
+		}
+	}
+
+	if dsl.Groups != nil {
+		for _, group := range *dsl.Groups {
+			if group.Rollup != "" {
+				errs = append(errs, errors.Errorf("groups %s Elasticsearch 不支持 rollup", group.Field.ToString()))
+			}
+		}
+	}
+
+	errs = append(errs, validateWheres(dsl.Wheres)...)
+	return errs
+}
+
+func validateWheres(wheres []gou.Where) []error {
+	errs := []error{}
+	for _, where := range wheres {
+		if where.Query != nil {
+			errs = append(errs, errors.Errorf("wheres Elasticsearch 不支持子查询"))
+		}
+		if where.ValueExpression != nil {
+			errs = append(errs, errors.Errorf("wheres %s Elasticsearch 不支持字段比较", where.Field.ToString()))
+		}
+		errs = append(errs, validateWheres(where.Wheres)...)
+	}
+	return errs
+}
+
+// Body 生成检索请求 (query, _source, sort, aggs), 不含分页 (from, size)
+func (es Query) Body(data maps.Map) (map[string]interface{}, error) {
+
+	query, err := es.filter(es.Wheres, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if query == nil {
+		query = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	body := map[string]interface{}{"query": query}
+
+	// 非聚合查询
+	if !es.aggregated() {
+		if source := es.source(); len(source) > 0 {
+			body["_source"] = source
+		}
+
+		if len(es.Orders) > 0 {
+			sorts := []interface{}{}
+			for _, order := range es.Orders {
+				direction := "asc"
+				if strings.ToLower(order.Sort) == "desc" {
+					direction = "desc"
+				}
+				sorts = append(sorts, map[string]interface{}{es.path(*order.Field): map[string]interface{}{"order": direction}})
+			}
+			body["sort"] = sorts
+		}
+		return body, nil
+	}
+
+	// 聚合查询
+	metrics := map[string]interface{}{}
+	for _, exp := range es.Select {
+		if !exp.IsFun {
+			continue
+		}
+		fun := Metrics[strings.ToLower(exp.FunName)]
+		if fun == "" || len(exp.FunArgs) == 0 {
+			continue
+		}
+		metrics[es.output(exp)] = map[string]interface{}{fun: map[string]interface{}{"field": es.path(exp.FunArgs[0])}}
+	}
+
+	body["size"] = 0
+	if es.Groups == nil || len(*es.Groups) == 0 {
+		body["track_total_hits"] = true
+		if len(metrics) > 0 {
+			body["aggs"] = metrics
+		}
+		return body, nil
+	}
+
+	sources := []interface{}{}
+	for _, group := range *es.Groups {
+		sources = append(sources, map[string]interface{}{
+			es.output(*group.Field): map[string]interface{}{"terms": map[string]interface{}{"field": es.path(*group.Field)}},
+		})
+	}
+
+	groups := map[string]interface{}{"composite": map[string]interface{}{"size": BucketSize, "sources": sources}}
+	if len(metrics) > 0 {
+		groups["aggs"] = metrics
+	}
+	body["aggs"] = map[string]interface{}{"groups": groups}
+	return body, nil
+}
+
+// source 读取的字段列表
+func (es Query) source() []string {
+	source := []string{}
+	for _, exp := range es.Select {
+		if exp.IsFun || exp.Field == "" || exp.Field == "_id" {
+			continue
+		}
+		source = append(source, es.path(exp))
+	}
+	return source
+}
+
+// aggregated 是否为聚合查询
+func (es Query) aggregated() bool {
+	if es.Groups != nil && len(*es.Groups) > 0 {
+		return true
+	}
+	for _, exp := range es.Select {
+		if exp.IsFun {
+			return true
+		}
+	}
+	return false
+}
+
+// filter 查询条件转换为 bool 查询, 连续的 and 条件为一组, or 条件开始新的分组
+func (es Query) filter(wheres []gou.Where, data maps.Map) (map[string]interface{}, error) {
+
+	if len(wheres) == 0 {
+		return nil, nil
+	}
+
+	groups := [][]interface{}{{}}
+	for i, where := range wheres {
+		var cond map[string]interface{}
+		var err error
+		if where.Wheres != nil {
+			cond, err = es.filter(where.Wheres, data)
+		} else {
+			cond, err = es.condition(where.Condition, data)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if cond == nil {
+			continue
+		}
+
+		if where.OR && i > 0 {
+			groups = append(groups, []interface{}{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], cond)
+	}
+
+	should := []interface{}{}
+	for _, group := range groups {
+		switch len(group) {
+		case 0:
+			continue
+		case 1:
+			should = append(should, group[0])
+		default:
+			should = append(should, map[string]interface{}{"bool": map[string]interface{}{"filter": group}})
+		}
+	}
+
+	switch len(should) {
+	case 0:
+		return nil, nil
+	case 1:
+		return should[0].(map[string]interface{}), nil
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"should": should, "minimum_should_match": 1}}, nil
+}
+
+// condition 查询条件转换为 Elasticsearch 查询条件
+func (es Query) condition(cond gou.Condition, data maps.Map) (map[string]interface{}, error) {
+
+	if cond.Field == nil {
+		return nil, nil
+	}
+
+	field := es.path(*cond.Field)
+	value := helper.Bind(cond.Value, data)
+	if op, has := Ranges[cond.OP]; has {
+		return map[string]interface{}{"range": map[string]interface{}{field: map[string]interface{}{op: value}}}, nil
+	}
+
+	switch cond.OP {
+	case "=":
+		return map[string]interface{}{"term": map[string]interface{}{field: value}}, nil
+
+	case "<>":
+		return not(map[string]interface{}{"term": map[string]interface{}{field: value}}), nil
+
+	case "in":
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		return map[string]interface{}{"terms": map[string]interface{}{field: values}}, nil
+
+	case "like":
+		return map[string]interface{}{"wildcard": map[string]interface{}{field: map[string]interface{}{"value": Wildcard(fmt.Sprintf("%v", value))}}}, nil
+
+	case "match":
+		return map[string]interface{}{"match": map[string]interface{}{field: value}}, nil
+
+	case "is":
+		exists := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+		if value == "not null" {
+			return exists, nil
+		}
+		return not(exists), nil
+	}
+
+	return nil, errors.Errorf("%s 操作符暂不支持", cond.OP)
+}
+
+func not(query map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{"must_not": []interface{}{query}}}
+}
+
+// Wildcard SQL LIKE 匹配模式转换为 wildcard 查询 (% => *, _ => ?)
+func Wildcard(pattern string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "%", "*", "_", "?")
+	return replacer.Replace(pattern)
+}
+
+// hits 读取检索结果及总记录数
+func (es Query) hits(res map[string]interface{}) ([]share.Record, int) {
+	rows := []share.Record{}
+	hits, _ := res["hits"].(map[string]interface{})
+	if hits == nil {
+		return rows, 0
+	}
+
+	items, _ := hits["hits"].([]interface{})
+	for _, item := range items {
+		hit, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		source, _ := hit["_source"].(map[string]interface{})
+		if source == nil {
+			source = map[string]interface{}{}
+		}
+
+		if len(es.Select) == 0 {
+			row := share.Record(source)
+			row["_id"] = hit["_id"]
+			rows = append(rows, row)
+			continue
+		}
+
+		row := share.Record{}
+		for _, exp := range es.Select {
+			if exp.Field == "" {
+				row[es.name(exp)] = exp.Value
+				continue
+			}
+			if es.path(exp) == "_id" {
+				row[es.name(exp)] = hit["_id"]
+				continue
+			}
+			row[es.name(exp)] = maps.Of(source).Dot().Get(es.path(exp))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, total(hits["total"])
+}
+
+// aggregations 读取聚合结果
+func (es Query) aggregations(res map[string]interface{}) []share.Record {
+	aggs, _ := res["aggregations"].(map[string]interface{})
+	if es.Groups == nil || len(*es.Groups) == 0 {
+		hits, _ := res["hits"].(map[string]interface{})
+		count := 0
+		if hits != nil {
+			count = total(hits["total"])
+		}
+		return []share.Record{es.bucket(aggs, count)}
+	}
+
+	rows := []share.Record{}
+	if aggs == nil {
+		return rows
+	}
+
+	groups, _ := aggs["groups"].(map[string]interface{})
+	buckets, _ := groups["buckets"].([]interface{})
+	for _, item := range buckets {
+		bucket, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		row := es.bucket(bucket, any.Of(bucket["doc_count"]).CInt())
+		if key, ok := bucket["key"].(map[string]interface{}); ok {
+			for name, value := range key {
+				row[name] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// bucket 读取分组的统计数值
+func (es Query) bucket(bucket map[string]interface{}, count int) share.Record {
+	row := share.Record{}
+	for _, exp := range es.Select {
+		if !exp.IsFun {
+			continue
+		}
+
+		name := es.output(exp)
+		if strings.ToLower(exp.FunName) == "count" {
+			row[name] = count
+			continue
+		}
+
+		if metric, ok := bucket[name].(map[string]interface{}); ok {
+			row[name] = metric["value"]
+		}
+	}
+	return row
+}
+
+// sortRows 按 orders 排序聚合结果
+func (es Query) sortRows(rows []share.Record) {
+	if len(es.Orders) == 0 {
+		return
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, order := range es.Orders {
+			name := es.output(*order.Field)
+			res := compare(rows[i][name], rows[j][name])
+			if res == 0 {
+				continue
+			}
+			if strings.ToLower(order.Sort) == "desc" {
+				return res > 0
+			}
+			return res < 0
+		}
+		return false
+	})
+}
+
+// total 读取总记录数 {"value": 10, "relation": "eq"} 或 10
+func total(v interface{}) int {
+	if value, ok := v.(map[string]interface{}); ok {
+		return any.Of(value["value"]).CInt()
+	}
+	return any.Of(v).CInt()
+}
+
+// compare 比较两个数值
+func compare(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+
+	if any.Of(a).IsNumber() && any.Of(b).IsNumber() {
+		x, y := any.Of(a).CFloat64(), any.Of(b).CFloat64()
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// path 字段表达式对应的文档路径 (meta.age, object$.key)
+func (es Query) path(exp gou.Expression) string {
+	path := exp.Field
+	if exp.Table != "" && (es.From == nil || (exp.Table != es.From.Name && exp.Table != es.From.Alias)) {
+		path = exp.Table + "." + path
+	}
+
+	if (exp.IsObject || exp.IsArray) && exp.Key != "" {
+		path = path + "." + strings.TrimPrefix(exp.Key, ".")
+	}
+	return path
+}
+
+// name 查询结果字段名称
+func (es Query) name(exp gou.Expression) string {
+	return document.Name(exp, es.path)
+}
+
+// output 聚合查询结果字段名称, 优先使用 select 中的别名 (不能包含 .)
+func (es Query) output(exp gou.Expression) string {
+	return document.Output(es.Select, exp, es.path)
+}
@@ -0,0 +1,69 @@
+package elastic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/maps"
+)
+
+func TestLoad(t *testing.T) {
+	_, err := New(nil).Load(map[string]interface{}{
+		"select":  []interface{}{"city", ":COUNT(id) as total"},
+		"from":    "users",
+		"groups":  []interface{}{"city"},
+		"havings": []interface{}{map[string]interface{}{"field": "total", ">": 1}},
+	})
+	assert.NotNil(t, err)
+
+	_, err = New(nil).Load(map[string]interface{}{
+		"select": []interface{}{"id"},
+		"from":   "orders",
+		"wheres": []interface{}{map[string]interface{}{"field": "paid", "<": "{total}"}},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestBody(t *testing.T) {
+	q := load(t, nil, map[string]interface{}{
+		"select": []interface{}{"name", "meta.age as age"},
+		"from":   "users",
+		"wheres": []interface{}{
+			map[string]interface{}{"field": "status", "=": "?:$in.status"},
+			map[string]interface{}{"field": "meta.age", ">=": 18},
+			map[string]interface{}{"or": true, "field": "name", "like": "A%"},
+			map[string]interface{}{"field": "deleted_at", "is": "null"},
+		},
+		"orders": "meta.age desc",
+	})
+
+	body, err := q.Body(maps.Map{"$in.status": "enabled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"query": map[string]interface{}{"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"status": "enabled"}},
+					map[string]interface{}{"range": map[string]interface{}{"meta.age": map[string]interface{}{"gte": float64(18)}}},
+				}}},
+				map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{
+					map[string]interface{}{"wildcard": map[string]interface{}{"name": map[string]interface{}{"value": "A*"}}},
+					map[string]interface{}{"bool": map[string]interface{}{"must_not": []interface{}{
+						map[string]interface{}{"exists": map[string]interface{}{"field": "deleted_at"}},
+					}}},
+				}}},
+			},
+			"minimum_should_match": 1,
+		}},
+		"_source": []string{"name", "meta.age"},
+		"sort":    []interface{}{map[string]interface{}{"meta.age": map[string]interface{}{"order": "desc"}}},
+	}, body)
+}
+
+func TestWildcard(t *testing.T) {
+	assert.Equal(t, "A*", Wildcard("A%"))
+	assert.Equal(t, `a?b\*`, Wildcard("a_b*"))
+}
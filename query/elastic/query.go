@@ -0,0 +1,200 @@
+package elastic
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/query/document"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/kun/utils"
+)
+
+// MaxBuckets 聚合查询最多返回的分组数量
+var MaxBuckets = 10000
+
+// BucketSize 分组聚合每次请求读取的分组数量, 使用 after_key 读取下一页
+var BucketSize = 1000
+
+// Client Elasticsearch 检索接口 (connector/elastic.Connector)
+type Client interface {
+	Search(index string, body map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Query Elasticsearch 查询引擎, 将 Gou Query DSL 转换为 Elasticsearch Query DSL
+type Query struct {
+	gou.QueryDSL
+	Client Client
+}
+
+// New 创建 Elasticsearch 查询引擎
+func New(client Client) *Query {
+	return &Query{Client: client}
+}
+
+// ==================================================
+// share.DSL Interface
+// ==================================================
+
+// Load 加载查询条件
+func (es *Query) Load(data interface{}) (share.DSL, error) {
+	dsl, err := document.Parse(data, Validate)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{QueryDSL: dsl, Client: es.Client}, nil
+}
+
+// Run 执行查询根据查询条件返回结果
+func (es Query) Run(data maps.Map) interface{} {
+	if es.Page != nil || es.PageSize != nil {
+		return es.Paginate(data)
+	} else if es.QueryDSL.First != nil {
+		return es.First(data)
+	}
+	return es.Get(data)
+}
+
+// Get 执行查询并返回数据记录集合
+func (es Query) Get(data maps.Map) []share.Record {
+	offset := es.intOf(es.Offset, data, 0)
+	limit := es.intOf(es.Limit, data, 100)
+	if es.aggregated() {
+		rows := es.buckets(data)
+		return slice(rows, offset, limit)
+	}
+
+	body := es.mustBody(data)
+	body["from"] = offset
+	body["size"] = limit
+	rows, _ := es.hits(es.search(body))
+	return rows
+}
+
+// Paginate 执行查询并返回带分页信息的数据记录数组
+func (es Query) Paginate(data maps.Map) share.Paginate {
+	page, pageSize := document.Page(es.Page, es.PageSize, data)
+	if es.aggregated() {
+		rows := es.buckets(data)
+		return document.Paginate(page, pageSize, len(rows), slice(rows, (page-1)*pageSize, pageSize))
+	}
+
+	body := es.mustBody(data)
+	body["from"] = (page - 1) * pageSize
+	body["size"] = pageSize
+	body["track_total_hits"] = true
+	items, total := es.hits(es.search(body))
+	return document.Paginate(page, pageSize, total, items)
+}
+
+// First 执行查询并返回一条数据记录
+func (es Query) First(data maps.Map) share.Record {
+	es.Limit = 1
+	records := es.Get(data)
+	if len(records) > 0 {
+		return records[0]
+	}
+	return nil
+}
+
+// Index 查询的索引名称
+func (es Query) Index() string {
+	if es.From == nil {
+		return ""
+	}
+	return es.From.Name
+}
+
+// search 执行检索
+func (es Query) search(body map[string]interface{}) map[string]interface{} {
+	if es.Client == nil {
+		exception.New("未绑定数据连接", 500).Throw()
+	}
+
+	// Debug模式 打印查询信息
+	if es.Debug {
+		fmt.Println(es.Index())
+		utils.Dump(body)
+	}
+
+	res, err := es.Client.Search(es.Index(), body)
+	if err != nil {
+		exception.New("数据查询错误 %s", 500, err.Error()).Throw()
+	}
+	return res
+}
+
+// mustBody 生成检索请求
+func (es Query) mustBody(data maps.Map) map[string]interface{} {
+	body, err := es.Body(data)
+	if err != nil {
+		exception.New("查询条件错误 %s", 400, err.Error()).Throw()
+	}
+	return body
+}
+
+// buckets 执行聚合查询, 使用 after_key 逐页读取分组 (最多 MaxBuckets 个), 排序后返回全部分组
+func (es Query) buckets(data maps.Map) []share.Record {
+	body := es.mustBody(data)
+	res := es.search(body)
+	rows := es.aggregations(res)
+	for len(rows) < MaxBuckets {
+		after := afterKey(res)
+		composite := composite(body)
+		if after == nil || composite == nil {
+			break
+		}
+
+		composite["after"] = after
+		res = es.search(body)
+		page := es.aggregations(res)
+		if len(page) == 0 {
+			break
+		}
+		rows = append(rows, page...)
+	}
+
+	if len(rows) > MaxBuckets {
+		rows = rows[:MaxBuckets]
+	}
+	es.sortRows(rows)
+	return rows
+}
+
+// composite 检索请求中的分组聚合 (composite aggregation), 非分组聚合查询返回 nil
+func composite(body map[string]interface{}) map[string]interface{} {
+	aggs, _ := body["aggs"].(map[string]interface{})
+	groups, _ := aggs["groups"].(map[string]interface{})
+	composite, _ := groups["composite"].(map[string]interface{})
+	return composite
+}
+
+// afterKey 分组聚合结果的下一页位置, 没有下一页返回 nil
+func afterKey(res map[string]interface{}) map[string]interface{} {
+	aggs, _ := res["aggregations"].(map[string]interface{})
+	groups, _ := aggs["groups"].(map[string]interface{})
+	buckets, _ := groups["buckets"].([]interface{})
+	if len(buckets) == 0 {
+		return nil
+	}
+	after, _ := groups["after_key"].(map[string]interface{})
+	return after
+}
+
+// intOf 读取 limit/offset/page/pagesize 数值 (支持绑定参数)
+func (es Query) intOf(v interface{}, data maps.Map, defaults int) int {
+	return document.IntOf(v, data, defaults)
+}
+
+// slice 截取记录
+func slice(rows []share.Record, offset int, limit int) []share.Record {
+	if offset >= len(rows) {
+		return []share.Record{}
+	}
+	end := offset + limit
+	if limit < 0 || end > len(rows) {
+		end = len(rows)
+	}
+	return rows[offset:end]
+}
@@ -0,0 +1,190 @@
+package elastic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	connElastic "github.com/yaoapp/gou/connector/elastic"
+	"github.com/yaoapp/gou/query/share"
+)
+
+// mockClient 测试用检索接口, 记录请求并返回预设结果
+type mockClient struct {
+	index string
+	body  map[string]interface{}
+	res   map[string]interface{}
+}
+
+func (mock *mockClient) Search(index string, body map[string]interface{}) (map[string]interface{}, error) {
+	mock.index = index
+	mock.body = body
+	return mock.res, nil
+}
+
+// pagingClient 测试用检索接口, 按 after 依次返回分组聚合结果
+type pagingClient struct {
+	pages  []map[string]interface{}
+	afters []interface{}
+}
+
+func (mock *pagingClient) Search(index string, body map[string]interface{}) (map[string]interface{}, error) {
+	composite := body["aggs"].(map[string]interface{})["groups"].(map[string]interface{})["composite"].(map[string]interface{})
+	mock.afters = append(mock.afters, composite["after"])
+	if len(mock.afters) > len(mock.pages) {
+		return map[string]interface{}{"aggregations": map[string]interface{}{"groups": map[string]interface{}{"buckets": []interface{}{}}}}, nil
+	}
+	return mock.pages[len(mock.afters)-1], nil
+}
+
+func load(t *testing.T, client Client, dsl map[string]interface{}) *Query {
+	q, err := New(client).Load(dsl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q.(*Query)
+}
+
+func TestGetAndPaginate(t *testing.T) {
+	client := &mockClient{res: map[string]interface{}{
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{"value": 3.0, "relation": "eq"},
+			"hits": []interface{}{
+				map[string]interface{}{"_id": "1", "_source": map[string]interface{}{"name": "Alice", "meta": map[string]interface{}{"age": 30.0}}},
+				map[string]interface{}{"_id": "2", "_source": map[string]interface{}{"name": "Bob", "meta": map[string]interface{}{"age": 25.0}}},
+			},
+		},
+	}}
+
+	q := load(t, client, map[string]interface{}{"select": []interface{}{"_id as id", "name", "meta.age as age"}, "from": "users", "limit": 2})
+	rows := q.Get(nil)
+	assert.Equal(t, "users", client.index)
+	assert.Equal(t, 2, client.body["size"])
+	assert.Equal(t, []share.Record{
+		{"id": "1", "name": "Alice", "age": 30.0},
+		{"id": "2", "name": "Bob", "age": 25.0},
+	}, rows)
+
+	q = load(t, client, map[string]interface{}{"select": []interface{}{"name"}, "from": "users", "page": 2, "pagesize": 2})
+	res := q.Run(nil).(share.Paginate)
+	assert.Equal(t, 2, client.body["from"])
+	assert.Equal(t, true, client.body["track_total_hits"])
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, 2, res.PageCount)
+	assert.Equal(t, 1, res.Prev)
+	assert.Equal(t, -1, res.Next)
+}
+
+func TestGroups(t *testing.T) {
+	client := &mockClient{res: map[string]interface{}{
+		"aggregations": map[string]interface{}{
+			"groups": map[string]interface{}{
+				"buckets": []interface{}{
+					map[string]interface{}{"key": map[string]interface{}{"city": "Beijing"}, "doc_count": 2.0, "age": map[string]interface{}{"value": 27.5}},
+					map[string]interface{}{"key": map[string]interface{}{"city": "Shanghai"}, "doc_count": 5.0, "age": map[string]interface{}{"value": 31.0}},
+				},
+			},
+		},
+	}}
+
+	q := load(t, client, map[string]interface{}{
+		"select": []interface{}{"city", ":COUNT(id) as total", ":AVG(meta.age) as age"},
+		"from":   "users",
+		"groups": []interface{}{"city"},
+		"orders": []interface{}{map[string]interface{}{"field": "total", "sort": "desc"}},
+	})
+
+	rows := q.Get(nil)
+	assert.Equal(t, 0, client.body["size"])
+	assert.Equal(t, map[string]interface{}{"groups": map[string]interface{}{
+		"composite": map[string]interface{}{
+			"size":    BucketSize,
+			"sources": []interface{}{map[string]interface{}{"city": map[string]interface{}{"terms": map[string]interface{}{"field": "city"}}}},
+		},
+		"aggs": map[string]interface{}{"age": map[string]interface{}{"avg": map[string]interface{}{"field": "meta.age"}}},
+	}}, client.body["aggs"])
+
+	assert.Equal(t, []share.Record{
+		{"city": "Shanghai", "total": 5, "age": 31.0},
+		{"city": "Beijing", "total": 2, "age": 27.5},
+	}, rows)
+}
+
+func TestGroupsAfterKey(t *testing.T) {
+	page := func(after string, cities ...string) map[string]interface{} {
+		buckets := []interface{}{}
+		for _, city := range cities {
+			buckets = append(buckets, map[string]interface{}{"key": map[string]interface{}{"city": city}, "doc_count": 1.0})
+		}
+		return map[string]interface{}{"aggregations": map[string]interface{}{"groups": map[string]interface{}{
+			"buckets":   buckets,
+			"after_key": map[string]interface{}{"city": after},
+		}}}
+	}
+
+	client := &pagingClient{pages: []map[string]interface{}{
+		page("Chengdu", "Beijing", "Chengdu"),
+		page("Shanghai", "Guangzhou", "Shanghai"),
+	}}
+
+	q := load(t, client, map[string]interface{}{
+		"select": []interface{}{"city", ":COUNT(id) as total"},
+		"from":   "users",
+		"groups": []interface{}{"city"},
+	})
+
+	rows := q.Get(nil)
+	assert.Equal(t, []interface{}{
+		nil,
+		map[string]interface{}{"city": "Chengdu"},
+		map[string]interface{}{"city": "Shanghai"},
+	}, client.afters)
+	assert.Equal(t, 4, len(rows))
+	assert.Equal(t, "Shanghai", rows[3]["city"])
+
+	// 最多返回 MaxBuckets 个分组
+	maxBuckets := MaxBuckets
+	MaxBuckets = 3
+	defer func() { MaxBuckets = maxBuckets }()
+	client.afters = nil
+	rows = q.Get(nil)
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, 2, len(client.afters))
+}
+
+func TestFirst(t *testing.T) {
+	client := &mockClient{res: map[string]interface{}{
+		"hits": map[string]interface{}{
+			"hits": []interface{}{
+				map[string]interface{}{"_id": "1", "_source": map[string]interface{}{"name": "Alice"}},
+			},
+		},
+	}}
+
+	q := load(t, client, map[string]interface{}{"select": []interface{}{"name"}, "from": "users", "first": true})
+	res := q.Run(nil).(share.Record)
+	assert.Equal(t, 1, client.body["size"])
+	assert.Equal(t, share.Record{"name": "Alice"}, res)
+
+	client.res = map[string]interface{}{"hits": map[string]interface{}{"hits": []interface{}{}}}
+	assert.Nil(t, q.First(nil))
+}
+
+func TestConnectorClient(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"1","_source":{"name":"Alice"}}]}}`))
+	}))
+	defer server.Close()
+
+	es := &connElastic.Connector{Options: connElastic.Options{Hosts: []string{server.URL}, Timeout: 5}, Client: server.Client()}
+	q := load(t, es, map[string]interface{}{"select": []interface{}{"_id as id", "name"}, "from": "users"})
+	assert.Equal(t, []share.Record{{"id": "1", "name": "Alice"}}, q.Get(nil))
+	assert.Equal(t, "/users/_search", path)
+
+	// the query panics without a client
+	q = load(t, nil, map[string]interface{}{"select": []interface{}{"name"}, "from": "users"})
+	assert.Panics(t, func() { q.Get(nil) })
+}
@@ -2,6 +2,7 @@ package query
 
 import (
 	"io"
+	"time"
 
 	"github.com/yaoapp/gou/query/gou"
 )
@@ -20,3 +21,8 @@ func GouRead(reader io.Reader) *gou.Query {
 func GouOpen(filename string) *gou.Query {
 	return gou.Open(filename)
 }
+
+// SetSlowQuery 设定 Gou Query 慢查询日志阈值 (0 关闭)
+func SetSlowQuery(threshold time.Duration) {
+	gou.SetSlowQuery(threshold)
+}
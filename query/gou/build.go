@@ -22,6 +22,7 @@ func (gou *Query) Build() {
 		}
 	}
 
+	gou.buildWith()
 	gou.buildSelect()
 	gou.buildFrom()
 	gou.buildWheres()
@@ -44,6 +45,12 @@ func (gou *Query) buildSelect() *Query {
 			fields = append(fields, sql)
 		}
 	}
+	for _, window := range gou.Windows {
+		sql := gou.sqlWindow(window)
+		if sql != nil {
+			fields = append(fields, sql)
+		}
+	}
 	gou.Query.Select(fields...)
 	return gou
 }
@@ -1,6 +1,7 @@
 package gou
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -248,3 +249,52 @@ func TestBuildSelectAES(t *testing.T) {
 		assert.Contains(t, sql, "UNHEX(")
 	}
 }
+
+func TestBuildWith(t *testing.T) {
+	gou := Open(GetFileName("queries/with.json")).
+		With(qb, TableName).
+		SetAESKey(TestAESKey)
+	gou.Build()
+	sql := gou.ToSQL()
+	assert.True(t, strings.HasPrefix(sql, Q("WITH `paid`(`id`, `name`, `total`) AS (select `user_id`, `name`, SUM(`amount`) from `orders` where `status` = ?")), sql)
+	assert.Contains(t, sql, strings.ReplaceAll(") select `id`, `name`, `total` from `paid` where `total` > ", "`", Q("`")))
+	if TestDriver == "postgres" {
+		assert.True(t, strings.HasSuffix(sql, "$2"), sql)
+	}
+	assert.Len(t, gou.GetBindings(), 2)
+}
+
+func TestBuildWithRecursive(t *testing.T) {
+	gou := Open(GetFileName("queries/with.recursive.json")).
+		With(qb, TableName).
+		SetAESKey(TestAESKey)
+	gou.Build()
+	sql := gou.ToSQL()
+	assert.True(t, strings.HasPrefix(sql, Q("WITH RECURSIVE `tree`(`id`, `parent`) AS (")), sql)
+	assert.Contains(t, sql, "union all")
+	assert.Contains(t, sql, Q("from `tree`"))
+}
+
+func TestBuildWindows(t *testing.T) {
+	gou := Open(GetFileName("queries/windows.json")).
+		With(qb, TableName).
+		SetAESKey(TestAESKey)
+	gou.Build()
+	sql := gou.ToSQL()
+	assert.Contains(t, sql, Q("ROW_NUMBER() OVER (PARTITION BY `user_id` ORDER BY `created_at` DESC) AS `rn`"))
+	assert.Contains(t, sql, Q("SUM(`amount`) OVER (PARTITION BY `user_id` ORDER BY `id` ASC ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS `running`"))
+}
+
+func TestValidateWithWindows(t *testing.T) {
+	dsl := QueryDSL{
+		Select: []Expression{*NewExpression("id")},
+		From:   &Table{Name: "t"},
+		With: []CTE{
+			{Name: "t", Query: &QueryDSL{Select: []Expression{*NewExpression("id")}, From: &Table{Name: "t1"}}},
+			{Name: "t", Query: &QueryDSL{Select: []Expression{*NewExpression("id")}, From: &Table{Name: "t2"}}},
+		},
+		Windows: []Window{{Field: NewExpression(":ROW_NUMBER()")}},
+	}
+	errs := dsl.Validate()
+	assert.Len(t, errs, 2)
+}
@@ -0,0 +1,172 @@
+package gou
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/go-errors/errors"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// slowQuery 慢查询日志阈值 (0 不记录)
+var slowQuery time.Duration
+
+// SetSlowQuery 设定慢查询日志阈值, 查询耗时超过阈值时记录查询语句、绑定参数及来源处理器 (0 关闭)
+func SetSlowQuery(threshold time.Duration) {
+	slowQuery = threshold
+}
+
+// Explain 返回查询语句、绑定参数及数据库执行计划
+func (gou Query) Explain(data maps.Map) (*share.Explain, error) {
+	if gou.Query == nil {
+		return nil, errors.Errorf("未绑定数据连接")
+	}
+
+	if gou.STMT == "" {
+		return nil, errors.Errorf("查询条件尚未加载")
+	}
+
+	stmt, bindings := gou.prepare(data)
+	res := &share.Explain{Driver: gou.driver, SQL: stmt, Bindings: bindings, Plan: []share.Record{}}
+
+	var prefix, costPrefix string
+	switch gou.driver {
+	case "mysql":
+		prefix = "EXPLAIN "
+		costPrefix = "EXPLAIN FORMAT=JSON "
+	case "postgres":
+		prefix = "EXPLAIN "
+		costPrefix = "EXPLAIN (FORMAT JSON) "
+	case "sqlite3":
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return nil, errors.Errorf("数据库驱动 %s 不支持 EXPLAIN", gou.driver)
+	}
+
+	plan, err := gou.explainRows(prefix+stmt, bindings)
+	if err != nil {
+		return nil, errors.Errorf("EXPLAIN 执行失败 %s", err.Error())
+	}
+	res.Plan = plan
+
+	if costPrefix == "" {
+		return res, nil
+	}
+
+	rows, err := gou.explainRows(costPrefix+stmt, bindings)
+	if err != nil || len(rows) == 0 {
+		log.Warn("[Query] Explain cost: %v", err)
+		return res, nil
+	}
+
+	res.Cost = gou.explainCost(rows[0])
+	return res, nil
+}
+
+// explainRows 执行 EXPLAIN 语句并返回结果
+func (gou Query) explainRows(stmt string, bindings []interface{}) ([]share.Record, error) {
+	rows, err := gou.Query.New().DB().Query(stmt, bindings...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRows(rows)
+}
+
+// explainCost 解析 JSON 格式的执行计划, 读取查询成本
+func (gou Query) explainCost(row share.Record) float64 {
+	for _, value := range row {
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		switch gou.driver {
+		case "mysql":
+			// {"query_block": {"cost_info": {"query_cost": "1.20"}}}
+			var plan struct {
+				QueryBlock struct {
+					CostInfo struct {
+						QueryCost string `json:"query_cost"`
+					} `json:"cost_info"`
+				} `json:"query_block"`
+			}
+			if err := jsoniter.Unmarshal([]byte(text), &plan); err == nil {
+				cost, _ := strconv.ParseFloat(plan.QueryBlock.CostInfo.QueryCost, 64)
+				return cost
+			}
+
+		case "postgres":
+			// [{"Plan": {"Total Cost": 1.20}}]
+			var plan []struct {
+				Plan struct {
+					TotalCost float64 `json:"Total Cost"`
+				} `json:"Plan"`
+			}
+			if err := jsoniter.Unmarshal([]byte(text), &plan); err == nil && len(plan) > 0 {
+				return plan[0].Plan.TotalCost
+			}
+		}
+	}
+	return 0
+}
+
+// slow 记录慢查询日志
+func (gou Query) slow(stmt string, bindings []interface{}, data maps.Map, start time.Time) {
+	if slowQuery <= 0 {
+		return
+	}
+
+	duration := time.Since(start)
+	if duration < slowQuery {
+		return
+	}
+
+	name := "-"
+	if data != nil {
+		if v, ok := data[share.ProcessKey].(string); ok && v != "" {
+			name = v
+		}
+	}
+
+	log.With(log.F{"sql": stmt, "bindings": bindings, "process": name, "duration": duration.String()}).
+		Warn("[Query] Slow query %s (%s)", duration, name)
+}
+
+// scanRows 读取查询结果
+func scanRows(rows *sql.Rows) ([]share.Record, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []share.Record{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		record := share.Record{}
+		for i, column := range columns {
+			switch value := values[i].(type) {
+			case []byte:
+				record[column] = string(value)
+			default:
+				record[column] = value
+			}
+		}
+		res = append(res, record)
+	}
+
+	return res, rows.Err()
+}
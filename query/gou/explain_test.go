@@ -0,0 +1,63 @@
+package gou
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/log"
+)
+
+func TestExplain(t *testing.T) {
+	q := Open(GetFileName("queries/test_get.json")).
+		With(qb, TableName).
+		SetAESKey(TestAESKey)
+	q.Build()
+	q.STMT = q.ToSQL()
+	q.Bindings = q.GetBindings()
+	q.Selects = q.mapOfSelect()
+
+	res, err := q.Explain(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, q.STMT, res.SQL)
+	assert.Equal(t, TestDriver, res.Driver)
+	assert.NotEmpty(t, res.Plan)
+	if TestDriver == "postgres" {
+		assert.Greater(t, res.Cost, 0.0)
+	}
+
+	_, err = New().Explain(nil)
+	assert.NotNil(t, err)
+}
+
+func TestSlowQuery(t *testing.T) {
+	q := Open(GetFileName("queries/test_get.json")).
+		With(qb, TableName).
+		SetAESKey(TestAESKey)
+	q.Build()
+	q.STMT = q.ToSQL()
+	q.Bindings = q.GetBindings()
+	q.Selects = q.mapOfSelect()
+
+	output := &bytes.Buffer{}
+	log.SetOutput(output)
+	defer log.SetOutput(os.Stdout)
+
+	SetSlowQuery(time.Nanosecond)
+	defer SetSlowQuery(0)
+	rows := q.Get(map[string]interface{}{"$process": "flows.test"})
+	assert.Equal(t, 3, len(rows))
+	assert.Contains(t, output.String(), "[Query] Slow query")
+	assert.Contains(t, output.String(), "flows.test")
+
+	// under the threshold
+	output.Reset()
+	SetSlowQuery(time.Hour)
+	q.Get(map[string]interface{}{"$process": "flows.test"})
+	assert.NotContains(t, output.String(), "Slow query")
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-errors/errors"
 	jsoniter "github.com/json-iterator/go"
@@ -30,6 +31,8 @@ type Query struct {
 	AESKey       string
 	STMT         string
 	driver       string
+	with         string        // WITH 公用表表达式语句
+	withBindings []interface{} // WITH 公用表表达式绑定参数
 }
 
 // Quote wraps an identifier with the correct quote character for the active database driver.
@@ -125,7 +128,7 @@ func (gou Query) ToSQL() string {
 	if gou.Query == nil {
 		exception.New("未绑定数据连接", 500).Throw()
 	}
-	return gou.Query.ToSQL()
+	return gou.withSQL(gou.Query.ToSQL())
 }
 
 // GetBindings 返回SQL绑定数据
@@ -133,7 +136,11 @@ func (gou Query) GetBindings() []interface{} {
 	if gou.Query == nil {
 		exception.New("未绑定数据连接", 500).Throw()
 	}
-	return gou.Query.GetBindings()
+	if gou.with == "" {
+		return gou.Query.GetBindings()
+	}
+	bindings := append([]interface{}{}, gou.withBindings...)
+	return append(bindings, gou.Query.GetBindings()...)
 }
 
 // ==================================================
@@ -192,10 +199,12 @@ func (gou Query) Run(data maps.Map) interface{} {
 		utils.Dump(bindings)
 	}
 
+	start := time.Now()
 	res, err := qb.DB().Exec(sql, bindings...)
 	if err != nil {
 		exception.New("数据查询错误 %s", 500, err.Error()).Throw()
 	}
+	gou.slow(sql, bindings, data, start)
 	return res
 }
 
@@ -284,7 +293,9 @@ func (gou Query) Get(data maps.Map) []share.Record {
 		utils.Dump(bindings)
 	}
 
+	start := time.Now()
 	rows := qb.MustGet()
+	gou.slow(sql, bindings, data, start)
 
 	// 处理数据
 	for _, row := range rows {
@@ -313,7 +324,7 @@ func (gou Query) Paginate(data maps.Map) share.Paginate {
 	res.Next = page + 1
 	res.Items = []share.Record{}
 
-	total := gou.total(sql, bindings)
+	total := gou.total(sql, bindings, data)
 	res.Total = total
 	res.PageCount = -1
 	if total > 0 && pageSize > 0 {
@@ -340,7 +351,9 @@ func (gou Query) Paginate(data maps.Map) share.Paginate {
 		utils.Dump(bindings)
 	}
 
+	start := time.Now()
 	rows := qb.MustGet()
+	gou.slow(sql, bindings, data, start)
 
 	// 处理数据
 	for _, row := range rows {
@@ -351,22 +364,36 @@ func (gou Query) Paginate(data maps.Map) share.Paginate {
 }
 
 // total 计算分页 (应该在载入时准备)
-func (gou Query) total(sql string, bindings []interface{}) int {
+func (gou Query) total(sql string, bindings []interface{}, data maps.Map) int {
+
+	// WITH 公用表表达式, 使用子查询统计
+	if gou.with != "" && strings.HasPrefix(sql, gou.with) {
+		sql = fmt.Sprintf("%sSELECT COUNT(*) as %s FROM (%s) AS %s",
+			gou.with, gou.Quote("total"), strings.TrimPrefix(sql, gou.with), gou.Quote("__TOTAL__"))
+		return gou.count(sql, bindings, data)
+	}
+
 	matches := RegSelectSTMT.FindStringSubmatch(sql)
-	total := -1
 	if len(matches) > 0 {
 		sql = strings.ReplaceAll(sql, matches[1], " COUNT(*) as "+gou.Quote("total")+" ")
 		sql = RegOrderBySTMT.ReplaceAllString(sql, "")
-		qb := gou.Query.New().SQL(sql, bindings...)
-		// Debug模式 打印查询信息
-		if gou.Debug {
-			fmt.Println(sql)
-			utils.Dump(bindings)
-		}
-		row := qb.MustFirst()
-		total = row.GetInt("total")
+		return gou.count(sql, bindings, data)
+	}
+	return -1
+}
+
+// count 执行统计语句
+func (gou Query) count(sql string, bindings []interface{}, data maps.Map) int {
+	qb := gou.Query.New().SQL(sql, bindings...)
+	// Debug模式 打印查询信息
+	if gou.Debug {
+		fmt.Println(sql)
+		utils.Dump(bindings)
 	}
-	return total
+	start := time.Now()
+	row := qb.MustFirst()
+	gou.slow(sql, bindings, data, start)
+	return row.GetInt("total")
 }
 
 // First 执行查询并返回一条数据记录
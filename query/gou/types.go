@@ -76,6 +76,8 @@ type QueryDSL struct {
 	SubQuery *QueryDSL    `json:"query,omitempty"`     // 子查询
 	Alias    string       `json:"name,omitempty"`      // 子查询别名
 	Joins    []Join       `json:"joins,omitempty"`     // 表连接
+	With     []CTE        `json:"with,omitempty"`      // 公用表表达式 (WITH / WITH RECURSIVE)
+	Windows  []Window     `json:"windows,omitempty"`   // 窗口函数 (追加至查询字段列表)
 	SQL      *SQL         `json:"sql,omitempty"`       // SQL语句
 	Comment  string       `json:"comment,omitempty"`   // 查询条件注释
 	Debug    bool         `json:"debug,omitempty"`     // 是否开启调试(开启后计入查询日志)
@@ -185,3 +187,21 @@ type SQL struct {
 	Args    []interface{} `json:"args,omitempty"`    // 绑定参数表
 	Comment string        `json:"comment,omitempty"` // SQL语句注释
 }
+
+// CTE 公用表表达式 WITH name (columns) AS (query)
+type CTE struct {
+	Name      string    `json:"name"`                // 表达式名称 (可在 from / joins 中引用)
+	Columns   []string  `json:"columns,omitempty"`   // 字段名称列表
+	Query     *QueryDSL `json:"query"`               // 查询条件, 递归查询使用 unions 连接锚点查询与递归查询
+	Recursive bool      `json:"recursive,omitempty"` // 是否为递归查询 WITH RECURSIVE
+	Comment   string    `json:"comment,omitempty"`   // 注释
+}
+
+// Window 窗口函数 :FUN(args) OVER (PARTITION BY ... ORDER BY ... frame) AS alias
+type Window struct {
+	Field     *Expression  `json:"field"`               // 窗口函数 :ROW_NUMBER() as rn, :SUM(amount) as total
+	Partition []Expression `json:"partition,omitempty"` // PARTITION BY 字段列表
+	Orders    Orders       `json:"orders,omitempty"`    // ORDER BY 排序条件
+	Frame     string       `json:"frame,omitempty"`     // 窗口范围 ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW
+	Comment   string       `json:"comment,omitempty"`   // 注释
+}
@@ -23,6 +23,8 @@ func (gou QueryDSL) Validate() []error {
 	errs = append(errs, gou.ValidateUnions()...)  // unions
 	errs = append(errs, gou.ValidateQuery()...)   // query
 	errs = append(errs, gou.ValidateJoins()...)   // joins
+	errs = append(errs, gou.ValidateWith()...)    // with
+	errs = append(errs, gou.ValidateWindows()...) // windows
 	errs = append(errs, gou.ValidateSQL()...)     // sql
 
 	return errs
@@ -76,6 +78,14 @@ func (gou QueryDSL) ToMap() map[string]interface{} {
 		res["sql"] = gou.SQL
 	}
 
+	if gou.With != nil {
+		res["with"] = gou.With
+	}
+
+	if gou.Windows != nil {
+		res["windows"] = gou.Windows
+	}
+
 	if gou.SubQuery != nil {
 		res["query"] = gou.SubQuery
 	}
@@ -234,6 +244,44 @@ func (gou QueryDSL) ValidateJoins() []error {
 	return errs
 }
 
+// ValidateWith 校验 with
+func (gou QueryDSL) ValidateWith() []error {
+	errs := []error{}
+	names := map[string]bool{}
+	for i, cte := range gou.With {
+		if err := cte.Validate(); err != nil {
+			errs = append(errs, errors.Errorf("参数错误: 第 %d 个 with 公用表表达式, %s", i+1, err.Error()))
+			continue
+		}
+		if names[cte.Name] {
+			errs = append(errs, errors.Errorf("参数错误: 第 %d 个 with 公用表表达式, 名称 %s 重复", i+1, cte.Name))
+		}
+		names[cte.Name] = true
+	}
+
+	for i, union := range gou.Unions {
+		if union.With != nil {
+			errs = append(errs, errors.Errorf("参数错误: 第 %d 个 union 查询, with 仅支持在顶层查询中使用", i+1))
+		}
+	}
+
+	if gou.SubQuery != nil && gou.SubQuery.With != nil {
+		errs = append(errs, errors.Errorf("参数错误: query 子查询, with 仅支持在顶层查询中使用"))
+	}
+	return errs
+}
+
+// ValidateWindows 校验 windows
+func (gou QueryDSL) ValidateWindows() []error {
+	errs := []error{}
+	for i, window := range gou.Windows {
+		if err := window.Validate(); err != nil {
+			errs = append(errs, errors.Errorf("参数错误: 第 %d 个 window 窗口函数, %s", i+1, err.Error()))
+		}
+	}
+	return errs
+}
+
 // ValidateSQL 校验 sql
 func (gou QueryDSL) ValidateSQL() []error {
 	errs := []error{}
@@ -0,0 +1,137 @@
+package gou
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/xun/dbal"
+)
+
+// RegWindowFrame 窗口范围 ROWS|RANGE|GROUPS BETWEEN ... AND ...
+var RegWindowFrame = regexp.MustCompile(`^(?i)(ROWS|RANGE|GROUPS)[ ]+[A-Za-z0-9 ]+$`)
+
+// MarshalJSON for json marshalJSON
+func (window Window) MarshalJSON() ([]byte, error) {
+	return jsoniter.Marshal(window.ToMap())
+}
+
+// ToMap Window 转换为 map[string]interface{}
+func (window Window) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	if window.Field != nil {
+		res["field"] = window.Field.ToString()
+	}
+
+	if len(window.Partition) > 0 {
+		partition := []string{}
+		for _, exp := range window.Partition {
+			partition = append(partition, exp.ToString())
+		}
+		res["partition"] = partition
+	}
+
+	if window.Orders != nil {
+		res["orders"] = window.Orders
+	}
+
+	if window.Frame != "" {
+		res["frame"] = window.Frame
+	}
+
+	if window.Comment != "" {
+		res["comment"] = window.Comment
+	}
+	return res
+}
+
+// Validate 校验窗口函数
+func (window Window) Validate() error {
+	if window.Field == nil {
+		return errors.Errorf("缺少 field")
+	}
+
+	if !window.Field.IsFun {
+		return errors.Errorf("field %s 不是函数", window.Field.ToString())
+	}
+
+	if window.Field.Alias == "" {
+		return errors.Errorf("field %s 缺少别名 (as)", window.Field.ToString())
+	}
+
+	for _, exp := range window.Partition {
+		if err := exp.Validate(); err != nil {
+			return errors.Errorf("partition %s", err.Error())
+		}
+	}
+
+	for _, order := range window.Orders {
+		if err := order.Validate(); err != nil {
+			return errors.Errorf("orders %s", err.Error())
+		}
+	}
+
+	if window.Frame != "" && !RegWindowFrame.MatchString(window.Frame) {
+		return errors.Errorf("frame %s 格式不正确", window.Frame)
+	}
+
+	return nil
+}
+
+// sqlWindow 窗口函数转换为 SQL
+func (gou Query) sqlWindow(window Window) interface{} {
+	fun := *window.Field
+	alias := fun.Alias
+	fun.Alias = ""
+
+	sql := ""
+	switch exp := gou.sqlExpression(fun).(type) {
+	case dbal.Expression:
+		sql = exp.GetValue()
+	case string:
+		sql = exp
+	default:
+		return nil
+	}
+
+	over := []string{}
+	if len(window.Partition) > 0 {
+		fields := []string{}
+		for _, field := range window.Partition {
+			fields = append(fields, gou.sqlRaw(field))
+		}
+		over = append(over, "PARTITION BY "+strings.Join(fields, ", "))
+	}
+
+	if len(window.Orders) > 0 {
+		orders := []string{}
+		for _, order := range window.Orders {
+			sort := strings.ToUpper(order.Sort)
+			if sort == "" {
+				sort = "ASC"
+			}
+			orders = append(orders, fmt.Sprintf("%s %s", gou.sqlRaw(*order.Field), sort))
+		}
+		over = append(over, "ORDER BY "+strings.Join(orders, ", "))
+	}
+
+	if window.Frame != "" {
+		over = append(over, strings.ToUpper(window.Frame))
+	}
+
+	return dbal.Raw(fmt.Sprintf("%s OVER (%s) AS %s", sql, strings.Join(over, " "), gou.Quote(alias)))
+}
+
+// sqlRaw 字段表达式转换为 SQL 字符串 (不含别名)
+func (gou Query) sqlRaw(exp Expression) string {
+	exp.Alias = ""
+	switch value := gou.sqlExpression(exp).(type) {
+	case dbal.Expression:
+		return value.GetValue()
+	case string:
+		return value
+	}
+	return ""
+}
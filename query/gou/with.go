@@ -0,0 +1,116 @@
+package gou
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// RegCTEName 公用表表达式名称
+var RegCTEName = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// Validate 校验公用表表达式
+func (cte CTE) Validate() error {
+	if !RegCTEName.MatchString(cte.Name) {
+		return errors.Errorf("name %s 格式不正确", cte.Name)
+	}
+
+	for _, column := range cte.Columns {
+		if !RegCTEName.MatchString(column) {
+			return errors.Errorf("columns %s 格式不正确", column)
+		}
+	}
+
+	if cte.Query == nil {
+		return errors.Errorf("%s 缺少 query", cte.Name)
+	}
+
+	if cte.Query.With != nil {
+		return errors.Errorf("%s 不支持嵌套 with", cte.Name)
+	}
+
+	errs := cte.Query.Validate()
+	if len(errs) > 0 {
+		return errors.Errorf("%s %s", cte.Name, errs[0].Error())
+	}
+
+	if cte.Recursive && len(cte.Query.Unions) == 0 {
+		return errors.Errorf("%s 递归查询需使用 unions 连接锚点查询与递归查询", cte.Name)
+	}
+	return nil
+}
+
+// buildWith WITH 公用表表达式
+func (gou *Query) buildWith() *Query {
+	gou.with = ""
+	gou.withBindings = []interface{}{}
+	if len(gou.With) == 0 {
+		return gou
+	}
+
+	recursive := false
+	parts := []string{}
+	for _, cte := range gou.With {
+		if cte.Recursive {
+			recursive = true
+		}
+
+		gouCTE := gou.Clone()
+		gouCTE.QueryDSL = *cte.Query
+		gouCTE.Query = gou.Query.New()
+		gouCTE.Build()
+
+		sql := gouCTE.Query.ToSQL()
+		bindings := gouCTE.Query.GetBindings()
+		if gou.driver == "postgres" {
+			sql = shiftPlaceholders(sql, len(gou.withBindings))
+		}
+
+		name := gou.Quote(cte.Name)
+		if len(cte.Columns) > 0 {
+			columns := []string{}
+			for _, column := range cte.Columns {
+				columns = append(columns, gou.Quote(column))
+			}
+			name = fmt.Sprintf("%s(%s)", name, strings.Join(columns, ", "))
+		}
+
+		parts = append(parts, fmt.Sprintf("%s AS (%s)", name, sql))
+		gou.withBindings = append(gou.withBindings, bindings...)
+	}
+
+	keyword := "WITH "
+	if recursive {
+		keyword = "WITH RECURSIVE "
+	}
+	gou.with = keyword + strings.Join(parts, ", ") + " "
+	return gou
+}
+
+// withSQL 在查询语句前添加 WITH 公用表表达式
+func (gou Query) withSQL(sql string) string {
+	if gou.with == "" {
+		return sql
+	}
+	if gou.driver == "postgres" {
+		sql = shiftPlaceholders(sql, len(gou.withBindings))
+	}
+	return gou.with + sql
+}
+
+// RegPlaceholder Postgres 绑定参数占位符 $1
+var RegPlaceholder = regexp.MustCompile(`\$([0-9]+)`)
+
+// shiftPlaceholders Postgres 绑定参数占位符序号偏移 ($1 -> $n+1)
+func shiftPlaceholders(sql string, offset int) string {
+	if offset == 0 {
+		return sql
+	}
+	return RegPlaceholder.ReplaceAllStringFunc(sql, func(placeholder string) string {
+		var index int
+		fmt.Sscanf(placeholder, "$%d", &index)
+		return fmt.Sprintf("$%d", index+offset)
+	})
+}
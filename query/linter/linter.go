@@ -334,6 +334,12 @@ func (r *LintResult) validateDSL(source string) {
 
 	// Validate subquery
 	r.validateSubQuery(source)
+
+	// Validate with (CTEs)
+	r.validateWith(source)
+
+	// Validate windows
+	r.validateWindows(source)
 }
 
 // findFieldPosition finds the position of a field in the source
@@ -712,3 +718,82 @@ func (r *LintResult) validateSubQuery(source string) {
 		})
 	}
 }
+
+// validateWith validates the with clause (common table expressions)
+func (r *LintResult) validateWith(source string) {
+	names := map[string]bool{}
+	for i, cte := range r.DSL.With {
+		path := fmt.Sprintf("with[%d]", i)
+		if err := cte.Validate(); err != nil {
+			pos := r.findFieldPosition(path, source)
+			r.Diagnostics = append(r.Diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Position: pos,
+				Path:     path,
+				Code:     "E200",
+			})
+			continue
+		}
+
+		if names[cte.Name] {
+			pos := r.findFieldPosition(path+".name", source)
+			r.Diagnostics = append(r.Diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Duplicate CTE name: %s", cte.Name),
+				Position: pos,
+				Path:     path + ".name",
+				Code:     "E201",
+				Source:   cte.Name,
+			})
+		}
+		names[cte.Name] = true
+	}
+
+	for i, union := range r.DSL.Unions {
+		if union.With != nil {
+			path := fmt.Sprintf("unions[%d].with", i)
+			pos := r.findFieldPosition(path, source)
+			r.Diagnostics = append(r.Diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Message:  "with is only supported in the top-level query",
+				Position: pos,
+				Path:     path,
+				Code:     "E202",
+			})
+		}
+	}
+
+	if r.DSL.SubQuery != nil && r.DSL.SubQuery.With != nil {
+		pos := r.findFieldPosition("query.with", source)
+		r.Diagnostics = append(r.Diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Message:  "with is only supported in the top-level query",
+			Position: pos,
+			Path:     "query.with",
+			Code:     "E202",
+		})
+	}
+}
+
+// validateWindows validates the window functions
+func (r *LintResult) validateWindows(source string) {
+	for i, window := range r.DSL.Windows {
+		path := fmt.Sprintf("windows[%d]", i)
+		if err := window.Validate(); err != nil {
+			pos := r.findFieldPosition(path, source)
+			origin := ""
+			if window.Field != nil {
+				origin = window.Field.Origin
+			}
+			r.Diagnostics = append(r.Diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Position: pos,
+				Path:     path,
+				Code:     "E210",
+				Source:   origin,
+			})
+		}
+	}
+}
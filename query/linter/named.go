@@ -0,0 +1,300 @@
+package linter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamTypes the supported named query parameter types
+var ParamTypes = map[string]bool{
+	"string":  true,
+	"integer": true,
+	"number":  true,
+	"boolean": true,
+	"array":   true,
+	"object":  true,
+	"any":     true,
+}
+
+// regParamRef matches the parameter references of a named query, e.g. "?:status", "?:filter.name"
+var regParamRef = regexp.MustCompile(`\?:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// NamedQuery is a saved, parameterised query (queries/*.query.yao)
+//
+//	{
+//	  "name": "Active users",
+//	  "engine": "default",
+//	  "params": { "status": { "type": "string", "default": "enabled" } },
+//	  "query": { "select": ["id", "name"], "from": "user", "wheres": [{ "field": "status", "=": "?:status" }] }
+//	}
+type NamedQuery struct {
+	Name    string           `json:"name,omitempty"`    // Query name
+	Engine  string           `json:"engine,omitempty"`  // Query engine name, "default" if empty
+	Params  map[string]Param `json:"params,omitempty"`  // Declared parameters
+	Query   json.RawMessage  `json:"query"`             // Query DSL, parameters are referenced with ?:name
+	Comment string           `json:"comment,omitempty"` // Comment
+}
+
+// Param is a named query parameter declaration
+type Param struct {
+	Type     string        `json:"type,omitempty"`     // string, integer, number, boolean, array, object, any (default)
+	Default  interface{}   `json:"default,omitempty"`  // Default value
+	Required bool          `json:"required,omitempty"` // The parameter must be given when no default is declared
+	Enum     []interface{} `json:"enum,omitempty"`     // Allowed values
+	Comment  string        `json:"comment,omitempty"`  // Comment
+}
+
+// Cast converts the value to the declared parameter type and checks the enum
+func (p Param) Cast(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	var res interface{} = value
+	switch p.Type {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			res = v
+		case float64, float32, int, int64, int32, bool:
+			res = fmt.Sprintf("%v", v)
+		default:
+			return nil, fmt.Errorf("should be a string, got %T", value)
+		}
+
+	case "integer":
+		switch v := value.(type) {
+		case int:
+			res = v
+		case int64:
+			res = int(v)
+		case int32:
+			res = int(v)
+		case float64:
+			if v != float64(int(v)) {
+				return nil, fmt.Errorf("should be an integer, got %v", v)
+			}
+			res = int(v)
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("should be an integer, got %q", v)
+			}
+			res = n
+		default:
+			return nil, fmt.Errorf("should be an integer, got %T", value)
+		}
+
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			res = v
+		case float32:
+			res = float64(v)
+		case int:
+			res = float64(v)
+		case int64:
+			res = float64(v)
+		case int32:
+			res = float64(v)
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("should be a number, got %q", v)
+			}
+			res = n
+		default:
+			return nil, fmt.Errorf("should be a number, got %T", value)
+		}
+
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			res = v
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("should be a boolean, got %q", v)
+			}
+			res = b
+		default:
+			return nil, fmt.Errorf("should be a boolean, got %T", value)
+		}
+
+	case "array":
+		switch v := value.(type) {
+		case []interface{}:
+			res = v
+		case []string:
+			arr := []interface{}{}
+			for _, s := range v {
+				arr = append(arr, s)
+			}
+			res = arr
+		case string: // comma-separated
+			arr := []interface{}{}
+			for _, s := range strings.Split(v, ",") {
+				arr = append(arr, strings.TrimSpace(s))
+			}
+			res = arr
+		default:
+			return nil, fmt.Errorf("should be an array, got %T", value)
+		}
+
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("should be an object, got %T", value)
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		for _, item := range p.Enum {
+			if fmt.Sprintf("%v", item) == fmt.Sprintf("%v", res) {
+				return res, nil
+			}
+		}
+		return nil, fmt.Errorf("%v is not one of %v", res, p.Enum)
+	}
+
+	return res, nil
+}
+
+// ParseNamed parses a named query source and validates the parameter declarations,
+// the parameter references and the embedded Query DSL.
+// Returns: (*NamedQuery, *LintResult) - NamedQuery is nil if parsing/validation failed
+func ParseNamed(source string) (named *NamedQuery, result *LintResult) {
+	result = &LintResult{
+		Source:      source,
+		Diagnostics: []Diagnostic{},
+		Valid:       true,
+	}
+	result.buildLineOffsets()
+
+	var parsed NamedQuery
+	if err := json.Unmarshal([]byte(source), &parsed); err != nil {
+		result.addJSONError(err)
+		result.Valid = false
+		return nil, result
+	}
+
+	result.validateParams(parsed, source)
+	result.validateNamedQuery(parsed, source)
+	result.Valid = !result.HasErrors()
+	if result.Valid {
+		return &parsed, result
+	}
+	return nil, result
+}
+
+// LintNamed validates a named query source and returns only the LintResult.
+func LintNamed(source string) *LintResult {
+	_, result := ParseNamed(source)
+	return result
+}
+
+// validateParams validates the parameter declarations
+func (r *LintResult) validateParams(named NamedQuery, source string) {
+	names := []string{}
+	for name := range named.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		param := named.Params[name]
+		path := "params." + name
+		message := ""
+		if param.Type != "" && !ParamTypes[param.Type] {
+			message = fmt.Sprintf("Unknown parameter type: %s", param.Type)
+		} else if param.Default != nil {
+			if _, err := param.Cast(param.Default); err != nil {
+				message = fmt.Sprintf("Invalid default value: %s", err.Error())
+			}
+		}
+
+		if message == "" {
+			continue
+		}
+
+		r.Diagnostics = append(r.Diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Message:  message,
+			Position: r.findFieldPosition(path, source),
+			Path:     path,
+			Code:     "E220",
+			Source:   name,
+		})
+	}
+}
+
+// validateNamedQuery validates the parameter references and the embedded Query DSL
+func (r *LintResult) validateNamedQuery(named NamedQuery, source string) {
+
+	raw := bytes.TrimSpace(named.Query)
+	if len(raw) == 0 || string(raw) == "null" {
+		r.Diagnostics = append(r.Diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Message:  "Missing required field: query",
+			Position: Position{Line: 1, Column: 1, EndLine: 1, EndColumn: 1},
+			Path:     "query",
+			Code:     "E222",
+		})
+		return
+	}
+
+	// Parameter references
+	base := strings.Index(source, string(named.Query))
+	for _, match := range regParamRef.FindAllSubmatchIndex(raw, -1) {
+		name := string(raw[match[2]:match[3]])
+		if _, has := named.Params[name]; has {
+			continue
+		}
+
+		pos := Position{Line: 1, Column: 1, EndLine: 1, EndColumn: 1}
+		if base >= 0 {
+			pos = r.position(base+match[0], match[1]-match[0])
+		}
+
+		r.Diagnostics = append(r.Diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Undeclared parameter: %s", name),
+			Position: pos,
+			Path:     "query",
+			Code:     "E221",
+			Source:   string(raw[match[0]:match[1]]),
+		})
+	}
+
+	// The Tai cross-source DSL declares sources instead of a Gou Query DSL
+	var probe map[string]interface{}
+	if err := json.Unmarshal(raw, &probe); err == nil {
+		if _, has := probe["sources"]; has {
+			return
+		}
+	}
+
+	// Embedded Query DSL, the positions are shifted to the named query source
+	sub := Lint(string(raw))
+	for _, d := range sub.Diagnostics {
+		if base >= 0 {
+			d.Position = r.position(base+d.Position.Offset, d.Position.EndOffset-d.Position.Offset)
+		}
+		d.Path = strings.TrimSuffix("query."+d.Path, ".")
+		r.Diagnostics = append(r.Diagnostics, d)
+	}
+}
+
+// position returns the position of the source range
+func (r *LintResult) position(offset, length int) Position {
+	if length < 1 {
+		length = 1
+	}
+	line, col := r.offsetToPosition(offset)
+	endLine, endCol := r.offsetToPosition(offset + length)
+	return Position{Line: line, Column: col, Offset: offset, EndLine: endLine, EndColumn: endCol, EndOffset: offset + length}
+}
@@ -0,0 +1,106 @@
+package linter
+
+import (
+	"testing"
+)
+
+func TestParseNamed(t *testing.T) {
+	source := `{
+  "name": "Active users",
+  "params": {
+    "status": { "type": "string", "default": "enabled" },
+    "limit": { "type": "integer", "default": 20 }
+  },
+  "query": {
+    "select": ["id", "name"],
+    "from": "user",
+    "wheres": [{ "field": "status", "=": "?:status" }],
+    "limit": "?:limit"
+  }
+}`
+
+	named, result := ParseNamed(source)
+	if !result.Valid {
+		t.Fatalf("expected valid, got:\n%s", result.FormatDiagnostics())
+	}
+	if named.Name != "Active users" || len(named.Params) != 2 {
+		t.Fatalf("unexpected named query: %#v", named)
+	}
+}
+
+func TestParseNamedErrors(t *testing.T) {
+	source := `{
+  "params": {
+    "limit": { "type": "integer", "default": "many" },
+    "kind": { "type": "uuid" }
+  },
+  "query": {
+    "select": ["id"],
+    "wheres": [{ "field": "status", "=": "?:status" }]
+  }
+}`
+
+	named, result := ParseNamed(source)
+	if named != nil || result.Valid {
+		t.Fatal("expected invalid")
+	}
+
+	codes := map[string]int{}
+	for _, d := range result.Errors() {
+		codes[d.Code]++
+	}
+
+	if codes["E220"] != 2 {
+		t.Errorf("expected 2 E220, got %d:\n%s", codes["E220"], result.FormatDiagnostics())
+	}
+
+	if codes["E221"] != 1 {
+		t.Errorf("expected 1 E221, got %d:\n%s", codes["E221"], result.FormatDiagnostics())
+	}
+
+	// Missing from, reported inside the embedded query
+	if codes["E110"] != 1 {
+		t.Errorf("expected 1 E110, got %d:\n%s", codes["E110"], result.FormatDiagnostics())
+	}
+
+	for _, d := range result.Errors() {
+		if d.Code == "E221" && d.Position.Line != 8 {
+			t.Errorf("expected E221 at line 8, got %s", d.Position.String())
+		}
+	}
+
+	_, result = ParseNamed(`{"params": {}}`)
+	if result.Valid || result.Errors()[0].Code != "E222" {
+		t.Errorf("expected E222, got:\n%s", result.FormatDiagnostics())
+	}
+}
+
+func TestParamCast(t *testing.T) {
+	cases := []struct {
+		param Param
+		value interface{}
+		want  interface{}
+		err   bool
+	}{
+		{Param{Type: "integer"}, "12", 12, false},
+		{Param{Type: "integer"}, 12.0, 12, false},
+		{Param{Type: "integer"}, 1.5, nil, true},
+		{Param{Type: "number"}, "1.5", 1.5, false},
+		{Param{Type: "boolean"}, "true", true, false},
+		{Param{Type: "string"}, 1, "1", false},
+		{Param{Type: "object"}, "x", nil, true},
+		{Param{Type: "string", Enum: []interface{}{"a", "b"}}, "c", nil, true},
+		{Param{}, "x", "x", false},
+	}
+
+	for i, c := range cases {
+		got, err := c.param.Cast(c.value)
+		if (err != nil) != c.err {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if !c.err && got != c.want {
+			t.Errorf("case %d: expected %v, got %v", i, c.want, got)
+		}
+	}
+}
@@ -40,6 +40,13 @@ import (
 //   - string: "kind, city rollup 所有城市"
 //   - string array: ["kind", "city rollup 所有城市"]
 //   - object array: [{"field": "kind"}, {"field": "city", "rollup": "所有城市"}]
+//
+// With Syntax (common table expressions, top-level query only):
+//   - [{"name": "tree", "columns": ["id", "parent"], "recursive": true, "query": {..., "unions": [...]}}]
+//
+// Windows Syntax (appended to the select list):
+//   - [{"field": ":ROW_NUMBER() as rn", "partition": ["user_id"], "orders": ["created_at desc"]}]
+//   - [{"field": ":SUM(amount) as running", "orders": ["id"], "frame": "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW"}]
 const QueryDSLSchemaJSON = `{
   "$schema": "http://json-schema.org/draft-07/schema#",
   "title": "QueryDSL",
@@ -149,6 +156,36 @@ const QueryDSLSchemaJSON = `{
       },
       "required": ["from", "key", "foreign"]
     },
+    "cte": {
+      "type": "object",
+      "description": "Common table expression: WITH [RECURSIVE] name (columns) AS (query)",
+      "properties": {
+        "name": { "type": "string", "pattern": "^[A-Za-z_][A-Za-z0-9_]*$", "description": "CTE name, referenced by from/joins" },
+        "columns": { "type": "array", "items": { "type": "string" }, "description": "Column names" },
+        "query": { "$ref": "#", "description": "CTE query. Recursive CTEs join the anchor and recursive parts with unions" },
+        "recursive": { "type": "boolean", "description": "WITH RECURSIVE", "default": false },
+        "comment": { "type": "string" }
+      },
+      "required": ["name", "query"]
+    },
+    "window": {
+      "type": "object",
+      "description": "Window function: :FUNC(args) OVER (PARTITION BY ... ORDER BY ... frame) AS alias",
+      "properties": {
+        "field": { "$ref": "#/definitions/expression", "description": "Window function with alias, e.g. ':ROW_NUMBER() as rn'" },
+        "partition": { "type": "array", "items": { "$ref": "#/definitions/expression" }, "description": "PARTITION BY fields" },
+        "orders": {
+          "oneOf": [
+            { "type": "array", "items": { "$ref": "#/definitions/order" } },
+            { "type": "string", "description": "Comma-separated: 'id desc, name asc'" }
+          ],
+          "description": "ORDER BY within the window"
+        },
+        "frame": { "type": "string", "description": "Frame clause, e.g. 'ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW'" },
+        "comment": { "type": "string" }
+      },
+      "required": ["field"]
+    },
     "sql": {
       "type": "object",
       "description": "Raw SQL statement",
@@ -208,6 +245,16 @@ const QueryDSLSchemaJSON = `{
       "$ref": "#",
       "description": "Subquery (alternative to from)"
     },
+    "with": {
+      "type": "array",
+      "description": "WITH common table expressions (top-level query only)",
+      "items": { "$ref": "#/definitions/cte" }
+    },
+    "windows": {
+      "type": "array",
+      "description": "Window functions appended to the select list",
+      "items": { "$ref": "#/definitions/window" }
+    },
     "name": {
       "type": "string",
       "description": "Subquery alias"
@@ -0,0 +1,387 @@
+package mongo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/query/document"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/kun/maps"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Accumulators 支持的聚合函数
+var Accumulators = map[string]string{
+	"count": "$sum",
+	"sum":   "$sum",
+	"avg":   "$avg",
+	"max":   "$max",
+	"min":   "$min",
+	"first": "$first",
+	"last":  "$last",
+}
+
+// OPs 比较运算符
+var OPs = map[string]string{
+	"=":  "$eq",
+	">":  "$gt",
+	">=": "$gte",
+	"<":  "$lt",
+	"<=": "$lte",
+	"<>": "$ne",
+}
+
+// Validate 校验 MongoDB 不支持的查询条件
+func Validate(dsl gou.QueryDSL) []error {
+	errs := []error{}
+	if dsl.SQL != nil {
+		errs = append(errs, errors.Errorf("MongoDB 不支持 sql"))
+	}
+
+	if dsl.From == nil {
+		errs = append(errs, errors.Errorf("缺少 from (数据集合名称)"))
+	} else if dsl.From.IsModel {
+		errs = append(errs, errors.Errorf("MongoDB 不支持数据模型 %s", dsl.From.Name))
+	}
+
+	if len(dsl.Joins) > 0 || len(dsl.Unions) > 0 || dsl.SubQuery != nil || len(dsl.With) > 0 || len(dsl.Windows) > 0 {
+		errs = append(errs, errors.Errorf("MongoDB 不支持 joins, unions, query, with, windows"))
+	}
+
+	for _, exp := range dsl.Select {
+		if exp.IsFun {
+			if _, has := Accumulators[strings.ToLower(exp.FunName)]; !has {
+				errs = append(errs, errors.Errorf("select %s 函数暂不支持", exp.ToString()))
+			}
+		}
+	}
+
+	if dsl.Groups != nil {
+		for _, group := range *dsl.Groups {
+			if group.Rollup != "" {
+				errs = append(errs, errors.Errorf("groups %s MongoDB 不支持 rollup", group.Field.ToString()))
+			}
+		}
+	}
+
+	errs = append(errs, validateWheres(dsl.Wheres)...)
+	return errs
+}
+
+func validateWheres(wheres []gou.Where) []error {
+	errs := []error{}
+	for _, where := range wheres {
+		if where.Query != nil {
+			errs = append(errs, errors.Errorf("wheres MongoDB 不支持子查询"))
+		}
+		if where.ValueExpression != nil {
+			if _, has := OPs[where.OP]; !has {
+				errs = append(errs, errors.Errorf("wheres %s 字段比较不支持 %s", where.Field.ToString(), where.OP))
+			}
+		}
+		errs = append(errs, validateWheres(where.Wheres)...)
+	}
+	return errs
+}
+
+// Pipeline 生成聚合管道 ($match, $group, $sort), 不含分页 ($skip, $limit) 及字段映射
+func (m Query) Pipeline(data maps.Map) ([]interface{}, error) {
+
+	pipeline := []interface{}{}
+	match, err := m.filter(m.Wheres, data, m.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if match != nil {
+		pipeline = append(pipeline, bson.M{"$match": match})
+	}
+
+	// 非聚合查询
+	if !m.aggregated() {
+		if sort := m.sort(m.path); len(sort) > 0 {
+			pipeline = append(pipeline, bson.M{"$sort": sort})
+		}
+		return pipeline, nil
+	}
+
+	// 聚合查询
+	group, project := m.group()
+	pipeline = append(pipeline, bson.M{"$group": group}, bson.M{"$project": project})
+
+	having, err := m.filter(havings(m.Havings), data, m.output)
+	if err != nil {
+		return nil, err
+	}
+
+	if having != nil {
+		pipeline = append(pipeline, bson.M{"$match": having})
+	}
+
+	if sort := m.sort(m.output); len(sort) > 0 {
+		pipeline = append(pipeline, bson.M{"$sort": sort})
+	}
+
+	return pipeline, nil
+}
+
+// project 非聚合查询的字段映射 (聚合查询在 $group 之后映射)
+func (m Query) project() []interface{} {
+	if m.aggregated() || len(m.Select) == 0 {
+		return []interface{}{}
+	}
+
+	project := bson.M{}
+	for _, exp := range m.Select {
+		switch {
+		case exp.IsConst || exp.IsNumber || exp.IsString:
+			project[m.name(exp)] = bson.M{"$literal": exp.Value}
+		case exp.Alias != "":
+			project[exp.Alias] = "$" + m.path(exp)
+		default:
+			project[m.path(exp)] = 1
+		}
+	}
+
+	if _, has := project["_id"]; !has {
+		project["_id"] = 0
+	}
+	return []interface{}{bson.M{"$project": project}}
+}
+
+// aggregated 是否为聚合查询
+func (m Query) aggregated() bool {
+	if m.Groups != nil && len(*m.Groups) > 0 {
+		return true
+	}
+	for _, exp := range m.Select {
+		if exp.IsFun {
+			return true
+		}
+	}
+	return false
+}
+
+// group 生成 $group 及展开分组键的 $project
+func (m Query) group() (bson.M, bson.M) {
+
+	group := bson.M{"_id": nil}
+	project := bson.M{"_id": 0}
+
+	if m.Groups != nil && len(*m.Groups) > 0 {
+		keys := bson.M{}
+		for _, g := range *m.Groups {
+			name := m.output(*g.Field)
+			keys[name] = "$" + m.path(*g.Field)
+			project[name] = "$_id." + name
+		}
+		group["_id"] = keys
+	}
+
+	for _, exp := range m.Select {
+		if !exp.IsFun {
+			continue
+		}
+
+		name := m.output(exp)
+		fun := strings.ToLower(exp.FunName)
+		var arg interface{} = 1
+		if len(exp.FunArgs) > 0 {
+			arg = m.accumulate(fun, exp.FunArgs[0])
+		}
+
+		group[name] = bson.M{Accumulators[fun]: arg}
+		project[name] = 1
+	}
+
+	return group, project
+}
+
+// accumulate 聚合函数参数, count(field) 与 SQL 一致仅计数非 null 的值, count(*) 和 count(常量) 计数全部行
+func (m Query) accumulate(fun string, arg gou.Expression) interface{} {
+	if fun != "count" {
+		return "$" + m.path(arg)
+	}
+
+	if arg.IsConst || arg.Field == "*" || arg.Field == "" {
+		return 1
+	}
+
+	return bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + m.path(arg), nil}}, nil}},
+		0,
+		1,
+	}}
+}
+
+// sort 生成 $sort
+func (m Query) sort(name func(gou.Expression) string) bson.D {
+	sort := bson.D{}
+	for _, order := range m.Orders {
+		direction := 1
+		if strings.ToLower(order.Sort) == "desc" {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: name(*order.Field), Value: direction})
+	}
+	return sort
+}
+
+// filter 查询条件转换为 $match 条件, 连续的 and 条件为一组, or 条件开始新的分组
+func (m Query) filter(wheres []gou.Where, data maps.Map, name func(gou.Expression) string) (bson.M, error) {
+
+	if len(wheres) == 0 {
+		return nil, nil
+	}
+
+	groups := [][]interface{}{{}}
+	for i, where := range wheres {
+		var cond bson.M
+		var err error
+		if where.Wheres != nil {
+			cond, err = m.filter(where.Wheres, data, name)
+		} else {
+			cond, err = m.condition(where.Condition, data, name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if cond == nil {
+			continue
+		}
+
+		if where.OR && i > 0 {
+			groups = append(groups, []interface{}{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], cond)
+	}
+
+	ors := []interface{}{}
+	for _, group := range groups {
+		switch len(group) {
+		case 0:
+			continue
+		case 1:
+			ors = append(ors, group[0])
+		default:
+			ors = append(ors, bson.M{"$and": group})
+		}
+	}
+
+	switch len(ors) {
+	case 0:
+		return nil, nil
+	case 1:
+		return ors[0].(bson.M), nil
+	}
+	return bson.M{"$or": ors}, nil
+}
+
+// condition 查询条件转换为 MongoDB 查询条件
+func (m Query) condition(cond gou.Condition, data maps.Map, name func(gou.Expression) string) (bson.M, error) {
+
+	if cond.Field == nil {
+		return nil, nil
+	}
+
+	field := name(*cond.Field)
+
+	// 字段比较 {field}
+	if cond.ValueExpression != nil {
+		op, has := OPs[cond.OP]
+		if !has {
+			return nil, errors.Errorf("%s 字段比较不支持 %s", field, cond.OP)
+		}
+		return bson.M{"$expr": bson.M{op: bson.A{"$" + field, "$" + name(*cond.ValueExpression)}}}, nil
+	}
+
+	value := helper.Bind(cond.Value, data)
+	if op, has := OPs[cond.OP]; has {
+		return bson.M{field: bson.M{op: value}}, nil
+	}
+
+	switch cond.OP {
+	case "in":
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		return bson.M{field: bson.M{"$in": values}}, nil
+
+	case "like":
+		return bson.M{field: bson.M{"$regex": Like(fmt.Sprintf("%v", value))}}, nil
+
+	case "match":
+		return bson.M{field: bson.M{"$regex": regexp.QuoteMeta(fmt.Sprintf("%v", value)), "$options": "i"}}, nil
+
+	case "is":
+		if value == "not null" {
+			return bson.M{field: bson.M{"$ne": nil}}, nil
+		}
+		return bson.M{field: nil}, nil
+	}
+
+	return nil, errors.Errorf("%s 操作符暂不支持", cond.OP)
+}
+
+// Like SQL LIKE 匹配模式转换为正则表达式 (% 任意字符, _ 单个字符)
+func Like(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// path 字段表达式对应的文档路径 (meta.age, object$.key, array[0])
+func (m Query) path(exp gou.Expression) string {
+	path := exp.Field
+	if exp.Table != "" && (m.From == nil || (exp.Table != m.From.Name && exp.Table != m.From.Alias)) {
+		path = exp.Table + "." + path
+	}
+
+	if exp.IsArray && exp.Index != gou.Star {
+		path = fmt.Sprintf("%s.%d", path, exp.Index)
+	}
+
+	if (exp.IsObject || exp.IsArray) && exp.Key != "" {
+		path = path + "." + strings.TrimPrefix(exp.Key, ".")
+	}
+	return path
+}
+
+// name 查询结果字段名称
+func (m Query) name(exp gou.Expression) string {
+	return document.Name(exp, m.path)
+}
+
+// output 聚合查询结果字段名称, 优先使用 select 中的别名 (不能包含 .)
+func (m Query) output(exp gou.Expression) string {
+	return document.Output(m.Select, exp, m.path)
+}
+
+// havings 聚合查询筛选条件转换为 wheres
+func havings(items []gou.Having) []gou.Where {
+	if items == nil {
+		return nil
+	}
+	wheres := []gou.Where{}
+	for _, having := range items {
+		wheres = append(wheres, gou.Where{Condition: having.Condition, Wheres: havings(having.Havings)})
+	}
+	return wheres
+}
@@ -0,0 +1,129 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/kun/maps"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func load(t *testing.T, dsl map[string]interface{}) *Query {
+	q, err := New(nil).Load(dsl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q.(*Query)
+}
+
+func TestLoad(t *testing.T) {
+	_, err := New(nil).Load(map[string]interface{}{
+		"select": []interface{}{"id"},
+		"from":   "users",
+		"joins":  []interface{}{map[string]interface{}{"from": "pets", "key": "pets.owner", "foreign": "users.id"}},
+	})
+	assert.NotNil(t, err)
+
+	_, err = New(nil).Load(map[string]interface{}{"select": []interface{}{":CONCAT(name, id) as n"}, "from": "users"})
+	assert.NotNil(t, err)
+
+	_, err = New(nil).Load(map[string]interface{}{"select": []interface{}{"id"}, "from": "$user"})
+	assert.NotNil(t, err)
+
+	q := load(t, map[string]interface{}{"select": []interface{}{"id"}, "from": "users"})
+	assert.Equal(t, "users", q.Collection())
+}
+
+func TestPipelineWheres(t *testing.T) {
+	q := load(t, map[string]interface{}{
+		"select": []interface{}{"name", "meta.age as age"},
+		"from":   "users",
+		"wheres": []interface{}{
+			map[string]interface{}{"field": "status", "=": "?:$in.status"},
+			map[string]interface{}{"field": "meta.age", ">=": 18},
+			map[string]interface{}{"or": true, "field": "name", "like": "A%"},
+			map[string]interface{}{"wheres": []interface{}{
+				map[string]interface{}{"field": "role", "in": []interface{}{"admin", "owner"}},
+				map[string]interface{}{"or": true, "field": "deleted_at", "is": "null"},
+			}},
+		},
+		"orders": "meta.age desc, name",
+	})
+
+	pipeline, err := q.Pipeline(maps.Map{"$in.status": "enabled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []interface{}{
+		bson.M{"$match": bson.M{"$or": []interface{}{
+			bson.M{"$and": []interface{}{
+				bson.M{"status": bson.M{"$eq": "enabled"}},
+				bson.M{"meta.age": bson.M{"$gte": float64(18)}},
+			}},
+			bson.M{"$and": []interface{}{
+				bson.M{"name": bson.M{"$regex": "^A.*$"}},
+				bson.M{"$or": []interface{}{
+					bson.M{"role": bson.M{"$in": []interface{}{"admin", "owner"}}},
+					bson.M{"deleted_at": nil},
+				}},
+			}},
+		}}},
+		bson.M{"$sort": bson.D{{Key: "meta.age", Value: -1}, {Key: "name", Value: 1}}},
+	}, pipeline)
+
+	assert.Equal(t, []interface{}{
+		bson.M{"$project": bson.M{"name": 1, "age": "$meta.age", "_id": 0}},
+	}, q.project())
+}
+
+func TestPipelineGroups(t *testing.T) {
+	q := load(t, map[string]interface{}{
+		"select":  []interface{}{"city", ":COUNT(id) as total", ":AVG(meta.age) as age"},
+		"from":    "users",
+		"groups":  []interface{}{"city"},
+		"havings": []interface{}{map[string]interface{}{"field": "total", ">": 1}},
+		"orders":  []interface{}{map[string]interface{}{"field": "total", "sort": "desc"}},
+	})
+
+	pipeline, err := q.Pipeline(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []interface{}{
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"city": "$city"},
+			"total": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$id", nil}}, nil}}, 0, 1}}},
+			"age":   bson.M{"$avg": "$meta.age"},
+		}},
+		bson.M{"$project": bson.M{"_id": 0, "city": "$_id.city", "total": 1, "age": 1}},
+		bson.M{"$match": bson.M{"total": bson.M{"$gt": float64(1)}}},
+		bson.M{"$sort": bson.D{{Key: "total", Value: -1}}},
+	}, pipeline)
+	assert.Len(t, q.project(), 0)
+
+	// count(*) and count(constant) count all the rows
+	assert.Equal(t, 1, q.accumulate("count", gou.Expression{Field: "*"}))
+	assert.Equal(t, 1, q.accumulate("count", gou.Expression{IsConst: true, Value: 1}))
+}
+
+func TestPipelineFieldCompare(t *testing.T) {
+	q := load(t, map[string]interface{}{
+		"select": []interface{}{"id"},
+		"from":   "orders",
+		"wheres": []interface{}{map[string]interface{}{"field": "paid", "<": "{total}"}},
+	})
+
+	pipeline, err := q.Pipeline(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.M{"$match": bson.M{"$expr": bson.M{"$lt": bson.A{"$paid", "$total"}}}}, pipeline[0])
+}
+
+func TestLike(t *testing.T) {
+	assert.Equal(t, "^A.*$", Like("A%"))
+	assert.Equal(t, "^a\\.b.c$", Like("a.b_c"))
+}
@@ -0,0 +1,184 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/gou/query/document"
+	"github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/kun/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Query MongoDB 查询引擎, 将 Gou Query DSL 转换为聚合管道 (aggregation pipeline)
+type Query struct {
+	gou.QueryDSL
+	Database *mongo.Database
+	Timeout  time.Duration
+}
+
+// New 创建 MongoDB 查询引擎
+func New(database *mongo.Database) *Query {
+	return &Query{Database: database, Timeout: 30 * time.Second}
+}
+
+// ==================================================
+// share.DSL Interface
+// ==================================================
+
+// Load 加载查询条件
+func (m *Query) Load(data interface{}) (share.DSL, error) {
+	dsl, err := document.Parse(data, Validate)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{QueryDSL: dsl, Database: m.Database, Timeout: m.Timeout}, nil
+}
+
+// Run 执行查询根据查询条件返回结果
+func (m Query) Run(data maps.Map) interface{} {
+	if m.Page != nil || m.PageSize != nil {
+		return m.Paginate(data)
+	} else if m.QueryDSL.First != nil {
+		return m.First(data)
+	}
+	return m.Get(data)
+}
+
+// Get 执行查询并返回数据记录集合
+func (m Query) Get(data maps.Map) []share.Record {
+	pipeline := m.mustPipeline(data)
+	if offset := m.intOf(m.Offset, data, 0); offset > 0 {
+		pipeline = append(pipeline, bson.M{"$skip": offset})
+	}
+	pipeline = append(pipeline, bson.M{"$limit": m.intOf(m.Limit, data, 100)})
+	pipeline = append(pipeline, m.project()...)
+	return m.aggregate(pipeline)
+}
+
+// Paginate 执行查询并返回带分页信息的数据记录数组
+func (m Query) Paginate(data maps.Map) share.Paginate {
+	page, pageSize := document.Page(m.Page, m.PageSize, data)
+	pipeline := m.mustPipeline(data)
+
+	total := 0
+	count := append(append([]interface{}{}, pipeline...), bson.M{"$count": "total"})
+	if rows := m.aggregate(count); len(rows) > 0 {
+		total = any.Of(rows[0]["total"]).CInt()
+	}
+
+	pipeline = append(pipeline, bson.M{"$skip": (page - 1) * pageSize}, bson.M{"$limit": pageSize})
+	pipeline = append(pipeline, m.project()...)
+	return document.Paginate(page, pageSize, total, m.aggregate(pipeline))
+}
+
+// First 执行查询并返回一条数据记录
+func (m Query) First(data maps.Map) share.Record {
+	m.Limit = 1
+	records := m.Get(data)
+	if len(records) > 0 {
+		return records[0]
+	}
+	return nil
+}
+
+// Collection 查询的数据集合名称
+func (m Query) Collection() string {
+	if m.From == nil {
+		return ""
+	}
+	return m.From.Name
+}
+
+// aggregate 执行聚合管道
+func (m Query) aggregate(pipeline []interface{}) []share.Record {
+
+	if m.Database == nil {
+		exception.New("未绑定数据连接", 500).Throw()
+	}
+
+	// Debug模式 打印查询信息
+	if m.Debug {
+		fmt.Println(m.Collection())
+		utils.Dump(pipeline)
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cursor, err := m.Database.Collection(m.Collection()).Aggregate(ctx, pipeline)
+	if err != nil {
+		exception.New("数据查询错误 %s", 500, err.Error()).Throw()
+	}
+	defer cursor.Close(ctx)
+
+	rows := []bson.M{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		exception.New("数据查询错误 %s", 500, err.Error()).Throw()
+	}
+
+	res := []share.Record{}
+	for _, row := range rows {
+		res = append(res, share.Record(value(row).(map[string]interface{})))
+	}
+	return res
+}
+
+// mustPipeline 生成聚合管道 (不含分页及字段映射)
+func (m Query) mustPipeline(data maps.Map) []interface{} {
+	pipeline, err := m.Pipeline(data)
+	if err != nil {
+		exception.New("查询条件错误 %s", 400, err.Error()).Throw()
+	}
+	return pipeline
+}
+
+// intOf 读取 limit/offset/page/pagesize 数值 (支持绑定参数)
+func (m Query) intOf(v interface{}, data maps.Map, defaults int) int {
+	return document.IntOf(v, data, defaults)
+}
+
+// value 转换 BSON 数据类型
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.M:
+		res := map[string]interface{}{}
+		for key, val := range v {
+			res[key] = value(val)
+		}
+		return res
+	case bson.D:
+		res := map[string]interface{}{}
+		for _, e := range v {
+			res[e.Key] = value(e.Value)
+		}
+		return res
+	case bson.A:
+		res := []interface{}{}
+		for _, val := range v {
+			res = append(res, value(val))
+		}
+		return res
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.DateTime:
+		return v.Time()
+	case primitive.Decimal128:
+		return v.String()
+	case primitive.Binary:
+		return v.Data
+	}
+	return v
+}
@@ -0,0 +1,170 @@
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/query/linter"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Queries 已加载的命名查询
+var Queries = map[string]*Named{}
+
+// Named 命名查询 (queries/*.query.yao), 声明类型化参数, 可被处理器及工作流节点按名称复用
+type Named struct {
+	linter.NamedQuery
+	ID   string    `json:"-"`
+	File string    `json:"-"`
+	DSL  share.DSL `json:"-"`
+}
+
+// Load 载入命名查询
+func Load(file string, id string) (*Named, error) {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSource(data, file, id)
+}
+
+// LoadSource 从源码载入命名查询
+func LoadSource(data []byte, file string, id string) (*Named, error) {
+
+	source := data
+	if filepath.Ext(file) != ".json" {
+		var input interface{}
+		err := application.Parse(file, data, &input)
+		if err != nil {
+			return nil, err
+		}
+
+		source, err = jsoniter.MarshalIndent(input, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dsl, result := linter.ParseNamed(string(source))
+	if !result.Valid {
+		return nil, fmt.Errorf("queries.%s %s\n%s", id, file, result.FormatDiagnostics())
+	}
+
+	named := &Named{NamedQuery: *dsl, ID: id, File: file}
+	if err := named.prepare(); err != nil {
+		return nil, err
+	}
+
+	// 处理器名称不区分大小写 (queries.<id>.Get), 使用小写名称注册
+	Queries[strings.ToLower(id)] = named
+	return named, nil
+}
+
+// SelectQuery 选择命名查询 (名称不区分大小写)
+func SelectQuery(id string) (*Named, error) {
+	named, has := Queries[strings.ToLower(id)]
+	if !has {
+		return nil, fmt.Errorf("queries.%s not loaded", id)
+	}
+	return named, nil
+}
+
+// UnloadQuery 卸载命名查询
+func UnloadQuery(id string) {
+	delete(Queries, strings.ToLower(id))
+}
+
+// prepare 使用查询引擎加载 Query DSL
+func (named *Named) prepare() error {
+	name := named.Engine
+	if name == "" {
+		name = "default"
+	}
+
+	engine, err := Select(name)
+	if err != nil {
+		return fmt.Errorf("queries.%s engine %s", named.ID, err.Error())
+	}
+
+	var input interface{}
+	err = jsoniter.Unmarshal(named.Query, &input)
+	if err != nil {
+		return fmt.Errorf("queries.%s %s", named.ID, err.Error())
+	}
+
+	named.DSL, err = engine.Load(input)
+	if err != nil {
+		return fmt.Errorf("queries.%s %s", named.ID, err.Error())
+	}
+	return nil
+}
+
+// Bind 校验参数类型并填充默认值, 返回查询绑定数据 (未声明的参数将被忽略)
+func (named *Named) Bind(params map[string]interface{}) (maps.Map, error) {
+	data := maps.Map{}
+	for name, param := range named.Params {
+		value, has := params[name]
+		if !has || value == nil {
+			value = param.Default
+		}
+
+		if value == nil {
+			if param.Required {
+				return nil, fmt.Errorf("queries.%s param %s is required", named.ID, name)
+			}
+			data[name] = nil
+			continue
+		}
+
+		value, err := param.Cast(value)
+		if err != nil {
+			return nil, fmt.Errorf("queries.%s param %s %s", named.ID, name, err.Error())
+		}
+		data[name] = value
+	}
+	return data.Dot(), nil
+}
+
+// Run 执行命名查询 (根据 Query DSL 返回记录集合、分页数据或单条记录)
+func (named *Named) Run(params map[string]interface{}) (interface{}, error) {
+	data, err := named.Bind(params)
+	if err != nil {
+		return nil, err
+	}
+	data[share.ProcessKey] = "queries." + named.ID
+	return named.DSL.Run(data), nil
+}
+
+// Get 执行命名查询并返回数据记录集合
+func (named *Named) Get(params map[string]interface{}) ([]share.Record, error) {
+	data, err := named.Bind(params)
+	if err != nil {
+		return nil, err
+	}
+	data[share.ProcessKey] = "queries." + named.ID
+	return named.DSL.Get(data), nil
+}
+
+// Paginate 执行命名查询并返回带分页信息的数据记录数组
+func (named *Named) Paginate(params map[string]interface{}) (share.Paginate, error) {
+	data, err := named.Bind(params)
+	if err != nil {
+		return share.Paginate{}, err
+	}
+	data[share.ProcessKey] = "queries." + named.ID
+	return named.DSL.Paginate(data), nil
+}
+
+// First 执行命名查询并返回一条数据记录
+func (named *Named) First(params map[string]interface{}) (share.Record, error) {
+	data, err := named.Bind(params)
+	if err != nil {
+		return nil, err
+	}
+	data[share.ProcessKey] = "queries." + named.ID
+	return named.DSL.First(data), nil
+}
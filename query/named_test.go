@@ -0,0 +1,154 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/maps"
+)
+
+// namedEngine 测试用查询引擎, 返回绑定数据
+type namedEngine struct{}
+
+func (engine *namedEngine) Load(data interface{}) (share.DSL, error) { return engine, nil }
+func (engine *namedEngine) Run(data maps.Map) interface{}            { return engine.First(data) }
+func (engine *namedEngine) Get(data maps.Map) []share.Record {
+	return []share.Record{engine.First(data)}
+}
+func (engine *namedEngine) Paginate(data maps.Map) share.Paginate {
+	return share.Paginate{Items: engine.Get(data), Total: 1}
+}
+func (engine *namedEngine) First(data maps.Map) share.Record {
+	return share.Record{"status": data["status"], "limit": data["limit"], "ids": data["ids"], "process": data[share.ProcessKey]}
+}
+
+const namedSource = `{
+  "name": "Active users",
+  "engine": "named-test",
+  "params": {
+    "status": { "type": "string", "default": "enabled", "enum": ["enabled", "disabled"] },
+    "limit": { "type": "integer", "default": 20 },
+    "ids": { "type": "array", "required": true }
+  },
+  "query": {
+    "select": ["id", "name"],
+    "from": "user",
+    "wheres": [{ "field": "status", "=": "?:status" }, { "field": "id", "in": "?:ids" }],
+    "limit": "?:limit"
+  }
+}`
+
+func TestNamedLoad(t *testing.T) {
+	Register("named-test", &namedEngine{})
+	defer Unregister("named-test")
+
+	named, err := LoadSource([]byte(namedSource), "queries/user/active.query.json", "user.active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnloadQuery("user.active")
+
+	assert.Equal(t, "Active users", named.Name)
+	assert.Len(t, named.Params, 3)
+	assert.NotNil(t, named.DSL)
+
+	_, err = LoadSource([]byte(`{"query": {"select": ["id"], "from": "user", "wheres": [{"field": "id", "=": "?:id"}]}}`), "queries/bad.query.json", "bad")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "E221")
+
+	_, err = LoadSource([]byte(`{"engine": "not-found", "query": {"select": ["id"], "from": "user"}}`), "queries/bad.query.json", "bad")
+	assert.NotNil(t, err)
+}
+
+func TestNamedBind(t *testing.T) {
+	Register("named-test", &namedEngine{})
+	defer Unregister("named-test")
+
+	named, err := LoadSource([]byte(namedSource), "queries/user/active.query.json", "user.active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnloadQuery("user.active")
+
+	row, err := named.First(map[string]interface{}{"ids": "1, 2", "limit": "5", "extra": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "enabled", row["status"])
+	assert.Equal(t, 5, row["limit"])
+	assert.Equal(t, []interface{}{"1", "2"}, row["ids"])
+	assert.Equal(t, "queries.user.active", row["process"])
+
+	_, err = named.Get(map[string]interface{}{})
+	assert.NotNil(t, err)
+
+	_, err = named.Get(map[string]interface{}{"ids": []interface{}{1}, "status": "deleted"})
+	assert.NotNil(t, err)
+
+	_, err = named.Get(map[string]interface{}{"ids": []interface{}{1}, "limit": 1.5})
+	assert.NotNil(t, err)
+}
+
+func TestNamedProcess(t *testing.T) {
+	Register("named-test", &namedEngine{})
+	defer Unregister("named-test")
+
+	_, err := LoadSource([]byte(namedSource), "queries/user/active.query.json", "user.active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnloadQuery("user.active")
+
+	rows, err := execNamed("queries.user.active.Get", map[string]interface{}{"ids": []interface{}{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, rows, 1)
+
+	res, err := execNamed("queries.user.active.Paginate", map[string]interface{}{"ids": []interface{}{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, res.(share.Paginate).Total)
+
+	row, err := execNamed("queries.user.active.First", map[string]interface{}{"ids": []interface{}{1}, "status": "disabled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "disabled", row.(share.Record)["status"])
+
+	_, err = execNamed("queries.user.active.First", map[string]interface{}{})
+	assert.NotNil(t, err)
+
+	_, err = execNamed("queries.not.found.Get")
+	assert.NotNil(t, err)
+
+	// the process names are lowercased, the queries are registered case-insensitively
+	_, err = LoadSource([]byte(namedSource), "queries/user/ActiveUsers.query.json", "user.ActiveUsers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnloadQuery("user.ActiveUsers")
+
+	rows, err = execNamed("queries.user.ActiveUsers.Get", map[string]interface{}{"ids": []interface{}{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, rows, 1)
+
+	named, err := SelectQuery("user.activeusers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "user.ActiveUsers", named.ID)
+}
+
+func execNamed(name string, args ...interface{}) (interface{}, error) {
+	p, err := process.Of(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Exec()
+}
@@ -0,0 +1,127 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+)
+
+func init() {
+	process.RegisterGroup("query", map[string]process.Handler{
+		"explain": ProcessExplain,
+	})
+
+	process.RegisterGroup("queries", map[string]process.Handler{
+		"get":      ProcessQueriesGet,
+		"paginate": ProcessQueriesPaginate,
+		"first":    ProcessQueriesFirst,
+	})
+}
+
+// ProcessExplain query.Explain
+// Returns the generated SQL, bindings and the database execution plan of a Query DSL
+//
+// Args:
+//   - engine string - The query engine name (e.g. "default")
+//   - dsl map - The Query DSL
+//   - data map (optional) - The binding data
+//
+// Returns: share.Explain
+//
+// Usage:
+//
+//	var plan = Process("query.Explain", "default", {"select": ["id"], "from": "user", "wheres": [{"field": "id", "value": "?:id"}]}, {"id": 1})
+//	// Returns: {"driver": "mysql", "sql": "SELECT ...", "bindings": [1], "plan": [...], "cost": 1.2}
+func ProcessExplain(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	name := process.ArgsString(0)
+	input := process.ArgsMap(1)
+	data := maps.Map{}
+	if process.NumOfArgs() > 2 {
+		data = process.ArgsMap(2).Dot()
+	}
+
+	res, err := Explain(name, input, data)
+	if err != nil {
+		exception.New("query.Explain: %s", 500, err.Error()).Throw()
+	}
+	return res
+}
+
+// Explain 返回 Query DSL 的查询语句、绑定参数及数据库执行计划
+func Explain(name string, input interface{}, data maps.Map) (*share.Explain, error) {
+	engine, err := Select(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dsl, err := engine.Load(input)
+	if err != nil {
+		return nil, err
+	}
+
+	explainer, ok := dsl.(share.Explainer)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support explain", name)
+	}
+	return explainer.Explain(data)
+}
+
+// ProcessQueriesGet queries.<name>.Get
+// Executes a named query and returns the records
+//
+// Args:
+//   - params map (optional) - The query parameters, validated against the declared params
+//
+// Usage:
+//
+//	var rows = Process("queries.user.active.Get", {"status": "enabled"})
+func ProcessQueriesGet(process *process.Process) interface{} {
+	named := selectNamed(process)
+	res, err := named.Get(process.ArgsMap(0, maps.MapStrAny{}))
+	if err != nil {
+		exception.New("%s", 400, err.Error()).Throw()
+	}
+	return res
+}
+
+// ProcessQueriesPaginate queries.<name>.Paginate
+// Executes a named query and returns the records with the pagination info
+//
+// Usage:
+//
+//	var res = Process("queries.user.active.Paginate", {"status": "enabled", "page": 2})
+func ProcessQueriesPaginate(process *process.Process) interface{} {
+	named := selectNamed(process)
+	res, err := named.Paginate(process.ArgsMap(0, maps.MapStrAny{}))
+	if err != nil {
+		exception.New("%s", 400, err.Error()).Throw()
+	}
+	return res
+}
+
+// ProcessQueriesFirst queries.<name>.First
+// Executes a named query and returns the first record
+//
+// Usage:
+//
+//	var row = Process("queries.user.active.First", {"status": "enabled"})
+func ProcessQueriesFirst(process *process.Process) interface{} {
+	named := selectNamed(process)
+	res, err := named.First(process.ArgsMap(0, maps.MapStrAny{}))
+	if err != nil {
+		exception.New("%s", 400, err.Error()).Throw()
+	}
+	return res
+}
+
+func selectNamed(process *process.Process) *Named {
+	named, err := SelectQuery(process.ID)
+	if err != nil {
+		exception.New("%s", 404, err.Error()).Throw()
+	}
+	return named
+}
@@ -26,3 +26,20 @@ type Paginate struct {
 	PageSize  int      `json:"pagesize"` // 每页记录数量
 	PageCount int      `json:"pagecnt"`  // 总页数
 }
+
+// ProcessKey 查询数据中的来源处理器名称 (用于慢查询日志)
+const ProcessKey = "$process"
+
+// Explainer 支持输出执行计划的 Query DSL
+type Explainer interface {
+	Explain(data maps.Map) (*Explain, error) // 返回查询语句、绑定参数及数据库执行计划
+}
+
+// Explain 查询执行计划
+type Explain struct {
+	Driver   string        `json:"driver"`         // 数据库驱动
+	SQL      string        `json:"sql"`            // 查询语句
+	Bindings []interface{} `json:"bindings"`       // 绑定参数
+	Plan     []Record      `json:"plan"`           // 数据库 EXPLAIN 输出
+	Cost     float64       `json:"cost,omitempty"` // 数据库估算的查询成本 (MySQL query_cost / Postgres Total Cost)
+}
@@ -1,29 +1,35 @@
 package query
 
 import (
-	"github.com/yaoapp/gou/query/share"
+	"io"
+
 	"github.com/yaoapp/gou/query/tai"
+	"github.com/yaoapp/kun/exception"
 )
 
-// Tai Query DSL
-type Tai tai.QueryDSL
+func init() {
+	Register("tai", tai.New(Select))
+}
 
-// Run 执行查询根据查询条件返回结果
-func (tai Tai) Run() interface{} {
-	return []share.Record{}
+// Tai 创建 Tai Query share.DSL (跨数据源查询)
+func Tai(input []byte) *tai.Query {
+	return prepareTai(tai.Make(input).With(Select))
 }
 
-// Get 执行查询并返回数据记录集合
-func (tai Tai) Get() []share.Record {
-	return []share.Record{}
+// TaiRead 创建 Tai Query share.DSL (输入接口)
+func TaiRead(reader io.Reader) *tai.Query {
+	return prepareTai(tai.Read(reader).With(Select))
 }
 
-// Paginate 执行查询并返回带分页信息的数据记录数组
-func (tai Tai) Paginate() share.Paginate {
-	return share.Paginate{}
+// TaiOpen 创建 Tai Query share.DSL (文件)
+func TaiOpen(filename string) *tai.Query {
+	return prepareTai(tai.Open(filename).With(Select))
 }
 
-// First 执行查询并返回一条数据记录
-func (tai Tai) First() share.Record {
-	return share.Record{}
+// prepareTai 加载数据源 Query DSL, 失败时抛出异常
+func prepareTai(query *tai.Query) *tai.Query {
+	if err := query.Prepare(); err != nil {
+		exception.New("%s", 400, err.Error()).Throw()
+	}
+	return query
 }
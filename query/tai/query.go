@@ -0,0 +1,525 @@
+package tai
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/kun/utils"
+)
+
+// RegAlias 别名正则表达式
+var RegAlias = regexp.MustCompile("[ ]+[Aa][Ss][ ]+")
+
+// Query Tai Query share.DSL
+type Query struct {
+	QueryDSL
+	Selector Selector
+}
+
+// New 创建 Tai Query share.DSL (用于注册查询引擎)
+func New(selector Selector) *Query {
+	return &Query{Selector: selector}
+}
+
+// Make 创建 Tai Query share.DSL
+func Make(input []byte) *Query {
+	var dsl QueryDSL
+	err := jsoniter.Unmarshal(input, &dsl)
+	if err != nil {
+		exception.New("DSL 解析失败 %s", 500, err.Error()).Throw()
+	}
+
+	errs := dsl.Validate()
+	if len(errs) > 0 {
+		exception.New("%s", 400, errs[0]).Ctx(errs).Throw()
+	}
+
+	return &Query{QueryDSL: dsl}
+}
+
+// Read 创建 Tai Query share.DSL (输入接口)
+func Read(reader io.Reader) *Query {
+	buf := bytes.NewBuffer(nil)
+	_, err := io.Copy(buf, reader)
+	if err != nil {
+		exception.New("读取数据失败 %s", 500, err.Error()).Throw()
+	}
+	return Make(buf.Bytes())
+}
+
+// Open 创建 Tai Query share.DSL (文件)
+func Open(filename string) *Query {
+	file, err := os.Open(filename)
+	if err != nil {
+		exception.New("读取文件失败 %s", 500, err.Error()).Throw()
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	return Read(reader)
+}
+
+// With 绑定数据源查询引擎选择器
+func (tai *Query) With(selector Selector) *Query {
+	tai.Selector = selector
+	return tai
+}
+
+// Prepare 加载数据源 Query DSL
+func (tai *Query) Prepare() error {
+	for name, src := range tai.Sources {
+		if src.Engine == "" {
+			continue
+		}
+
+		if tai.Selector == nil {
+			return errors.Errorf("sources.%s: 未绑定查询引擎选择器", name)
+		}
+
+		engine, err := tai.Selector(src.Engine)
+		if err != nil {
+			return errors.Errorf("sources.%s: 查询引擎 %s", name, err.Error())
+		}
+
+		src.DSL, err = engine.Load(src.Query)
+		if err != nil {
+			return errors.Errorf("sources.%s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// ==================================================
+// share.DSL Interface
+// ==================================================
+
+// Load 加载查询条件
+func (tai *Query) Load(data interface{}) (share.DSL, error) {
+
+	input, err := jsoniter.Marshal(data)
+	if err != nil {
+		return nil, errors.Errorf("加载失败 %s", err.Error())
+	}
+
+	var dsl QueryDSL
+	err = jsoniter.Unmarshal(input, &dsl)
+	if err != nil {
+		return nil, errors.Errorf("加载失败 %s", err.Error())
+	}
+
+	errs := dsl.Validate()
+	if len(errs) > 0 {
+		return nil, errors.Errorf("查询条件错误 %#v", errs)
+	}
+
+	query := &Query{QueryDSL: dsl, Selector: tai.Selector}
+	err = query.Prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// Run 执行查询根据查询条件返回结果
+func (tai Query) Run(data maps.Map) interface{} {
+	if tai.Page != nil || tai.PageSize != nil {
+		return tai.Paginate(data)
+	} else if tai.QueryDSL.First != nil {
+		return tai.First(data)
+	}
+	return tai.Get(data)
+}
+
+// Get 执行查询并返回数据记录集合
+func (tai Query) Get(data maps.Map) []share.Record {
+	rows := tai.records(data)
+
+	offset := tai.intValue(tai.Offset, data, 0)
+	if offset > 0 {
+		if offset >= len(rows) {
+			return []share.Record{}
+		}
+		rows = rows[offset:]
+	}
+
+	limit := tai.intValue(tai.Limit, data, -1)
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	return tai.format(rows)
+}
+
+// Paginate 执行查询并返回带分页信息的数据记录数组
+func (tai Query) Paginate(data maps.Map) share.Paginate {
+	res := share.Paginate{}
+	page := tai.intValue(tai.Page, data, 1)
+	pageSize := tai.intValue(tai.PageSize, data, 20)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	rows := tai.records(data)
+	res.Page = page
+	res.PageSize = pageSize
+	res.Prev = page - 1
+	res.Next = page + 1
+	res.Total = len(rows)
+	res.PageCount = int(math.Ceil(float64(res.Total) / float64(pageSize)))
+
+	if res.Prev == 0 {
+		res.Prev = -1
+	}
+
+	if res.Next > res.PageCount {
+		res.Next = -1
+	}
+
+	res.Items = []share.Record{}
+	offset := (page - 1) * pageSize
+	if offset < len(rows) {
+		end := offset + pageSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		res.Items = tai.format(rows[offset:end])
+	}
+
+	return res
+}
+
+// First 执行查询并返回一条数据记录
+func (tai Query) First(data maps.Map) share.Record {
+	tai.Limit = 1
+	records := tai.Get(data)
+	if len(records) > 0 {
+		return records[0]
+	}
+	return nil
+}
+
+// records 读取数据源数据, 关联并排序
+func (tai Query) records(data maps.Map) []share.Record {
+	if data == nil {
+		data = maps.Map{}
+	}
+
+	rows, err := tai.fetch(tai.From, data)
+	if err != nil {
+		exception.New("数据源 %s 查询错误 %s", 500, tai.From, err.Error()).Throw()
+	}
+
+	for _, join := range tai.Joins {
+		joined, err := tai.fetch(join.Source, data)
+		if err != nil {
+			exception.New("数据源 %s 查询错误 %s", 500, join.Source, err.Error()).Throw()
+		}
+		rows = join.apply(rows, joined)
+	}
+
+	if len(tai.Orders) > 0 {
+		tai.sort(rows)
+	}
+
+	// Debug模式 打印查询信息
+	if tai.Debug {
+		fmt.Printf("Tai: from %s, %d joins, %d records\n", tai.From, len(tai.Joins), len(rows))
+	}
+
+	return rows
+}
+
+// fetch 读取数据源数据
+func (tai Query) fetch(name string, data maps.Map) ([]share.Record, error) {
+	src, has := tai.Sources[name]
+	if !has || src == nil {
+		return nil, errors.Errorf("数据源不存在")
+	}
+
+	if src.Engine != "" {
+		if src.DSL == nil {
+			return nil, errors.Errorf("查询条件尚未加载")
+		}
+		return src.DSL.Get(data), nil
+	}
+
+	args := []interface{}{}
+	for _, arg := range src.Args {
+		args = append(args, helper.Bind(arg, data))
+	}
+
+	// Debug模式 打印查询信息
+	if tai.Debug {
+		fmt.Println(src.Process)
+		utils.Dump(args)
+	}
+
+	p, err := process.Of(src.Process, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Release()
+
+	err = p.Execute()
+	if err != nil {
+		return nil, err
+	}
+	return Records(p.Value())
+}
+
+// apply 将关联数据源数据合并至主数据源记录
+func (join Join) apply(rows []share.Record, joined []share.Record) []share.Record {
+
+	index := map[string][]share.Record{}
+	for _, row := range joined {
+		value := Get(row, join.Key)
+		if value == nil {
+			continue
+		}
+		key := fmt.Sprintf("%v", value)
+		index[key] = append(index[key], row)
+	}
+
+	res := []share.Record{}
+	for _, row := range rows {
+		var matches []share.Record
+		if value := Get(row, join.Foreign); value != nil {
+			matches = index[fmt.Sprintf("%v", value)]
+		}
+
+		if len(matches) == 0 && !join.Left {
+			continue
+		}
+
+		record := share.Record{}
+		for k, v := range row {
+			record[k] = v
+		}
+
+		if join.As != "" {
+			if join.Many {
+				items := []share.Record{}
+				items = append(items, matches...)
+				record[join.As] = items
+			} else if len(matches) > 0 {
+				record[join.As] = matches[0]
+			} else {
+				record[join.As] = nil
+			}
+			res = append(res, record)
+			continue
+		}
+
+		// 未指定关联结果字段名称, 合并第一条关联记录 (不覆盖主记录字段)
+		if len(matches) > 0 {
+			for k, v := range matches[0] {
+				if _, has := record[k]; !has {
+					record[k] = v
+				}
+			}
+		}
+		res = append(res, record)
+	}
+
+	return res
+}
+
+// sort 按排序条件排序
+func (tai Query) sort(rows []share.Record) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, order := range tai.Orders {
+			cmp := compare(Get(rows[i], order.Field), Get(rows[j], order.Field))
+			if cmp == 0 {
+				continue
+			}
+			if strings.ToLower(order.Sort) == "desc" {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// format 按查询字段列表格式化输出
+func (tai Query) format(rows []share.Record) []share.Record {
+	if len(tai.Select) == 0 {
+		return rows
+	}
+
+	res := []share.Record{}
+	for _, row := range rows {
+		record := share.Record{}
+		for _, field := range tai.Select {
+			name := strings.TrimSpace(field)
+			alias := name
+			if parts := RegAlias.Split(name, 2); len(parts) == 2 {
+				name = strings.TrimSpace(parts[0])
+				alias = strings.TrimSpace(parts[1])
+			}
+			record[alias] = Get(row, name)
+		}
+		res = append(res, record)
+	}
+	return res
+}
+
+// intValue 读取数值 (支持绑定参数)
+func (tai Query) intValue(value interface{}, data maps.Map, defaults int) int {
+	if value == nil {
+		return defaults
+	}
+	switch value.(type) {
+	case float64, float32, int, int64, int32:
+		return any.Of(value).CInt()
+	case string:
+		return any.Of(helper.Bind(value, data)).CInt()
+	}
+	return defaults
+}
+
+// Get 读取记录字段数值, 支持 object.field 访问嵌套字段
+func Get(row share.Record, field string) interface{} {
+	if value, has := row[field]; has {
+		return value
+	}
+
+	var value interface{} = map[string]interface{}(row)
+	for _, key := range strings.Split(field, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case share.Record:
+			value = v[key]
+		case maps.MapStrAny:
+			value = v[key]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// Records 将处理器返回值转换为数据记录集合
+func Records(value interface{}) ([]share.Record, error) {
+	switch v := value.(type) {
+	case nil:
+		return []share.Record{}, nil
+	case []share.Record:
+		return v, nil
+	case share.Paginate:
+		return v.Items, nil
+	case []map[string]interface{}:
+		res := []share.Record{}
+		for _, row := range v {
+			res = append(res, share.Record(row))
+		}
+		return res, nil
+	case []maps.MapStrAny:
+		res := []share.Record{}
+		for _, row := range v {
+			res = append(res, share.Record(row))
+		}
+		return res, nil
+	case map[string]interface{}:
+		return mapRecords(v)
+	case maps.MapStrAny:
+		return mapRecords(v)
+	case share.Record:
+		return mapRecords(v)
+	}
+
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []share.Record{}
+	err = jsoniter.Unmarshal(bytes, &res)
+	if err != nil {
+		return nil, errors.Errorf("返回值不是数据记录集合 %s", err.Error())
+	}
+	return res, nil
+}
+
+// mapRecords 分页数据 ( data | items ) 或单条记录
+func mapRecords(value map[string]interface{}) ([]share.Record, error) {
+	for _, key := range []string{"data", "items"} {
+		if items, has := value[key]; has {
+			return Records(items)
+		}
+	}
+	return []share.Record{share.Record(value)}, nil
+}
+
+// compare 比较字段数值 (数字按数值, 其他按字符串)
+func compare(a, b interface{}) int {
+	if a == nil && b == nil {
+		return 0
+	} else if a == nil {
+		return -1
+	} else if b == nil {
+		return 1
+	}
+
+	fa, aok := number(a)
+	fb, bok := number(b)
+	if aok && bok {
+		if fa < fb {
+			return -1
+		} else if fa > fb {
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// number 转换为数字
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,217 @@
+package tai
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/maps"
+)
+
+// mockEngine 测试用查询引擎, 返回 query.rows 中的数据
+type mockEngine struct {
+	rows []share.Record
+}
+
+func (mock *mockEngine) Load(data interface{}) (share.DSL, error) {
+	input, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid query")
+	}
+	rows, err := Records(input["rows"])
+	if err != nil {
+		return nil, err
+	}
+	return &mockEngine{rows: rows}, nil
+}
+func (mock *mockEngine) Run(data maps.Map) interface{}    { return mock.rows }
+func (mock *mockEngine) Get(data maps.Map) []share.Record { return mock.rows }
+func (mock *mockEngine) Paginate(data maps.Map) share.Paginate {
+	return share.Paginate{Items: mock.rows}
+}
+func (mock *mockEngine) First(data maps.Map) share.Record { return mock.rows[0] }
+
+func selector(name string) (share.DSL, error) {
+	if name == "mock" {
+		return &mockEngine{}, nil
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+func prepare(t *testing.T) *Query {
+	process.Register("tai.test.pets", func(p *process.Process) interface{} {
+		owner := p.ArgsInt(0, 0)
+		pets := []map[string]interface{}{
+			{"id": 1, "owner": 1, "name": "Cookie"},
+			{"id": 2, "owner": 1, "name": "Lucky"},
+			{"id": 3, "owner": 2, "name": "Max"},
+		}
+		if owner == 0 {
+			return pets
+		}
+		res := []map[string]interface{}{}
+		for _, pet := range pets {
+			if pet["owner"] == owner {
+				res = append(res, pet)
+			}
+		}
+		return res
+	})
+
+	process.Register("tai.test.profiles", func(p *process.Process) interface{} {
+		return map[string]interface{}{
+			"data": []interface{}{
+				map[string]interface{}{"user_id": 1.0, "city": "Beijing"},
+				map[string]interface{}{"user_id": 3.0, "city": "Shanghai"},
+			},
+		}
+	})
+
+	return New(selector)
+}
+
+func dsl(extra map[string]interface{}) map[string]interface{} {
+	res := map[string]interface{}{
+		"sources": map[string]interface{}{
+			"users": map[string]interface{}{
+				"engine": "mock",
+				"query": map[string]interface{}{
+					"rows": []interface{}{
+						map[string]interface{}{"id": 1, "name": "Alice", "meta": map[string]interface{}{"age": 30}},
+						map[string]interface{}{"id": 2, "name": "Bob", "meta": map[string]interface{}{"age": 25}},
+						map[string]interface{}{"id": 3, "name": "Charlie", "meta": map[string]interface{}{"age": 35}},
+					},
+				},
+			},
+			"pets":     map[string]interface{}{"process": "tai.test.pets", "args": []interface{}{"?:$in.owner"}},
+			"profiles": map[string]interface{}{"process": "tai.test.profiles"},
+		},
+		"from": "users",
+	}
+	for key, value := range extra {
+		res[key] = value
+	}
+	return res
+}
+
+func TestLoad(t *testing.T) {
+	engine := prepare(t)
+	q, err := engine.Load(dsl(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, q.(*Query).Sources["users"].DSL)
+	assert.Nil(t, q.(*Query).Sources["pets"].DSL)
+
+	_, err = engine.Load(map[string]interface{}{"from": "users"})
+	assert.NotNil(t, err)
+
+	_, err = engine.Load(dsl(map[string]interface{}{"from": "not-found"}))
+	assert.NotNil(t, err)
+
+	_, err = engine.Load(dsl(map[string]interface{}{
+		"joins": []interface{}{map[string]interface{}{"source": "pets", "key": "owner"}},
+	}))
+	assert.NotNil(t, err)
+
+	_, err = New(nil).Load(dsl(nil))
+	assert.NotNil(t, err)
+}
+
+func TestGetJoinMany(t *testing.T) {
+	engine := prepare(t)
+	q, err := engine.Load(dsl(map[string]interface{}{
+		"select": []interface{}{"id", "name as user", "meta.age as age", "pets"},
+		"joins": []interface{}{
+			map[string]interface{}{"source": "pets", "key": "owner", "foreign": "id", "as": "pets", "many": true, "left": true},
+		},
+		"orders": []interface{}{map[string]interface{}{"field": "meta.age", "sort": "desc"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := q.Get(maps.Map{})
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "Charlie", rows[0]["user"])
+	assert.Equal(t, 35.0, rows[0]["age"])
+	assert.Len(t, rows[0]["pets"], 0)
+	assert.Equal(t, "Alice", rows[1]["user"])
+	assert.Len(t, rows[1]["pets"], 2)
+	assert.Equal(t, "Bob", rows[2]["user"])
+	assert.Len(t, rows[2]["pets"], 1)
+	assert.Nil(t, rows[0]["name"])
+}
+
+func TestGetJoinMerge(t *testing.T) {
+	engine := prepare(t)
+	q, err := engine.Load(dsl(map[string]interface{}{
+		"joins": []interface{}{
+			map[string]interface{}{"source": "profiles", "key": "user_id", "foreign": "id"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := q.Get(nil)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "Alice", rows[0]["name"])
+	assert.Equal(t, "Beijing", rows[0]["city"])
+	assert.Equal(t, "Charlie", rows[1]["name"])
+	assert.Equal(t, "Shanghai", rows[1]["city"])
+}
+
+func TestGetBindings(t *testing.T) {
+	engine := prepare(t)
+	q, err := engine.Load(dsl(map[string]interface{}{
+		"from": "pets",
+		"joins": []interface{}{
+			map[string]interface{}{"source": "users", "key": "id", "foreign": "owner", "as": "owner"},
+		},
+		"select": []interface{}{"name", "owner.name as owner"},
+		"limit":  "?:$in.limit",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := maps.Map{"$in.owner": 1, "$in.limit": 1}
+	rows := q.Get(data)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "Cookie", rows[0]["name"])
+	assert.Equal(t, "Alice", rows[0]["owner"])
+}
+
+func TestPaginateAndFirst(t *testing.T) {
+	engine := prepare(t)
+	q, err := engine.Load(dsl(map[string]interface{}{
+		"page":     2,
+		"pagesize": 2,
+		"orders":   []interface{}{map[string]interface{}{"field": "id"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := q.Run(nil).(share.Paginate)
+	assert.True(t, ok)
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, 2, res.Page)
+	assert.Equal(t, 2, res.PageCount)
+	assert.Equal(t, 1, res.Prev)
+	assert.Equal(t, -1, res.Next)
+	assert.Len(t, res.Items, 1)
+	assert.Equal(t, "Charlie", res.Items[0]["name"])
+
+	row := q.First(nil)
+	assert.Equal(t, "Alice", row["name"])
+}
+
+func TestMake(t *testing.T) {
+	assert.Panics(t, func() { Make([]byte(`{"from":"users"}`)) })
+	q := Make([]byte(`{"sources":{"users":{"process":"tai.test.pets"}},"from":"users"}`))
+	assert.Equal(t, "users", q.From)
+}
@@ -1,4 +1,53 @@
 package tai
 
+import (
+	"github.com/yaoapp/gou/query/share"
+)
+
 // QueryDSL Tai Query Domain Specific Language
-type QueryDSL struct{}
+// Tai 跨数据源查询: 分别从多个数据源(查询引擎或处理器)读取数据, 在内存中关联、排序与分页
+type QueryDSL struct {
+	Sources  map[string]*Source `json:"sources"`            // 数据源列表
+	From     string             `json:"from"`               // 主数据源名称
+	Joins    []Join             `json:"joins,omitempty"`    // 数据源关联
+	Select   []string           `json:"select,omitempty"`   // 查询字段列表 ( field, field as alias, object.field )
+	Orders   []Order            `json:"orders,omitempty"`   // 排序条件
+	First    interface{}        `json:"first,omitempty"`    // 限定读取单条数据
+	Limit    interface{}        `json:"limit,omitempty"`    // 限定读取记录的数量
+	Offset   interface{}        `json:"offset,omitempty"`   // 记录开始位置
+	Page     interface{}        `json:"page,omitempty"`     // 分页查询当前页面页码
+	PageSize interface{}        `json:"pagesize,omitempty"` // 每页读取记录的数量
+	Comment  string             `json:"comment,omitempty"`  // 查询条件注释
+	Debug    bool               `json:"debug,omitempty"`    // 是否开启调试(开启后计入查询日志)
+}
+
+// Source 数据源 (查询引擎 + Query DSL 或 处理器 + 参数表)
+type Source struct {
+	Engine  string        `json:"engine,omitempty"`  // 查询引擎名称
+	Query   interface{}   `json:"query,omitempty"`   // 查询引擎 Query DSL
+	Process string        `json:"process,omitempty"` // 处理器名称
+	Args    []interface{} `json:"args,omitempty"`    // 处理器参数表 (支持 ?:$in.0 / {{$in.0}} 绑定)
+	Comment string        `json:"comment,omitempty"` // 数据源注释
+	DSL     share.DSL     `json:"-"`                 // 已加载的 Query DSL
+}
+
+// Join 数据源关联
+type Join struct {
+	Source  string `json:"source"`            // 关联数据源名称
+	Key     string `json:"key"`               // 关联数据源字段名称
+	Foreign string `json:"foreign"`           // 主数据源字段名称
+	As      string `json:"as,omitempty"`      // 关联结果字段名称, 为空时将关联记录字段合并至主记录
+	Many    bool   `json:"many,omitempty"`    // true 关联结果为记录数组, 默认为 false 关联结果为单条记录
+	Left    bool   `json:"left,omitempty"`    // true 连接方式为 LEFT JOIN, 默认为 false 连接方式为 JOIN
+	Comment string `json:"comment,omitempty"` // 关联条件注释
+}
+
+// Order 排序条件
+type Order struct {
+	Field   string `json:"field"`             // 排序字段
+	Sort    string `json:"sort,omitempty"`    // 排序方式 asc | desc
+	Comment string `json:"comment,omitempty"` // 排序条件注释
+}
+
+// Selector 选择数据源 Query Engine
+type Selector func(name string) (share.DSL, error)
@@ -0,0 +1,101 @@
+package tai
+
+import (
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Validate 校验DSL格式
+func (tai QueryDSL) Validate() []error {
+	errs := []error{}
+	errs = append(errs, tai.ValidateSources()...) // sources
+	errs = append(errs, tai.ValidateFrom()...)    // from
+	errs = append(errs, tai.ValidateJoins()...)   // joins
+	errs = append(errs, tai.ValidateOrders()...)  // orders
+	return errs
+}
+
+// ValidateSources 校验数据源
+func (tai QueryDSL) ValidateSources() []error {
+	errs := []error{}
+	if len(tai.Sources) == 0 {
+		errs = append(errs, errors.Errorf("sources: 未指定数据源"))
+		return errs
+	}
+
+	for name, src := range tai.Sources {
+		if src == nil {
+			errs = append(errs, errors.Errorf("sources.%s: 数据源格式错误", name))
+			continue
+		}
+
+		if src.Process == "" && src.Engine == "" {
+			errs = append(errs, errors.Errorf("sources.%s: 未指定查询引擎(engine)或处理器(process)", name))
+			continue
+		}
+
+		if src.Process != "" && src.Engine != "" {
+			errs = append(errs, errors.Errorf("sources.%s: 查询引擎(engine)与处理器(process)不能同时指定", name))
+			continue
+		}
+
+		if src.Engine != "" && src.Query == nil {
+			errs = append(errs, errors.Errorf("sources.%s: 未指定查询条件(query)", name))
+		}
+	}
+	return errs
+}
+
+// ValidateFrom 校验主数据源
+func (tai QueryDSL) ValidateFrom() []error {
+	errs := []error{}
+	if tai.From == "" {
+		errs = append(errs, errors.Errorf("from: 未指定主数据源"))
+		return errs
+	}
+
+	if _, has := tai.Sources[tai.From]; !has {
+		errs = append(errs, errors.Errorf("from: 数据源 %s 不存在", tai.From))
+	}
+	return errs
+}
+
+// ValidateJoins 校验数据源关联
+func (tai QueryDSL) ValidateJoins() []error {
+	errs := []error{}
+	for i, join := range tai.Joins {
+		if join.Source == "" {
+			errs = append(errs, errors.Errorf("joins[%d].source: 未指定关联数据源", i))
+		} else if _, has := tai.Sources[join.Source]; !has {
+			errs = append(errs, errors.Errorf("joins[%d].source: 数据源 %s 不存在", i, join.Source))
+		} else if join.Source == tai.From {
+			errs = append(errs, errors.Errorf("joins[%d].source: 不能关联主数据源 %s", i, join.Source))
+		}
+
+		if join.Key == "" {
+			errs = append(errs, errors.Errorf("joins[%d].key: 未指定关联数据源字段", i))
+		}
+
+		if join.Foreign == "" {
+			errs = append(errs, errors.Errorf("joins[%d].foreign: 未指定主数据源字段", i))
+		}
+	}
+	return errs
+}
+
+// ValidateOrders 校验排序条件
+func (tai QueryDSL) ValidateOrders() []error {
+	errs := []error{}
+	for i, order := range tai.Orders {
+		if order.Field == "" {
+			errs = append(errs, errors.Errorf("orders[%d].field: 未指定排序字段", i))
+		}
+
+		sort := strings.ToLower(order.Sort)
+		if sort != "" && sort != "asc" && sort != "desc" {
+			errs = append(errs, errors.Errorf("orders[%d].sort: 排序方式 %s 不支持 (asc|desc)", i, order.Sort))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,51 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/query/share"
+	"github.com/yaoapp/kun/maps"
+)
+
+// taiEngine 测试用查询引擎, 返回固定数据
+type taiEngine struct{ loaded bool }
+
+func (engine *taiEngine) Load(data interface{}) (share.DSL, error) {
+	return &taiEngine{loaded: true}, nil
+}
+func (engine *taiEngine) Run(data maps.Map) interface{} { return engine.Get(data) }
+func (engine *taiEngine) Get(data maps.Map) []share.Record {
+	if !engine.loaded {
+		panic("the query is not loaded")
+	}
+	return []share.Record{{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}}
+}
+func (engine *taiEngine) Paginate(data maps.Map) share.Paginate {
+	return share.Paginate{Items: engine.Get(data), Total: 2}
+}
+func (engine *taiEngine) First(data maps.Map) share.Record { return engine.Get(data)[0] }
+
+const taiSource = `{
+  "sources": { "users": { "engine": "tai-test", "query": { "from": "user" } } },
+  "from": "users",
+  "orders": [{ "field": "id", "sort": "desc" }]
+}`
+
+func TestTai(t *testing.T) {
+	Register("tai-test", &taiEngine{})
+	defer Unregister("tai-test")
+
+	rows := Tai([]byte(taiSource)).Get(nil)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "Bob", rows[0]["name"])
+	}
+
+	rows = TaiRead(strings.NewReader(taiSource)).Get(nil)
+	assert.Len(t, rows, 2)
+
+	assert.Panics(t, func() {
+		Tai([]byte(`{"sources": {"users": {"engine": "not-exists", "query": {}}}, "from": "users"}`))
+	})
+}
@@ -2,16 +2,19 @@ package job
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
+	"github.com/yaoapp/gou/task"
 	"rogchap.com/v8go"
 )
 
 var jobs = sync.Map{}
+var uniques = sync.Map{} // unique key => the id of the waiting job
 
 // Object Javascript API
 type Object struct{}
@@ -26,6 +29,8 @@ type Job struct {
 	cancel  context.CancelFunc
 	err     error
 	created int64
+	unique  string
+	mu      sync.Mutex
 }
 
 const (
@@ -53,6 +58,8 @@ func (obj *Object) ExportObject(iso *v8go.Isolate) *v8go.ObjectTemplate {
 
 // ExportFunction Export as a javascript FS function
 // var job = new Job(processName, args...);
+// var job = new Job({ process: "scripts.mail.Send", delay: 1000, unique: "mail" }, args...);
+// var job = new Job({ task: "mail", priority: 10, at: "2023-01-02T15:04:05Z" }, args...);
 func (obj *Object) ExportFunction(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	object := obj.ExportObject(iso)
 	tmpl := v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
@@ -61,13 +68,13 @@ func (obj *Object) ExportFunction(iso *v8go.Isolate) *v8go.FunctionTemplate {
 			return bridge.JsException(info.Context(), "missing parameters")
 		}
 
-		if !jsArgs[0].IsString() {
-			return bridge.JsException(info.Context(), "the first parameter should be a string")
+		exec, taskName, option, err := jobOption(info.Context(), jsArgs[0])
+		if err != nil {
+			return bridge.JsException(info.Context(), err)
 		}
 
-		var err error
 		goArgs := []interface{}{}
-		if len(jsArgs) > 2 {
+		if len(jsArgs) > 1 {
 			goArgs, err = bridge.GoValues(jsArgs[1:], info.Context())
 			if err != nil {
 				return bridge.JsException(info.Context(), err)
@@ -79,31 +86,74 @@ func (obj *Object) ExportFunction(iso *v8go.Isolate) *v8go.FunctionTemplate {
 			return bridge.JsException(info.Context(), err.Error())
 		}
 
+		// Add the job to the task queue
+		if taskName != "" {
+			id, err := addTask(taskName, option, goArgs)
+			if err != nil {
+				return bridge.JsException(info.Context(), err)
+			}
+			this.Set("id", id)
+			return this.Value
+		}
+
+		// The duplicate job while the job with the same unique key is waiting
+		if option.Unique != "" {
+			if id, ok := duplicate(option, goArgs); ok {
+				this.Set("id", id)
+				return this.Value
+			}
+		}
+
+		share, err := bridge.ShareData(info.Context())
+		if err != nil {
+			return bridge.JsException(info.Context(), err)
+		}
+
 		id := uuid.New().String()
-		exec := jsArgs[0].String()
 		ctx, cancel := context.WithCancel(context.Background())
-
-		jobs.Store(id, &Job{
+		job := &Job{
 			id:      id,
 			status:  StatusRunning,
 			process: exec,
 			args:    goArgs,
 			created: time.Now().UnixNano(),
 			cancel:  cancel,
+			unique:  option.Unique,
 			data:    nil,
 			err:     nil,
-		})
+		}
+		jobs.Store(id, job)
+		if job.unique != "" {
+			uniques.Store(job.unique, id)
+		}
 
-		share, err := bridge.ShareData(info.Context())
-		if err != nil {
-			return bridge.JsException(info.Context(), err)
+		runAt := option.At
+		if option.Delay > 0 {
+			runAt = time.Now().Add(option.Delay)
 		}
 
 		go func() {
+			if wait := time.Until(runAt); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+
+			if job.unique != "" {
+				uniques.CompareAndDelete(job.unique, id)
+			}
+
 			select {
 			case <-ctx.Done():
 				return
 			default:
+				job.mu.Lock()
+				goArgs := job.args
+				job.mu.Unlock()
 				goRes, err := process.New(exec, goArgs...).
 					WithGlobal(share.Global).
 					WithSID(share.Sid).
@@ -126,6 +176,125 @@ func (obj *Object) ExportFunction(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	return tmpl
 }
 
+// jobOption parse the first parameter, a process name or an option object
+// { process, task, priority, at, delay, unique, duplicate }
+func jobOption(ctx *v8go.Context, value *v8go.Value) (string, string, task.AddOption, error) {
+	if value.IsString() {
+		return value.String(), "", task.AddOption{}, nil
+	}
+
+	if !value.IsObject() {
+		return "", "", task.AddOption{}, fmt.Errorf("the first parameter should be a string or an object")
+	}
+
+	goValue, err := bridge.GoValue(value, ctx)
+	if err != nil {
+		return "", "", task.AddOption{}, err
+	}
+
+	values, ok := goValue.(map[string]interface{})
+	if !ok {
+		return "", "", task.AddOption{}, fmt.Errorf("the first parameter should be a string or an object")
+	}
+
+	option, err := task.ParseAddOption(values)
+	if err != nil {
+		return "", "", option, err
+	}
+
+	exec, _ := values["process"].(string)
+	taskName, _ := values["task"].(string)
+	if exec == "" && taskName == "" {
+		return "", "", option, fmt.Errorf("the process or the task is required")
+	}
+
+	if option.Priority != 0 && taskName == "" {
+		return "", "", option, fmt.Errorf("the priority option requires the task")
+	}
+	return exec, taskName, option, nil
+}
+
+// duplicate drop or merge the job with the same unique key which is waiting to run
+func duplicate(option task.AddOption, args []interface{}) (string, bool) {
+	id, ok := uniques.Load(option.Unique)
+	if !ok {
+		return "", false
+	}
+
+	v, ok := jobs.Load(id)
+	if !ok {
+		return "", false
+	}
+
+	job, ok := v.(*Job)
+	if !ok {
+		return "", false
+	}
+
+	if option.Duplicate == "merge" {
+		job.mu.Lock()
+		job.args = args
+		job.mu.Unlock()
+	}
+	return id.(string), true
+}
+
+// addTask add the job to the task queue and wait for the result
+func addTask(name string, option task.AddOption, args []interface{}) (string, error) {
+	t, err := task.Get(name)
+	if err != nil {
+		return "", err
+	}
+
+	taskID, err := t.AddWith(option, args...)
+	if err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	ctx, stop := context.WithCancel(context.Background())
+	cancel := func() {
+		stop()
+		t.Cancel(taskID)
+	}
+	jobs.Store(id, &Job{
+		id:      id,
+		status:  StatusRunning,
+		process: fmt.Sprintf("tasks.%s", name),
+		args:    args,
+		created: time.Now().UnixNano(),
+		cancel:  cancel,
+	})
+
+	go func() {
+		res, err := t.Wait(ctx, taskID)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var data interface{}
+		if err == nil {
+			data = res["response"]
+			if res["status"] == "FAILURE" {
+				err = fmt.Errorf("%v", data)
+				data = nil
+			}
+		}
+
+		jobs.Store(id, &Job{
+			id:      id,
+			status:  StatusDone,
+			process: fmt.Sprintf("tasks.%s", name),
+			args:    args,
+			created: time.Now().UnixNano(),
+			data:    data,
+			err:     err,
+		})
+	}()
+
+	return id, nil
+}
+
 func (obj *Object) pending(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
 		args := info.Args()
@@ -233,7 +402,9 @@ func (obj *Object) cancel(iso *v8go.Isolate) *v8go.FunctionTemplate {
 			return v8go.Undefined(info.Context().Isolate())
 		}
 
-		job.cancel()
+		if job.cancel != nil {
+			job.cancel()
+		}
 		defer jobs.Delete(id.String())
 		return v8go.Undefined(info.Context().Isolate())
 	})
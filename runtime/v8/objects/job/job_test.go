@@ -1,12 +1,14 @@
 package job
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
+	"github.com/yaoapp/gou/task"
 	"rogchap.com/v8go"
 )
 
@@ -47,6 +49,77 @@ func TestJob(t *testing.T) {
 	assert.Greater(t, res["progress"], float64(0))
 }
 
+func TestJobOption(t *testing.T) {
+
+	ctx := prepare(t, false, "", nil)
+	defer close(ctx)
+
+	jsRes, err := ctx.RunScript(`
+		const test = () => {
+			let job = new Job({ process: "test.job.run", delay: 100, unique: "test" }, "http://test.com", "foo=bar");
+			let dropped = new Job({ process: "test.job.run", unique: "test" }, "http://dropped.com");
+			let merged = new Job({ process: "test.job.run", unique: "test", duplicate: "merge" }, "http://merged.com", "foo=merged");
+			job.Pending(() => {});
+			let data = job.Data()
+			return {same: job.id == dropped.id && job.id == merged.id, ...data};
+		}
+		test()
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goRes, err := bridge.GoValue(jsRes, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := goRes.(map[string]interface{})
+	if !ok {
+		t.Fatal("result error")
+	}
+
+	assert.Equal(t, true, res["same"])
+	assert.Equal(t, "http://merged.com", res["url"])
+	assert.Equal(t, "foo=merged", res["payload"])
+
+	_, err = ctx.RunScript(`new Job({ process: "test.job.run", priority: 1 })`, "")
+	assert.NotNil(t, err)
+}
+
+func TestJobTaskCancel(t *testing.T) {
+
+	ctx := prepare(t, false, "", nil)
+	defer close(ctx)
+
+	calls := int32(0)
+	tsk := task.New(
+		&task.Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				return args[0], nil
+			},
+		},
+		task.Option{Name: "unit-test-job", WorkerNums: 1, Timeout: 5},
+	)
+	task.Tasks["unit-test-job"] = tsk
+	defer delete(task.Tasks, "unit-test-job")
+	go tsk.Start()
+	defer tsk.Stop()
+
+	_, err := ctx.RunScript(`
+		const job = new Job({ task: "unit-test-job", delay: 200 }, "foo");
+		job.Cancel();
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(1), tsk.Stats().Canceled)
+}
+
 func close(ctx *v8go.Context) {
 	ctx.Isolate().Dispose()
 }
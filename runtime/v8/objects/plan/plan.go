@@ -10,6 +10,7 @@ import (
 	"github.com/yaoapp/gou/plan"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
+	"github.com/yaoapp/gou/store"
 	"rogchap.com/v8go"
 )
 
@@ -144,6 +145,7 @@ func StringStatus(status plan.Status) string {
 func (obj *Object) ExportObject(iso *v8go.Isolate) *v8go.ObjectTemplate {
 	tmpl := v8go.NewObjectTemplate(iso)
 	tmpl.Set("Run", obj.run(iso))            // Run the plan
+	tmpl.Set("Resume", obj.resume(iso))      // Resume the plan from the checkpoint
 	tmpl.Set("Add", obj.add(iso))            // Add a task to the plan
 	tmpl.Set("Status", obj.status(iso))      // Get the status of the plan and each task
 	tmpl.Set("Release", obj.release(iso))    // Release the plan
@@ -168,6 +170,11 @@ func (obj *Object) ExportObject(iso *v8go.Isolate) *v8go.ObjectTemplate {
 //	plan.Add("task-id", 1, function(task, shared) {
 //		shared.Set("foo", "bar");
 //	});
+//
+// Save the checkpoints to a store, and resume the incomplete tasks after a crash:
+// const plan = new Plan("plan-id", { checkpoint: "store-name" });
+// plan.Add("task-id", 1, "scripts.task.Run");
+// plan.Resume();
 func (obj *Object) ExportFunction(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	object := obj.ExportObject(iso)
 	tmpl := v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
@@ -181,12 +188,17 @@ func (obj *Object) ExportFunction(iso *v8go.Isolate) *v8go.FunctionTemplate {
 		}
 
 		id := args[0].String()
+		opts, err := planOptions(info.Context(), args[1:]...)
+		if err != nil {
+			return bridge.JsException(info.Context(), err.Error())
+		}
+
 		this, err := object.NewInstance(info.Context())
 		if err != nil {
 			return bridge.JsException(info.Context(), fmt.Sprintf("failed to create plan object %s", err.Error()))
 		}
 
-		return obj.NewInstance(id, this)
+		return obj.newInstance(id, this, opts)
 	})
 	return tmpl
 }
@@ -208,8 +220,39 @@ func (plan *Instance) Data() interface{} {
 	return plan.data
 }
 
+// planOptions the plan options from the javascript options { checkpoint: "store-name" }
+func planOptions(ctx *v8go.Context, args ...*v8go.Value) ([]plan.Option, error) {
+	opts := []plan.Option{}
+	if len(args) == 0 || !args[0].IsObject() {
+		return opts, nil
+	}
+
+	v, err := bridge.GoValue(args[0], ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the options %s", err.Error())
+	}
+
+	options, ok := v.(map[string]interface{})
+	if !ok {
+		return opts, nil
+	}
+
+	if name, ok := options["checkpoint"].(string); ok && name != "" {
+		stor, err := store.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("the checkpoint store %s", err.Error())
+		}
+		opts = append(opts, plan.WithCheckpoint(stor))
+	}
+	return opts, nil
+}
+
 // NewInstance create a new plan instance
 func (obj *Object) NewInstance(id string, this *v8go.Object, data ...interface{}) *v8go.Value {
+	return obj.newInstance(id, this, nil, data...)
+}
+
+func (obj *Object) newInstance(id string, this *v8go.Object, opts []plan.Option, data ...interface{}) *v8go.Value {
 
 	// Get the existing plan
 	if _, ok := plans.Load(id); ok {
@@ -225,7 +268,7 @@ func (obj *Object) NewInstance(id string, this *v8go.Object, data ...interface{}
 
 	shared := plan.NewMemorySharedSpace()
 	instance.shared = shared
-	instance.plan = plan.NewPlan(ctx, id, shared)
+	instance.plan = plan.NewPlan(ctx, id, shared, opts...)
 
 	// Set the data if provided
 	if len(data) > 0 {
@@ -378,6 +421,33 @@ func (obj *Object) run(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	})
 }
 
+func (obj *Object) resume(iso *v8go.Isolate) *v8go.FunctionTemplate {
+	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
+		id, err := obj.ID(info)
+		if err != nil {
+			return bridge.JsException(info.Context(), fmt.Sprintf("failed to get the id %s", err.Error()))
+		}
+
+		val, ok := plans.Load(id)
+		if !ok {
+			return bridge.JsException(info.Context(), fmt.Sprintf("plan %s not found", id))
+		}
+		instance := val.(*Instance)
+
+		// Restore the completed tasks and the shared space, then run the incomplete tasks
+		_, err = instance.plan.Restore()
+		if err != nil {
+			return bridge.JsException(info.Context(), fmt.Sprintf("failed to restore the plan %s", err.Error()))
+		}
+
+		err = instance.plan.Start()
+		if err != nil {
+			return bridge.JsException(info.Context(), fmt.Sprintf("failed to execute the plan %s", err.Error()))
+		}
+		return info.This().Value
+	})
+}
+
 func (obj *Object) release(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
 		id, err := obj.ID(info)
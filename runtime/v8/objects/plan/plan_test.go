@@ -8,6 +8,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/gou/store/lru"
+	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/maps"
 	"rogchap.com/v8go"
 )
@@ -135,6 +138,68 @@ func TestPlanRun(t *testing.T) {
 	}
 }
 
+func TestPlanResume(t *testing.T) {
+	ctx := prepare()
+	defer close(ctx)
+
+	cache, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Pools["plan-checkpoint"] = cache
+	defer delete(store.Pools, "plan-checkpoint")
+
+	runs := map[string]int{}
+	process.Register("unit.test.resume", func(process *process.Process) interface{} {
+		task := process.ArgsString(1)
+		runs[task]++
+		if task == "task-2" && runs[task] == 1 {
+			exception.New("crashed", 500).Throw()
+		}
+		return task
+	})
+
+	v, err := ctx.RunScript(`
+	function make() {
+		const plan = new Plan("resume-plan", { checkpoint: "plan-checkpoint" });
+		plan.Add("task-1", 1, "unit.test.resume");
+		plan.Add("task-2", 2, "unit.test.resume");
+		return plan;
+	}
+
+	function test() {
+		let plan = make();
+		try {
+			plan.Run();
+		} catch (err) {
+			print(err.message);
+		}
+		plan.Release(); // the checkpoint of the failed plan is kept
+
+		plan = make();
+		plan.Resume();
+		const status = plan.Status();
+		plan.Release();
+		return status;
+	}
+	test();
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := bridge.GoValue(v, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := maps.Of(res.(map[string]interface{})).Dot()
+	assert.Equal(t, "completed", status.Get("plan"))
+	assert.Equal(t, "completed", status.Get("tasks.task-2"))
+	assert.Equal(t, 1, runs["task-1"])
+	assert.Equal(t, 2, runs["task-2"])
+}
+
 func TestPlanEvents(t *testing.T) {
 	ctx := prepare()
 	defer close(ctx)
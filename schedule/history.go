@@ -0,0 +1,149 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/any"
+)
+
+// HistoryPrefix the key prefix of the schedule history in the store
+const HistoryPrefix = "yao:schedule:history:"
+
+// History the run history option
+type History struct {
+	Store string `json:"store,omitempty"` // the store name to persist the history and the last fire time, in memory if empty
+	Size  int    `json:"size,omitempty"`  // the max number of the runs to keep, default is 100
+}
+
+// Run the schedule run
+type Run struct {
+	ID          string    `json:"id"`
+	Schedule    string    `json:"schedule"`
+	Status      string    `json:"status"` // success, failed, skipped
+	ScheduledAt time.Time `json:"scheduled_at"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	Duration    int64     `json:"duration"` // milliseconds
+	Error       string    `json:"error,omitempty"`
+	Output      string    `json:"output,omitempty"` // the output summary
+	Trigger     string    `json:"trigger"`          // cron, catchup, manual
+}
+
+// history the run history, persisted to the store if set
+type history struct {
+	name  string
+	size  int
+	store store.Store
+	runs  []*Run
+	last  time.Time
+	mutex sync.Mutex
+}
+
+func newHistory(name string, option *History) (*history, error) {
+	h := &history{name: name, size: 100, runs: []*Run{}}
+	if option == nil {
+		return h, nil
+	}
+
+	if option.Size > 0 {
+		h.size = option.Size
+	}
+
+	if option.Store != "" {
+		stor, err := store.Get(option.Store)
+		if err != nil {
+			return nil, err
+		}
+		h.store = stor
+	}
+	return h, nil
+}
+
+// add the run to the history
+func (h *history) add(run *Run) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.store != nil {
+		bytes, err := jsoniter.Marshal(run)
+		if err != nil {
+			return
+		}
+		key := h.key("runs")
+		h.store.Push(key, string(bytes))
+		for h.store.ArrayLen(key) > h.size {
+			h.store.Pop(key, -1)
+		}
+		return
+	}
+
+	h.runs = append(h.runs, run)
+	if over := len(h.runs) - h.size; over > 0 {
+		h.runs = h.runs[over:]
+	}
+}
+
+// list the runs, the newest first
+func (h *history) list(limit int) ([]*Run, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	runs := h.runs
+	if h.store != nil {
+		values, err := h.store.ArrayAll(h.key("runs"))
+		if err != nil {
+			return nil, err
+		}
+
+		runs = []*Run{}
+		for _, value := range values {
+			run := &Run{}
+			if err := jsoniter.UnmarshalFromString(any.Of(value).CString(), run); err != nil {
+				return nil, err
+			}
+			runs = append(runs, run)
+		}
+	}
+
+	res := []*Run{}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		res = append(res, runs[i])
+	}
+	return res, nil
+}
+
+// fired set the last fire time
+func (h *history) fired(at time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if at.Before(h.last) {
+		return
+	}
+
+	h.last = at
+	if h.store != nil {
+		h.store.Set(h.key("last"), at.UnixMilli(), 0)
+	}
+}
+
+// lastFired the last fire time, zero if the schedule never fired
+func (h *history) lastFired() time.Time {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.store != nil {
+		if value, has := h.store.Get(h.key("last")); has {
+			return time.UnixMilli(int64(any.Of(value).CInt()))
+		}
+	}
+	return h.last
+}
+
+func (h *history) key(name string) string {
+	return HistoryPrefix + h.name + ":" + name
+}
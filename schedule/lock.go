@@ -0,0 +1,269 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/connector/redis"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// LockPrefix the key prefix of the schedule locks
+const LockPrefix = "yao:schedule:lock:"
+
+// DefaultLockTable the default table name of the database locker
+const DefaultLockTable = "__yao_schedule_locks"
+
+// Lock the distributed lock option, the schedule fires on the instance holding the lock (the leader) only.
+// The leader renews the lock every ttl/3, another instance takes over once the lock expires.
+type Lock struct {
+	Connector string `json:"connector"`       // redis or database connector
+	Table     string `json:"table,omitempty"` // the table of the database locker, default is __yao_schedule_locks
+	TTL       int    `json:"ttl,omitempty"`   // the lock ttl (seconds), default is 30
+}
+
+// Locker the distributed lock backend
+type Locker interface {
+	// Acquire acquire the lock or renew it if the owner holds the lock
+	Acquire(key string, owner string, ttl time.Duration) (bool, error)
+	// Release release the lock if the owner holds the lock
+	Release(key string, owner string) error
+}
+
+// RedisLocker the locker using a redis connector
+type RedisLocker struct {
+	Rdb *goredis.Client
+}
+
+// DBLocker the locker using a database connector
+type DBLocker struct {
+	Connector connector.Connector
+	Table     string
+}
+
+var acquireScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+var releaseScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// NewLocker create a locker using the redis or database connector of the lock option
+func NewLocker(lock *Lock) (Locker, error) {
+	c, err := connector.Select(lock.Connector)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Is(connector.REDIS) {
+		rdb, ok := c.(*redis.Connector)
+		if !ok {
+			return nil, fmt.Errorf("the connector %s was not a *redis.Connector", lock.Connector)
+		}
+		return &RedisLocker{Rdb: rdb.Rdb}, nil
+	}
+
+	if c.Is(connector.DATABASE) {
+		table := lock.Table
+		if table == "" {
+			table = DefaultLockTable
+		}
+		db := &DBLocker{Connector: c, Table: table}
+		if err := db.ensureTable(); err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+
+	return nil, fmt.Errorf("the connector %s should be a redis or database connector", lock.Connector)
+}
+
+// Acquire acquire or renew the lock
+func (r *RedisLocker) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	n, err := acquireScript.Run(context.Background(), r.Rdb, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Release release the lock
+func (r *RedisLocker) Release(key string, owner string) error {
+	return releaseScript.Run(context.Background(), r.Rdb, []string{key}, owner).Err()
+}
+
+// ensureTable create the lock table if not exists
+func (db *DBLocker) ensureTable() error {
+	sch, err := db.Connector.Schema()
+	if err != nil {
+		return err
+	}
+
+	has, err := sch.HasTable(db.Table)
+	if err != nil || has {
+		return err
+	}
+
+	return sch.CreateTable(db.Table, func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("name", 200).Unique()
+		table.String("owner", 200)
+		table.BigInteger("expired_at").Index()
+	})
+}
+
+// Acquire acquire or renew the lock, the expired lock is taken over
+func (db *DBLocker) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UnixMilli()
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return false, err
+	}
+
+	effect, err := qb.Table(db.Table).
+		Where("name", key).
+		Where(func(sub query.Query) {
+			sub.Where("owner", owner).OrWhere("expired_at", "<", now)
+		}).
+		Update(maps.MapStrAny{"owner": owner, "expired_at": now + ttl.Milliseconds()})
+	if err != nil {
+		return false, err
+	}
+
+	if effect > 0 {
+		return true, nil
+	}
+
+	qb, err = db.Connector.Query()
+	if err != nil {
+		return false, err
+	}
+
+	row, err := qb.Table(db.Table).Where("name", key).First()
+	if err != nil || !row.IsEmpty() {
+		return false, err
+	}
+
+	// The unique key rejects the other instances inserting at the same time
+	qb, err = db.Connector.Query()
+	if err != nil {
+		return false, err
+	}
+	err = qb.Table(db.Table).Insert(maps.MapStrAny{"name": key, "owner": owner, "expired_at": now + ttl.Milliseconds()})
+	if err != nil {
+		if isDuplicate(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// isDuplicate checks if the error is a unique key violation of mysql, postgres or sqlite
+func isDuplicate(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "duplicate entry") || // mysql
+		strings.Contains(message, "duplicate key") || // postgres
+		strings.Contains(message, "unique constraint") // sqlite
+}
+
+// Release release the lock
+func (db *DBLocker) Release(key string, owner string) error {
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return err
+	}
+	_, err = qb.Table(db.Table).Where("name", key).Where("owner", owner).Delete()
+	return err
+}
+
+// IsLeader checks if the instance holds the lock of the schedule, always true if the lock is not set
+func (sch *Schedule) IsLeader() bool {
+	if sch.locker == nil {
+		return true
+	}
+	return time.Now().UnixNano() < atomic.LoadInt64(&sch.leaseUntil)
+}
+
+// elect campaign for the lock until the schedule stops
+func (sch *Schedule) elect(ctx context.Context) {
+	ttl := sch.lockTTL()
+	sch.campaign(ttl)
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt64(&sch.leaseUntil, 0)
+			if err := sch.locker.Release(sch.lockKey(), sch.owner); err != nil {
+				log.Error("[Schedule] %s release the lock %s", sch.name, err.Error())
+			}
+			return
+		case <-ticker.C:
+			sch.campaign(ttl)
+		}
+	}
+}
+
+// campaign acquire or renew the lock, the lease is counted from the time before acquiring
+func (sch *Schedule) campaign(ttl time.Duration) {
+	start := time.Now()
+	leader := sch.IsLeader()
+	ok, err := sch.locker.Acquire(sch.lockKey(), sch.owner, ttl)
+	if err != nil {
+		log.Error("[Schedule] %s acquire the lock %s", sch.name, err.Error())
+		return
+	}
+
+	if !ok {
+		atomic.StoreInt64(&sch.leaseUntil, 0)
+		if leader {
+			log.Warn("[Schedule] %s lost the lock (%s)", sch.name, sch.owner)
+		}
+		return
+	}
+
+	atomic.StoreInt64(&sch.leaseUntil, start.Add(ttl).UnixNano())
+	if !leader {
+		log.Info("[Schedule] %s acquired the lock (%s)", sch.name, sch.owner)
+		go sch.catchup()
+	}
+}
+
+func (sch *Schedule) lockKey() string {
+	return LockPrefix + sch.name
+}
+
+func (sch *Schedule) lockTTL() time.Duration {
+	if sch.Lock == nil || sch.Lock.TTL <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(sch.Lock.TTL) * time.Second
+}
+
+// lockOwner the unique owner name of the instance
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.New().String()[:8])
+}
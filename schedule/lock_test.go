@@ -0,0 +1,194 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestScheduleLock(t *testing.T) {
+	testLeaderElection(t, &memLocker{locks: map[string]memLock{}})
+}
+
+func TestScheduleLockRedis(t *testing.T) {
+	testLeaderElection(t, prepareLocker(t, "redis"))
+}
+
+func TestScheduleLockDB(t *testing.T) {
+	testLeaderElection(t, prepareLocker(t, testDBConnector()))
+}
+
+func TestRedisLocker(t *testing.T) {
+	testLocker(t, prepareLocker(t, "redis"))
+}
+
+func TestDBLocker(t *testing.T) {
+	testLocker(t, prepareLocker(t, testDBConnector()))
+}
+
+func testLeaderElection(t *testing.T, locker Locker) {
+	var fired int64
+	process.Register("tests.schedule.lock", func(process *process.Process) interface{} {
+		atomic.AddInt64(&fired, 1)
+		return nil
+	})
+
+	sch1 := prepareLockSchedule(t, locker, "node-1")
+	sch2 := prepareLockSchedule(t, locker, "node-2")
+
+	sch1.Start()
+	time.Sleep(100 * time.Millisecond)
+	sch2.Start()
+	defer sch2.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, sch1.IsLeader())
+	assert.False(t, sch2.IsLeader())
+
+	time.Sleep(2 * time.Second)
+	n := atomic.LoadInt64(&fired)
+	assert.GreaterOrEqual(t, n, int64(1))
+	assert.LessOrEqual(t, n, int64(2)) // fired by the leader only
+
+	// the other instance takes over
+	sch1.Stop()
+	time.Sleep(1500 * time.Millisecond)
+	assert.False(t, sch1.IsLeader())
+	assert.True(t, sch2.IsLeader())
+}
+
+func testLocker(t *testing.T, locker Locker) {
+	key := LockPrefix + "unit-test"
+	locker.Release(key, "node-1")
+	locker.Release(key, "node-2")
+
+	ok, err := locker.Acquire(key, "node-1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+
+	// the owner renews the lock, the others are rejected
+	ok, err = locker.Acquire(key, "node-1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+
+	ok, err = locker.Acquire(key, "node-2", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, ok)
+
+	// the lock is released by the owner only
+	err = locker.Release(key, "node-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, _ = locker.Acquire(key, "node-2", time.Second)
+	assert.False(t, ok)
+
+	err = locker.Release(key, "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err = locker.Acquire(key, "node-2", 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+
+	// the expired lock is taken over
+	time.Sleep(300 * time.Millisecond)
+	ok, err = locker.Acquire(key, "node-1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+	locker.Release(key, "node-1")
+}
+
+func prepareLocker(t *testing.T, id string) Locker {
+	loadApp(t)
+	_, err := connector.Load(filepath.Join("connectors", fmt.Sprintf("%s.conn.yao", id)), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := &Lock{Connector: id, Table: "__unit_test_schedule_locks"}
+	if connector.Connectors[id].Is(connector.DATABASE) {
+		sch, err := connector.Connectors[id].Schema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sch.DropTableIfExists(lock.Table); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locker, err := NewLocker(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return locker
+}
+
+func testDBConnector() string {
+	if os.Getenv("GOU_TEST_DB_DRIVER") == "sqlite3" {
+		return "sqlite"
+	}
+	return "mysql"
+}
+
+func prepareLockSchedule(t *testing.T, locker Locker, owner string) *Schedule {
+	sch := &Schedule{name: "lock", Schedule: "@every 1s", Process: "tests.schedule.lock", Lock: &Lock{TTL: 1}}
+	lock := sch.Lock
+	sch.Lock = nil
+	err := sch.prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch.Lock = lock
+	sch.locker = locker
+	sch.owner = owner
+	return sch
+}
+
+type memLock struct {
+	owner   string
+	expired time.Time
+}
+
+type memLocker struct {
+	locks map[string]memLock
+	mutex sync.Mutex
+}
+
+func (m *memLocker) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	lock, has := m.locks[key]
+	if has && lock.owner != owner && time.Now().Before(lock.expired) {
+		return false, nil
+	}
+	m.locks[key] = memLock{owner: owner, expired: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (m *memLocker) Release(key string, owner string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if lock, has := m.locks[key]; has && lock.owner == owner {
+		delete(m.locks, key)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package schedule
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/robfig/cron/v3"
+	"github.com/yaoapp/kun/log"
+)
+
+// Info the schedule information
+type Info struct {
+	Name     string        `json:"name"`
+	Schedule string        `json:"schedule"`
+	Process  string        `json:"process,omitempty"`
+	TaskName string        `json:"task,omitempty"`
+	Args     []interface{} `json:"args,omitempty"`
+	Timezone string        `json:"timezone,omitempty"`
+	Enabled  bool          `json:"enabled"`
+	Paused   bool          `json:"paused"`
+	Leader   bool          `json:"leader"`
+	Next     *time.Time    `json:"next,omitempty"` // the next fire time
+	Prev     *time.Time    `json:"prev,omitempty"` // the previous fire time
+}
+
+// Create create a schedule from the DSL at runtime, the schedule is not started
+func Create(name string, dsl map[string]interface{}) (*Schedule, error) {
+	schedulesMu.RLock()
+	_, has := Schedules[name]
+	schedulesMu.RUnlock()
+	if has {
+		return nil, fmt.Errorf("schedule %s already exists", name)
+	}
+
+	sch, err := parse(name, dsl)
+	if err != nil {
+		return nil, err
+	}
+
+	schedulesMu.Lock()
+	Schedules[name] = sch
+	schedulesMu.Unlock()
+	return sch, nil
+}
+
+// Update replace the schedule with the DSL, the history is kept if the history option is not changed,
+// and the new schedule is started if the old one was enabled.
+func Update(name string, dsl map[string]interface{}) (*Schedule, error) {
+	schedulesMu.RLock()
+	old, has := Schedules[name]
+	schedulesMu.RUnlock()
+	if !has {
+		return nil, fmt.Errorf("schedule %s does not load", name)
+	}
+
+	sch, err := parse(name, dsl)
+	if err != nil {
+		return nil, err
+	}
+
+	if reflect.DeepEqual(old.History, sch.History) {
+		sch.history = old.history
+	}
+
+	enabled := old.Enabled
+	if old.Enabled {
+		old.Stop()
+	}
+	atomic.StoreInt32(&sch.paused, atomic.LoadInt32(&old.paused))
+
+	schedulesMu.Lock()
+	Schedules[name] = sch
+	schedulesMu.Unlock()
+
+	if enabled {
+		sch.Start()
+	}
+	return sch, nil
+}
+
+// List the schedules ordered by name
+func List() []Info {
+	schedulesMu.RLock()
+	list := []Info{}
+	for _, sch := range Schedules {
+		list = append(list, sch.Info())
+	}
+	schedulesMu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// parse the schedule DSL
+func parse(name string, dsl map[string]interface{}) (*Schedule, error) {
+	bytes, err := jsoniter.Marshal(dsl)
+	if err != nil {
+		return nil, err
+	}
+
+	sch := &Schedule{name: name}
+	err = jsoniter.Unmarshal(bytes, sch)
+	if err != nil {
+		return nil, err
+	}
+
+	if sch.Name == "" {
+		sch.Name = name
+	}
+
+	err = sch.prepare()
+	if err != nil {
+		return nil, err
+	}
+	return sch, nil
+}
+
+// Info the schedule information with the next and the previous fire time
+func (sch *Schedule) Info() Info {
+	info := Info{
+		Name:     sch.name,
+		Schedule: sch.Schedule,
+		Process:  sch.Process,
+		TaskName: sch.TaskName,
+		Args:     sch.Args,
+		Timezone: sch.location.String(),
+		Enabled:  sch.Enabled,
+		Paused:   sch.Paused(),
+		Leader:   sch.IsLeader(),
+	}
+
+	if next := sch.Next(); !next.IsZero() {
+		info.Next = &next
+	}
+
+	if prev := sch.history.lastFired(); !prev.IsZero() {
+		prev = prev.In(sch.location)
+		info.Prev = &prev
+	}
+	return info
+}
+
+// Next the next fire time
+func (sch *Schedule) Next() time.Time {
+	if sch.Enabled {
+		if next := sch.cron.Entry(sch.id).Next; !next.IsZero() {
+			return next
+		}
+	}
+
+	spec, err := cron.ParseStandard(sch.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	return spec.Next(time.Now().In(sch.location))
+}
+
+// Trigger run the schedule immediately, the lock and the pause are ignored
+func (sch *Schedule) Trigger() *Run {
+	log.Info("[Schedule] %s triggered", sch.name)
+	return sch.fire(time.Now().Truncate(time.Second), "manual")
+}
+
+// Pause skip the fires, the cron entry is kept
+func (sch *Schedule) Pause() {
+	atomic.StoreInt32(&sch.paused, 1)
+}
+
+// Resume the fires
+func (sch *Schedule) Resume() {
+	atomic.StoreInt32(&sch.paused, 0)
+}
+
+// Paused checks if the schedule is paused
+func (sch *Schedule) Paused() bool {
+	return atomic.LoadInt32(&sch.paused) == 1
+}
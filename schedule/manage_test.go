@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestScheduleManage(t *testing.T) {
+	var fired int64
+	process.Register("tests.schedule.manage", func(process *process.Process) interface{} {
+		atomic.AddInt64(&fired, 1)
+		return process.Args
+	})
+
+	defer func() {
+		schedulesMu.Lock()
+		delete(Schedules, "manage")
+		schedulesMu.Unlock()
+	}()
+
+	res, err := process.New("schedules.manage.Create", map[string]interface{}{
+		"schedule": "@every 1s",
+		"process":  "tests.schedule.manage",
+		"args":     []interface{}{"foo"},
+		"timezone": "Asia/Shanghai",
+	}).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := res.(Info)
+	assert.Equal(t, "manage", info.Name)
+	assert.Equal(t, "Asia/Shanghai", info.Timezone)
+	assert.False(t, info.Enabled)
+	assert.NotNil(t, info.Next)
+	assert.Nil(t, info.Prev)
+
+	_, err = process.New("schedules.manage.Create", map[string]interface{}{"schedule": "@every 1s", "process": "tests.schedule.manage"}).Exec()
+	assert.NotNil(t, err)
+
+	// trigger
+	res, err = process.New("schedules.manage.Trigger").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "manual", res.(*Run).Trigger)
+	assert.Equal(t, "[foo]", res.(*Run).Output)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&fired))
+
+	// pause and resume
+	sch := Select("manage")
+	sch.Start()
+	_, err = process.New("schedules.manage.Pause").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1200 * time.Millisecond)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&fired))
+
+	res, err = process.New("schedules.manage.Resume").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, res.(Info).Paused)
+	time.Sleep(1200 * time.Millisecond)
+	assert.GreaterOrEqual(t, atomic.LoadInt64(&fired), int64(2))
+
+	// update
+	res, err = process.New("schedules.manage.Update", map[string]interface{}{
+		"schedule": "0 9 * * *",
+		"process":  "tests.schedule.manage",
+	}).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info = res.(Info)
+	assert.True(t, info.Enabled)
+	assert.Equal(t, 9, info.Next.Hour())
+	assert.NotNil(t, info.Prev)
+	defer Select("manage").Stop()
+
+	runs, err := Select("manage").Runs(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.GreaterOrEqual(t, len(runs), 2) // the history is kept
+
+	// list
+	res, err = process.New("schedules.List").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, info := range res.([]Info) {
+		names = append(names, info.Name)
+	}
+	assert.Contains(t, names, "manage")
+}
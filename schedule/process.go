@@ -1,7 +1,10 @@
 package schedule
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/yaoapp/gou/application"
@@ -14,11 +17,19 @@ import (
 
 // Schedules the registered schedules
 var Schedules = map[string]*Schedule{}
+var schedulesMu sync.RWMutex
 
 // ScheduleHandlers chedule process handlers
 var ScheduleHandlers = map[string]process.Handler{
-	"start": processScheduleStart,
-	"stop":  processScheduleStop,
+	"start":   processScheduleStart,
+	"stop":    processScheduleStop,
+	"history": processScheduleHistory,
+	"list":    processScheduleList,
+	"create":  processScheduleCreate,
+	"update":  processScheduleUpdate,
+	"trigger": processScheduleTrigger,
+	"pause":   processSchedulePause,
+	"resume":  processScheduleResume,
 }
 
 func init() {
@@ -27,15 +38,31 @@ func init() {
 
 // Schedule the schedule struct
 type Schedule struct {
-	name     string
-	Name     string        `json:"name"`
-	Process  string        `json:"process,omitempty"`
-	Schedule string        `json:"schedule"`
-	TaskName string        `json:"task,omitempty"`
-	Args     []interface{} `json:"args,omitempty"`
-	id       cron.EntryID
-	Enabled  bool
-	cron     *cron.Cron
+	name       string
+	Name       string        `json:"name"`
+	Process    string        `json:"process,omitempty"`
+	Schedule   string        `json:"schedule"`
+	TaskName   string        `json:"task,omitempty"`
+	Args       []interface{} `json:"args,omitempty"`
+	Lock       *Lock         `json:"lock,omitempty"`     // fire once cluster-wide on the instance holding the lock
+	Timezone   string        `json:"timezone,omitempty"` // the IANA time zone of the cron expression, default is the local time zone
+	Jitter     int           `json:"jitter,omitempty"`   // the max random delay (seconds) before running
+	Overlap    string        `json:"overlap,omitempty"`  // the policy when the previous run is not finished: allow (default), skip, queue
+	Catchup    int           `json:"catchup,omitempty"`  // the max number of missed runs to run on starting, requires the history store
+	History    *History      `json:"history,omitempty"`  // the run history option
+	id         cron.EntryID
+	Enabled    bool
+	cron       *cron.Cron
+	locker     Locker
+	owner      string
+	leaseUntil int64 // the lock lease (unix nano) of the leader
+	cancel     context.CancelFunc
+	location   *time.Location
+	history    *history
+	handle     func() (interface{}, error)
+	running    int32
+	paused     int32
+	serial     sync.Mutex
 }
 
 // Load load schedule
@@ -52,27 +79,79 @@ func Load(file string, name string) (*Schedule, error) {
 		return nil, err
 	}
 
-	handler, err := sch.handler()
+	err = sch.prepare()
 	if err != nil {
 		return nil, err
 	}
 
-	c := cron.New()
-	id, err := c.AddFunc(sch.Schedule, handler)
+	schedulesMu.Lock()
+	Schedules[name] = sch
+	schedulesMu.Unlock()
+	return sch, nil
+}
 
+// prepare create the cron entry and the locker of the schedule
+func (sch *Schedule) prepare() error {
+	handler, err := sch.handler()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if sch.Lock != nil {
+		sch.locker, err = NewLocker(sch.Lock)
+		if err != nil {
+			return err
+		}
+		sch.owner = lockOwner()
+	}
+
+	sch.location = time.Local
+	if sch.Timezone != "" {
+		sch.location, err = time.LoadLocation(sch.Timezone)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch sch.Overlap {
+	case "", "allow", "skip", "queue":
+	default:
+		return fmt.Errorf("overlap %s is not supported (allow, skip, queue)", sch.Overlap)
+	}
+
+	sch.history, err = newHistory(sch.name, sch.History)
+	if err != nil {
+		return err
+	}
+
+	sch.handle = handler
+	c := cron.New(cron.WithLocation(sch.location))
+	id, err := c.AddFunc(sch.Schedule, func() {
+		if sch.Paused() {
+			log.Trace("[Schedule] %s skipped, the schedule is paused", sch.name)
+			return
+		}
+		if !sch.IsLeader() {
+			log.Trace("[Schedule] %s skipped, the lock is held by the other instance", sch.name)
+			return
+		}
+		sch.fire(time.Now().Truncate(time.Second), "cron")
+	})
+
+	if err != nil {
+		return err
 	}
 
 	sch.cron = c
 	sch.id = id
-	Schedules[name] = sch
-	return sch, nil
+	return nil
 }
 
 // Select select schedule by name
 func Select(name string) *Schedule {
+	schedulesMu.RLock()
 	sch, has := Schedules[name]
+	schedulesMu.RUnlock()
 	if !has {
 		exception.New("Schedule:%s does not load", 500, name).Throw()
 	}
@@ -94,8 +173,8 @@ func (sch *Schedule) parseArgs() {
 	sch.Args = args
 }
 
-// handler task or process
-func (sch *Schedule) handler() (func(), error) {
+// handler task or process, returns the output of the run
+func (sch *Schedule) handler() (func() (interface{}, error), error) {
 	sch.parseArgs()
 
 	if sch.TaskName != "" {
@@ -103,19 +182,29 @@ func (sch *Schedule) handler() (func(), error) {
 		if !has {
 			return nil, fmt.Errorf("%s was not loaded", sch.TaskName)
 		}
-		return func() { task.Tasks[sch.TaskName].Add(sch.Args...) }, nil
+		return func() (interface{}, error) {
+			id, err := task.Tasks[sch.TaskName].Add(sch.Args...)
+			if err != nil {
+				log.Error("[Schedule] %s %s %s", sch.name, sch.TaskName, err)
+				return nil, err
+			}
+			return fmt.Sprintf("job #%d", id), nil
+		}, nil
 	} else if sch.Process != "" {
-		return func() {
+		return func() (interface{}, error) {
 			p, err := process.Of(sch.Process, sch.Args...)
 			if err != nil {
 				log.Error("[Schedule] %s %s %s", sch.name, sch.Process, err)
+				return nil, err
 			}
+			defer p.Release()
 
 			err = p.Execute()
 			if err != nil {
 				log.Error("[Schedule] %s %s %s", sch.name, sch.Process, err)
+				return nil, err
 			}
-			defer p.Release()
+			return p.Value(), nil
 		}, nil
 	}
 
@@ -125,6 +214,13 @@ func (sch *Schedule) handler() (func(), error) {
 // Start start the schedule
 func (sch *Schedule) Start() {
 	sch.Enabled = true
+	if sch.locker != nil && sch.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		sch.cancel = cancel
+		go sch.elect(ctx)
+	} else if sch.locker == nil {
+		go sch.catchup()
+	}
 	sch.cron.Start()
 }
 
@@ -132,6 +228,10 @@ func (sch *Schedule) Start() {
 func (sch *Schedule) Stop() {
 	sch.Enabled = false
 	sch.cron.Stop()
+	if sch.cancel != nil {
+		sch.cancel()
+		sch.cancel = nil
+	}
 }
 
 // processScheduleStart
@@ -147,3 +247,62 @@ func processScheduleStop(process *process.Process) interface{} {
 	sch.Stop()
 	return map[string]interface{}{"enabled": sch.Enabled}
 }
+
+// processScheduleHistory schedules.<name>.History(limit?) the runs, the newest first
+func processScheduleHistory(process *process.Process) interface{} {
+	sch := Select(process.ID)
+	limit := 0
+	if process.NumOfArgs() > 0 {
+		limit = process.ArgsInt(0)
+	}
+
+	runs, err := sch.Runs(limit)
+	if err != nil {
+		exception.New("Schedule %s History: %s", 500, process.ID, err).Throw()
+	}
+	return runs
+}
+
+// processScheduleList schedules.List() list the schedules with the next and the previous fire time
+func processScheduleList(process *process.Process) interface{} {
+	return List()
+}
+
+// processScheduleCreate schedules.<name>.Create(dsl) create a schedule at runtime
+func processScheduleCreate(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	sch, err := Create(process.ID, process.ArgsMap(0))
+	if err != nil {
+		exception.New("Schedule %s Create: %s", 400, process.ID, err).Throw()
+	}
+	return sch.Info()
+}
+
+// processScheduleUpdate schedules.<name>.Update(dsl) replace the schedule at runtime
+func processScheduleUpdate(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	sch, err := Update(process.ID, process.ArgsMap(0))
+	if err != nil {
+		exception.New("Schedule %s Update: %s", 400, process.ID, err).Throw()
+	}
+	return sch.Info()
+}
+
+// processScheduleTrigger schedules.<name>.Trigger() run the schedule immediately
+func processScheduleTrigger(process *process.Process) interface{} {
+	return Select(process.ID).Trigger()
+}
+
+// processSchedulePause schedules.<name>.Pause() skip the fires, the cron entry is kept
+func processSchedulePause(process *process.Process) interface{} {
+	sch := Select(process.ID)
+	sch.Pause()
+	return sch.Info()
+}
+
+// processScheduleResume schedules.<name>.Resume() resume the fires
+func processScheduleResume(process *process.Process) interface{} {
+	sch := Select(process.ID)
+	sch.Resume()
+	return sch.Info()
+}
@@ -0,0 +1,135 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+)
+
+// fire run the schedule with the jitter and the overlap policy, and record the run
+func (sch *Schedule) fire(scheduled time.Time, trigger string) *Run {
+	run := &Run{ID: uuid.New().String(), Schedule: sch.name, ScheduledAt: scheduled, Trigger: trigger}
+	sch.history.fired(scheduled)
+
+	if sch.Jitter > 0 && trigger == "cron" {
+		time.Sleep(time.Duration(rand.Int63n(int64(sch.Jitter) * int64(time.Second))))
+	}
+
+	switch sch.Overlap {
+	case "skip":
+		if !atomic.CompareAndSwapInt32(&sch.running, 0, 1) {
+			run.Status = "skipped"
+			run.StartedAt = time.Now()
+			run.EndedAt = run.StartedAt
+			sch.history.add(run)
+			log.Warn("[Schedule] %s skipped, the previous run is not finished", sch.name)
+			return run
+		}
+		defer atomic.StoreInt32(&sch.running, 0)
+
+	case "queue":
+		sch.serial.Lock()
+		defer sch.serial.Unlock()
+	}
+
+	run.StartedAt = time.Now()
+	output, err := sch.exec()
+	run.EndedAt = time.Now()
+	run.Duration = run.EndedAt.Sub(run.StartedAt).Milliseconds()
+	run.Status = "success"
+	if err != nil {
+		run.Status = "failed"
+		run.Error = err.Error()
+	}
+	run.Output = summary(output)
+	sch.history.add(run)
+	return run
+}
+
+// exec run the handler, the panic is recovered as an error
+func (sch *Schedule) exec() (output interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+	return sch.handle()
+}
+
+// catchup run the missed runs since the last fire time (the app was down), the latest Catchup runs are run.
+func (sch *Schedule) catchup() {
+	if sch.Catchup <= 0 {
+		return
+	}
+
+	last := sch.history.lastFired()
+	if last.IsZero() {
+		return
+	}
+
+	missed, err := sch.missed(last, time.Now())
+	if err != nil {
+		log.Error("[Schedule] %s catchup %s", sch.name, err.Error())
+		return
+	}
+
+	if len(missed) > sch.Catchup {
+		missed = missed[len(missed)-sch.Catchup:]
+	}
+
+	for _, at := range missed {
+		if !sch.IsLeader() {
+			return
+		}
+		log.Info("[Schedule] %s catchup the run scheduled at %s", sch.name, at.Format(time.RFC3339))
+		sch.fire(at, "catchup")
+	}
+}
+
+// maxMissed the max number of the missed fire times to keep
+const maxMissed = 10000
+
+// missed the fire times after the last fire time and before now
+func (sch *Schedule) missed(last time.Time, now time.Time) ([]time.Time, error) {
+	spec, err := cron.ParseStandard(sch.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	missed := []time.Time{}
+	for at := spec.Next(last.In(sch.location)); !at.IsZero() && at.Before(now); at = spec.Next(at) {
+		missed = append(missed, at)
+		if len(missed) > maxMissed { // keeps the latest runs only
+			keep := sch.Catchup
+			if keep > maxMissed {
+				keep = maxMissed
+			}
+			missed = missed[len(missed)-keep:]
+		}
+	}
+	return missed, nil
+}
+
+// Runs the run history, the newest first
+func (sch *Schedule) Runs(limit int) ([]*Run, error) {
+	return sch.history.list(limit)
+}
+
+// summary the output summary of the run
+func summary(output interface{}) string {
+	if output == nil {
+		return ""
+	}
+
+	text := fmt.Sprintf("%v", output)
+	if len(text) > 256 {
+		return text[:256] + "..."
+	}
+	return text
+}
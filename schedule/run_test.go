@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestScheduleHistory(t *testing.T) {
+	process.Register("tests.schedule.run", func(process *process.Process) interface{} {
+		if process.ArgsString(0) == "fail" {
+			panic(fmt.Errorf("run failed"))
+		}
+		return "done"
+	})
+
+	sch := prepareRunSchedule(t, &Schedule{Process: "tests.schedule.run", Args: []interface{}{"ok"}})
+	sch.fire(time.Now(), "cron")
+	sch.Args = []interface{}{"fail"}
+	sch.handle, _ = sch.handler()
+	sch.fire(time.Now(), "cron")
+
+	runs, err := sch.Runs(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(runs))
+	assert.Equal(t, "failed", runs[0].Status)
+	assert.Contains(t, runs[0].Error, "run failed")
+	assert.Equal(t, "success", runs[1].Status)
+	assert.Equal(t, "done", runs[1].Output)
+	assert.Equal(t, "cron", runs[1].Trigger)
+
+	runs, err = sch.Runs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(runs))
+}
+
+func TestScheduleOverlap(t *testing.T) {
+	process.Register("tests.schedule.slow", func(process *process.Process) interface{} {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+
+	sch := prepareRunSchedule(t, &Schedule{Process: "tests.schedule.slow", Overlap: "skip"})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sch.fire(time.Now(), "cron")
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+	wg.Wait()
+
+	runs, _ := sch.Runs(0)
+	assert.Equal(t, 2, len(runs))
+	assert.Equal(t, "skipped", runs[0].Status)
+	assert.Equal(t, "success", runs[1].Status)
+
+	sch = prepareRunSchedule(t, &Schedule{Process: "tests.schedule.slow", Overlap: "queue"})
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sch.fire(time.Now(), "cron")
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	runs, _ = sch.Runs(0)
+	assert.Equal(t, "success", runs[0].Status)
+	assert.Equal(t, "success", runs[1].Status)
+
+	_, err := prepareSchedule(&Schedule{Process: "tests.schedule.slow", Overlap: "wait"})
+	assert.NotNil(t, err)
+}
+
+func TestScheduleCatchup(t *testing.T) {
+	process.Register("tests.schedule.catchup", func(process *process.Process) interface{} { return nil })
+
+	sch := prepareRunSchedule(t, &Schedule{Schedule: "0 9 * * *", Timezone: "Asia/Shanghai", Process: "tests.schedule.catchup", Catchup: 2})
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	now := time.Date(2026, 1, 10, 8, 0, 0, 0, loc)
+	missed, err := sch.missed(time.Date(2026, 1, 5, 9, 0, 0, 0, loc).UTC(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, len(missed))
+	assert.Equal(t, time.Date(2026, 1, 6, 9, 0, 0, 0, loc).Unix(), missed[0].Unix())
+	assert.Equal(t, time.Date(2026, 1, 9, 9, 0, 0, 0, loc).Unix(), missed[3].Unix())
+
+	// the runs missed in the last 3 days, the latest 2 runs are caught up
+	sch.history.fired(time.Now().Add(-72 * time.Hour))
+	sch.catchup()
+	runs, _ := sch.Runs(0)
+	assert.Equal(t, 2, len(runs))
+	assert.Equal(t, "catchup", runs[0].Trigger)
+	assert.True(t, runs[0].ScheduledAt.After(runs[1].ScheduledAt))
+
+	// more missed runs than kept, the catchup is larger than the kept runs
+	every, err := prepareSchedule(&Schedule{Schedule: "* * * * *", Process: "tests.schedule.catchup", Catchup: 20000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	missed, err = every.missed(now.Add(-10003*time.Minute), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, maxMissed, len(missed))
+	assert.True(t, missed[len(missed)-1].Before(now))
+
+	_, err = prepareSchedule(&Schedule{Process: "tests.schedule.catchup", Timezone: "Mars/Olympus"})
+	assert.NotNil(t, err)
+}
+
+func prepareRunSchedule(t *testing.T, sch *Schedule) *Schedule {
+	sch, err := prepareSchedule(sch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sch
+}
+
+func prepareSchedule(sch *Schedule) (*Schedule, error) {
+	sch.name = "run"
+	if sch.Schedule == "" {
+		sch.Schedule = "@every 1h"
+	}
+	return sch, sch.prepare()
+}
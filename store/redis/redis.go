@@ -506,3 +506,18 @@ func (store *Store) Decr(key string, delta int64) (int64, error) {
 	}
 	return result, nil
 }
+
+// Eval runs the Lua script atomically on the redis server, the keys are prefixed
+func (store *Store) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	}
+
+	result, err := store.rdb.Eval(context.Background(), script, prefixed, args...).Result()
+	if err != nil {
+		log.Error("Store redis Eval %v: %s", prefixed, err.Error())
+		return nil, err
+	}
+	return result, nil
+}
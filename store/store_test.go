@@ -93,6 +93,33 @@ func TestRedis(t *testing.T) {
 	testPrefix(t, store1, store2)
 }
 
+func TestRedisEval(t *testing.T) {
+	store := newStoreWithPrefix(t, getConnector(t, "redis"), "eval:")
+	store.Clear()
+	defer store.Clear()
+
+	scripts, ok := store.(interface {
+		Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+	})
+	if !ok {
+		t.Fatal("the redis store does not run the scripts")
+	}
+
+	res, err := scripts.Eval(`return redis.call('INCRBY', KEYS[1], ARGV[1])`, []string{"counter"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(5), res)
+
+	// the keys are prefixed
+	value, ok := store.Get("counter")
+	assert.True(t, ok)
+	assert.Equal(t, float64(5), value)
+
+	_, err = scripts.Eval(`return redis.call('NOSUCHCOMMAND')`, []string{})
+	assert.NotNil(t, err)
+}
+
 func TestMongo(t *testing.T) {
 	skipIfMongoUnavailable(t)
 	store := newStore(t, getConnector(t, "mongo"))
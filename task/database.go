@@ -0,0 +1,265 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// DefaultJobTable the default table name of the database job store
+const DefaultJobTable = "__yao_task_jobs"
+
+// DBStore the job store using a database connector
+type DBStore struct {
+	Connector connector.Connector
+	Table     string
+}
+
+// NewDBStore create a job store using the database connector, the table is created if not exists
+func NewDBStore(id string, table string) (*DBStore, error) {
+	c, err := connector.Select(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Is(connector.DATABASE) {
+		return nil, fmt.Errorf("the connector %s is not a database connector", id)
+	}
+
+	if table == "" {
+		table = DefaultJobTable
+	}
+
+	db := &DBStore{Connector: c, Table: table}
+	if err := db.ensureTable(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// ensureTable create the job table if not exists
+func (db *DBStore) ensureTable() error {
+	sch, err := db.Connector.Schema()
+	if err != nil {
+		return err
+	}
+
+	has, err := sch.HasTable(db.Table)
+	if err != nil || has {
+		return err
+	}
+
+	return sch.CreateTable(db.Table, func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("task", 200).Index()
+		table.Integer("status").Index()
+		table.Text("args").Null()
+		table.Integer("current").Null()
+		table.Integer("total").Null()
+		table.Text("message").Null()
+		table.Text("response").Null()
+		table.Text("error").Null()
+		table.String("owner", 200).Null()
+		table.Integer("runs").Null()
+		table.Integer("attempts").Null()
+		table.Integer("priority").Null()
+		table.BigInteger("run_at").Null()
+		table.String("unique_key", 200).Null().Index()
+		table.BigInteger("created_at").Null()
+		table.BigInteger("updated_at").Null()
+	})
+}
+
+// NextID reserve the next job id, the reserved row is not listed until the job is saved
+func (db *DBStore) NextID(task string) (int, error) {
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixMilli()
+	id, err := qb.Table(db.Table).InsertGetID(maps.MapStrAny{
+		"task":       task,
+		"status":     0,
+		"unique_key": "",
+		"created_at": now,
+		"updated_at": now,
+	}, "id")
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// Save the job, the job is inserted if the row is not reserved by NextID
+func (db *DBStore) Save(task string, record *Record) error {
+	record.UpdatedAt = time.Now().UnixMilli()
+	row, err := db.row(task, record)
+	if err != nil {
+		return err
+	}
+
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return err
+	}
+
+	// upsert by id, the concurrent saves of a job do not race on the insert
+	columns := []interface{}{}
+	for name := range row {
+		columns = append(columns, name)
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].(string) < columns[j].(string) })
+
+	row["id"] = record.ID
+	_, err = qb.Table(db.Table).Upsert(row, []interface{}{"id"}, columns)
+	return err
+}
+
+// Get the job
+func (db *DBStore) Get(task string, id int) (*Record, error) {
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := qb.Table(db.Table).Where("task", task).Where("id", id).First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row.IsEmpty() {
+		return nil, fmt.Errorf("job %d does not exist", id)
+	}
+	return db.record(row)
+}
+
+// List the jobs
+func (db *DBStore) List(task string, status ...int) ([]*Record, error) {
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	qb = qb.Table(db.Table).Where("task", task)
+	if len(status) > 0 {
+		values := []interface{}{}
+		for _, s := range status {
+			values = append(values, s)
+		}
+		qb = qb.WhereIn("status", values)
+	}
+
+	rows, err := qb.OrderBy("id").Get()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*Record{}
+	for _, row := range rows {
+		record, err := db.record(row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Claim the waiting job
+func (db *DBStore) Claim(task string, id int, owner string) (bool, error) {
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return false, err
+	}
+
+	effect, err := qb.Table(db.Table).
+		Where("task", task).
+		Where("id", id).
+		Where("status", WAITING).
+		Update(maps.MapStrAny{"status": RUNNING, "owner": owner, "updated_at": time.Now().UnixMilli()})
+	if err != nil {
+		return false, err
+	}
+	return effect > 0, nil
+}
+
+// Delete the job
+func (db *DBStore) Delete(task string, id int) error {
+	qb, err := db.Connector.Query()
+	if err != nil {
+		return err
+	}
+	_, err = qb.Table(db.Table).Where("task", task).Where("id", id).Delete()
+	return err
+}
+
+func (db *DBStore) row(task string, record *Record) (maps.MapStrAny, error) {
+	args, err := jsoniter.MarshalToString(record.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := jsoniter.MarshalToString(record.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return maps.MapStrAny{
+		"task":       task,
+		"status":     record.Status,
+		"args":       args,
+		"current":    record.Current,
+		"total":      record.Total,
+		"message":    record.Message,
+		"response":   response,
+		"error":      record.Error,
+		"owner":      record.Owner,
+		"runs":       record.Runs,
+		"attempts":   record.Attempts,
+		"priority":   record.Priority,
+		"run_at":     record.RunAt,
+		"unique_key": record.Unique,
+		"created_at": record.CreatedAt,
+		"updated_at": record.UpdatedAt,
+	}, nil
+}
+
+func (db *DBStore) record(row xun.R) (*Record, error) {
+	record := &Record{
+		ID:        any.Of(row.Get("id")).CInt(),
+		Status:    any.Of(row.Get("status")).CInt(),
+		Current:   any.Of(row.Get("current")).CInt(),
+		Total:     any.Of(row.Get("total")).CInt(),
+		Message:   any.Of(row.Get("message")).CString(),
+		Error:     any.Of(row.Get("error")).CString(),
+		Owner:     any.Of(row.Get("owner")).CString(),
+		Runs:      any.Of(row.Get("runs")).CInt(),
+		Attempts:  any.Of(row.Get("attempts")).CInt(),
+		Priority:  any.Of(row.Get("priority")).CInt(),
+		RunAt:     int64(any.Of(row.Get("run_at")).CInt()),
+		Unique:    any.Of(row.Get("unique_key")).CString(),
+		CreatedAt: int64(any.Of(row.Get("created_at")).CInt()),
+		UpdatedAt: int64(any.Of(row.Get("updated_at")).CInt()),
+	}
+
+	if args := any.Of(row.Get("args")).CString(); args != "" {
+		if err := jsoniter.UnmarshalFromString(args, &record.Args); err != nil {
+			return nil, err
+		}
+	}
+
+	if response := any.Of(row.Get("response")).CString(); response != "" {
+		if err := jsoniter.UnmarshalFromString(response, &record.Response); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
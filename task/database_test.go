@@ -0,0 +1,190 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/connector"
+)
+
+func TestDBStore(t *testing.T) {
+	jobs := prepareDBStore(t)
+
+	id, err := jobs.NextID("unit-test-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Greater(t, id, 0)
+
+	// the reserved row is not listed until the job is saved
+	records, err := jobs.List("unit-test-db", WAITING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, records)
+
+	now := time.Now().UnixMilli()
+	err = jobs.Save("unit-test-db", &Record{ID: id, Status: WAITING, Args: []interface{}{"foo", float64(1)}, Priority: 5, CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := jobs.Get("unit-test-db", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WAITING, record.Status)
+	assert.Equal(t, []interface{}{"foo", float64(1)}, record.Args)
+	assert.Equal(t, 5, record.Priority)
+
+	records, err = jobs.List("unit-test-db", WAITING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{id}, recordIDs(records))
+
+	// only one owner claims the job
+	ok, err := jobs.Claim("unit-test-db", id, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+
+	ok, err = jobs.Claim("unit-test-db", id, "p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, ok)
+
+	record, err = jobs.Get("unit-test-db", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, RUNNING, record.Status)
+	assert.Equal(t, "p1", record.Owner)
+
+	records, _ = jobs.List("unit-test-db", RUNNING)
+	assert.Equal(t, []int{id}, recordIDs(records))
+
+	err = jobs.Delete("unit-test-db", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = jobs.Get("unit-test-db", id)
+	assert.NotNil(t, err)
+}
+
+func TestDBStorePersist(t *testing.T) {
+	jobs := prepareDBStore(t)
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				return fmt.Sprintf("#%d done", id), nil
+			},
+		},
+		Option{Name: "unit-test-db", Timeout: 5, Store: jobs, PollInterval: 100},
+	)
+	go task.Start()
+	defer task.Stop()
+
+	id, err := task.Add("ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	record, err := jobs.Get("unit-test-db", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SUCCESS, record.Status)
+	assert.Equal(t, fmt.Sprintf("#%d done", id), record.Response)
+	assert.Equal(t, []interface{}{"ok"}, record.Args)
+}
+
+func TestDBStoreRecover(t *testing.T) {
+	jobs := prepareDBStore(t)
+	now := time.Now().UnixMilli()
+	ids := []int{}
+	for _, record := range []*Record{
+		{Status: WAITING, Args: []interface{}{"waiting"}, CreatedAt: now},
+		{Status: RUNNING, Args: []interface{}{"running"}, Owner: "unit-test", CreatedAt: now},
+		{Status: RUNNING, Args: []interface{}{"other"}, Owner: "other", CreatedAt: now},
+	} {
+		id, err := jobs.NextID("unit-test-db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		record.ID = id
+		if err := jobs.Save("unit-test-db", record); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	var mu sync.Mutex
+	res := map[int]interface{}{}
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				mu.Lock()
+				res[id] = args[0]
+				mu.Unlock()
+				return nil, nil
+			},
+		},
+		Option{Name: "unit-test-db", Timeout: 5, Store: jobs, Owner: "unit-test", PollInterval: 100},
+	)
+	go task.Start()
+	defer task.Stop()
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "waiting", res[ids[0]])
+	assert.Equal(t, "running", res[ids[1]])
+	assert.Nil(t, res[ids[2]]) // claimed by a running process
+
+	record, err := jobs.Get("unit-test-db", ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SUCCESS, record.Status)
+	assert.Equal(t, 1, record.Runs)
+}
+
+func prepareDBStore(t *testing.T) *DBStore {
+	loadApp(t)
+
+	id := "mysql"
+	if os.Getenv("GOU_TEST_DB_DRIVER") == "sqlite3" {
+		id = "sqlite"
+	}
+
+	_, err := connector.Load(filepath.Join("connectors", fmt.Sprintf("%s.conn.yao", id)), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sch, err := connector.Connectors[id].Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := "__unit_test_task_jobs"
+	if err := sch.DropTableIfExists(table); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := NewDBStore(id, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jobs
+}
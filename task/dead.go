@@ -0,0 +1,195 @@
+package task
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+)
+
+// retry requeue the failed job with the backoff delay, returns false if the attempts are exhausted
+// or the task was stopped.
+func (t *Task) retry(job *Job, err error) bool {
+	if t.ctx.Err() != nil || job.attempts+1 >= t.Option.Attempts {
+		return false
+	}
+
+	job.mu.Lock()
+	args := job.args
+	job.mu.Unlock()
+
+	delay := t.Option.attemptAfter(job.attempts + 1)
+	next := t.newJob(job.id, args, time.Now().Add(delay))
+	next.attempts = job.attempts + 1
+	next.runs = job.runs + 1
+	next.created = job.created
+	next.priority = job.priority
+	next.status = WAITING
+	next.err = err.Error()
+	next.done = job.done
+	if job.unique != "" {
+		if _, has := t.pool.queue.Queued(job.unique); !has {
+			next.unique = job.unique
+		}
+	}
+
+	t.mutex.Lock()
+	t.jobs[job.id] = next
+	t.mutex.Unlock()
+
+	t.save(next)
+	t.pool.queue.Push(next)
+	atomic.AddInt64(&t.metrics.retried, 1)
+	log.Trace("[TASK] %s #%d RETRY %d/%d after %v (%s)", t.name, job.id, next.attempts+1, t.Option.Attempts, delay, err.Error())
+	return true
+}
+
+// attemptAfter the delay before the nth retry
+func (option Option) attemptAfter(n int) time.Duration {
+	delay := time.Duration(option.AttemptAfter) * time.Millisecond
+	switch option.Backoff {
+	case "linear":
+		delay = delay * time.Duration(n)
+
+	case "exponential", "jitter":
+		if n > 30 {
+			n = 30
+		}
+		delay = delay * time.Duration(1<<uint(n-1))
+	}
+
+	if option.MaxAttemptAfter > 0 && delay > time.Duration(option.MaxAttemptAfter)*time.Millisecond {
+		delay = time.Duration(option.MaxAttemptAfter) * time.Millisecond
+	}
+
+	if option.Backoff == "jitter" && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+	return delay
+}
+
+// bury add the failed job to the dead-letter list, the store keeps the failed jobs if the task has a store.
+func (t *Task) bury(job *Job) {
+	if t.store != nil {
+		return
+	}
+
+	record := t.record(job)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.dead = append(t.dead, record)
+	if over := len(t.dead) - t.Option.DeadLetterSize; over > 0 {
+		t.dead = t.dead[over:]
+	}
+}
+
+// Dead returns the dead-letter list, the jobs which failed after exhausting the attempts
+func (t *Task) Dead() ([]*Record, error) {
+	if t.store != nil {
+		return t.store.List(t.name, FAILURE)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	records := make([]*Record, len(t.dead))
+	copy(records, t.dead)
+	return records, nil
+}
+
+// Requeue the dead jobs with the given ids (all dead jobs if no ids), the attempts are reset.
+// returns the number of requeued jobs.
+func (t *Task) Requeue(ids ...int) (int, error) {
+	records, err := t.pick(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, record := range records {
+		record.Status = WAITING
+		record.Attempts = 0
+		record.Error = ""
+		record.Response = nil
+		record.Runs++
+		record.RunAt = 0
+
+		job := t.jobOf(record)
+		t.mutex.Lock()
+		t.jobs[record.ID] = job
+		t.mutex.Unlock()
+		t.save(job)
+		t.pool.queue.Push(job)
+		log.Trace("[TASK] %s #%d REQUEUED", t.name, record.ID)
+	}
+
+	return len(records), nil
+}
+
+// Purge remove the dead jobs with the given ids (all dead jobs if no ids), returns the number of removed jobs.
+func (t *Task) Purge(ids ...int) (int, error) {
+	records, err := t.pick(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if t.store != nil {
+		for _, record := range records {
+			if err := t.store.Delete(t.name, record.ID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(records), nil
+}
+
+// pick remove the dead jobs from the dead-letter list
+func (t *Task) pick(ids []int) ([]*Record, error) {
+	filter := map[int]bool{}
+	for _, id := range ids {
+		filter[id] = true
+	}
+
+	if t.store != nil {
+		records := []*Record{}
+		if len(ids) == 0 {
+			return t.store.List(t.name, FAILURE)
+		}
+
+		for _, id := range ids {
+			record, err := t.store.Get(t.name, id)
+			if err != nil {
+				return nil, err
+			}
+			if record.Status != FAILURE {
+				return nil, fmt.Errorf("job %d is not in the dead-letter list", id)
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	picked := []*Record{}
+	remains := []*Record{}
+	for _, record := range t.dead {
+		if len(ids) == 0 || filter[record.ID] {
+			picked = append(picked, record)
+			delete(filter, record.ID)
+			continue
+		}
+		remains = append(remains, record)
+	}
+
+	if len(filter) > 0 {
+		missing := []int{}
+		for id := range filter {
+			missing = append(missing, id)
+		}
+		return nil, fmt.Errorf("jobs %v are not in the dead-letter list", missing)
+	}
+
+	t.dead = remains
+	return picked, nil
+}
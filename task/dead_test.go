@@ -0,0 +1,141 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[int]int{}
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				mu.Lock()
+				calls[id]++
+				n := calls[id]
+				mu.Unlock()
+				if n < 3 {
+					return nil, fmt.Errorf("attempt %d failed", n)
+				}
+				return "done", nil
+			},
+		},
+		Option{Name: "unit-test-dead", Timeout: 5, Attempts: 3, AttemptAfter: 20, Backoff: "exponential"},
+	)
+	go task.Start()
+	defer task.Stop()
+
+	id, err := task.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := task.Wait(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUCCESS", res["status"])
+	assert.Equal(t, "done", res["response"])
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, calls[id])
+}
+
+func TestDeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	fail := true
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if fail {
+					return nil, fmt.Errorf("#%d failed", id)
+				}
+				return "done", nil
+			},
+		},
+		Option{Name: "unit-test-dead", Timeout: 5, Attempts: 2, AttemptAfter: 10},
+	)
+	go task.Start()
+	defer task.Stop()
+
+	id, _ := task.Add("a")
+	res, err := task.Wait(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "FAILURE", res["status"])
+
+	id2, _ := task.Add("b")
+	task.Wait(context.Background(), id2)
+
+	dead, err := task.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(dead))
+	assert.Equal(t, []interface{}{"a"}, dead[0].Args)
+	assert.Equal(t, 1, dead[0].Attempts)
+	assert.Equal(t, fmt.Sprintf("#%d failed", id), dead[0].Error)
+
+	_, err = task.Requeue(id, 99)
+	assert.NotNil(t, err)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	n, err := task.Requeue(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, n)
+
+	res, err = task.Wait(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUCCESS", res["status"])
+
+	n, err = task.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, n)
+
+	dead, err = task.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(dead))
+}
+
+func TestAttemptAfter(t *testing.T) {
+	option := Option{AttemptAfter: 100}
+	assert.Equal(t, 100*time.Millisecond, option.attemptAfter(3))
+
+	option.Backoff = "linear"
+	assert.Equal(t, 300*time.Millisecond, option.attemptAfter(3))
+
+	option.Backoff = "exponential"
+	assert.Equal(t, 100*time.Millisecond, option.attemptAfter(1))
+	assert.Equal(t, 400*time.Millisecond, option.attemptAfter(3))
+
+	option.MaxAttemptAfter = 250
+	assert.Equal(t, 250*time.Millisecond, option.attemptAfter(3))
+
+	option.Backoff = "jitter"
+	for i := 0; i < 10; i++ {
+		delay := option.attemptAfter(3)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, 250*time.Millisecond)
+	}
+}
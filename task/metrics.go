@@ -0,0 +1,119 @@
+package task
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// metrics the task counters
+type metrics struct {
+	running   int64
+	succeeded int64
+	failed    int64
+	retried   int64
+	canceled  int64
+	completed int64
+	duration  int64 // the total duration (ns) of the completed jobs
+}
+
+// Stats the task statistics
+type Stats struct {
+	Name        string  `json:"name"`
+	Queued      int     `json:"queued"`
+	Running     int     `json:"running"`
+	Succeeded   int64   `json:"succeeded"`
+	Failed      int64   `json:"failed"`
+	Retried     int64   `json:"retried"`
+	Canceled    int64   `json:"canceled"`
+	AvgDuration float64 `json:"avg_duration"` // the average duration (ms) of the completed jobs
+	Workers     int     `json:"workers"`
+	Utilization float64 `json:"utilization"` // the ratio of the busy workers
+	Paused      bool    `json:"paused"`
+}
+
+// Stats returns the task statistics
+func (t *Task) Stats() Stats {
+	stats := Stats{
+		Name:      t.name,
+		Queued:    t.pool.queue.Len(),
+		Running:   int(atomic.LoadInt64(&t.metrics.running)),
+		Succeeded: atomic.LoadInt64(&t.metrics.succeeded),
+		Failed:    atomic.LoadInt64(&t.metrics.failed),
+		Retried:   atomic.LoadInt64(&t.metrics.retried),
+		Canceled:  atomic.LoadInt64(&t.metrics.canceled),
+		Workers:   t.pool.size,
+		Paused:    t.Paused(),
+	}
+
+	if completed := atomic.LoadInt64(&t.metrics.completed); completed > 0 {
+		avg := time.Duration(atomic.LoadInt64(&t.metrics.duration) / completed)
+		stats.AvgDuration = float64(avg) / float64(time.Millisecond)
+	}
+
+	if stats.Workers > 0 {
+		stats.Utilization = float64(stats.Running) / float64(stats.Workers)
+	}
+	return stats
+}
+
+// Metrics returns the metrics of the loaded tasks in the Prometheus text format
+func Metrics() string {
+	tasksMu.RLock()
+	names := []string{}
+	for name := range Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := []Stats{}
+	for _, name := range names {
+		stats = append(stats, Tasks[name].Stats())
+	}
+	tasksMu.RUnlock()
+
+	metrics := []struct {
+		name  string
+		typ   string
+		help  string
+		value func(Stats) interface{}
+	}{
+		{"yao_task_jobs_queued", "gauge", "The number of queued jobs", func(s Stats) interface{} { return s.Queued }},
+		{"yao_task_jobs_running", "gauge", "The number of running jobs", func(s Stats) interface{} { return s.Running }},
+		{"yao_task_jobs_succeeded_total", "counter", "The number of succeeded jobs", func(s Stats) interface{} { return s.Succeeded }},
+		{"yao_task_jobs_failed_total", "counter", "The number of failed jobs", func(s Stats) interface{} { return s.Failed }},
+		{"yao_task_jobs_retried_total", "counter", "The number of retried attempts", func(s Stats) interface{} { return s.Retried }},
+		{"yao_task_jobs_canceled_total", "counter", "The number of canceled jobs", func(s Stats) interface{} { return s.Canceled }},
+		{"yao_task_job_duration_avg_milliseconds", "gauge", "The average duration of the completed jobs", func(s Stats) interface{} { return s.AvgDuration }},
+		{"yao_task_workers", "gauge", "The number of workers", func(s Stats) interface{} { return s.Workers }},
+		{"yao_task_worker_utilization", "gauge", "The ratio of the busy workers", func(s Stats) interface{} { return s.Utilization }},
+		{"yao_task_paused", "gauge", "Whether the task is paused", func(s Stats) interface{} {
+			if s.Paused {
+				return 1
+			}
+			return 0
+		}},
+	}
+
+	var b strings.Builder
+	for _, metric := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", metric.name, metric.typ)
+		for _, s := range stats {
+			fmt.Fprintf(&b, "%s{task=%q} %v\n", metric.name, s.Name, metric.value(s))
+		}
+	}
+	return b.String()
+}
+
+// MetricsHandler the Prometheus metrics handler of the loaded tasks,
+// mount it with gin.WrapH(task.MetricsHandler()) or http.Handle.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(Metrics()))
+	})
+}
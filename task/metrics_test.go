@@ -0,0 +1,138 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	task := prepareMetricsTask(t)
+	go task.Start()
+	defer task.Stop()
+
+	id, _ := task.Add(50)
+	id2, _ := task.Add("fail")
+	task.Wait(context.Background(), id)
+	task.Wait(context.Background(), id2)
+
+	stats := task.Stats()
+	assert.Equal(t, "unit-test-metrics", stats.Name)
+	assert.Equal(t, int64(1), stats.Succeeded)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, 0, stats.Queued)
+	assert.Equal(t, 2, stats.Workers)
+	assert.Greater(t, stats.AvgDuration, float64(0))
+
+	id3, _ := task.Add(300)
+	time.Sleep(50 * time.Millisecond)
+	stats = task.Stats()
+	assert.Equal(t, 1, stats.Running)
+	assert.Equal(t, 0.5, stats.Utilization)
+	task.Wait(context.Background(), id3)
+}
+
+func TestPauseCancel(t *testing.T) {
+	task := prepareMetricsTask(t)
+	go task.Start()
+	defer task.Stop()
+
+	task.Pause()
+	id, _ := task.Add(10)
+	id2, _ := task.Add(10)
+	time.Sleep(50 * time.Millisecond)
+
+	jobs, err := task.List(WAITING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(jobs))
+	assert.True(t, task.Stats().Paused)
+
+	// cancel the queued job
+	done := waitJob(task, id)
+	err = task.Cancel(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := <-done
+	assert.Equal(t, "CANCELED", res["status"])
+
+	task.Resume()
+	res, err = task.Wait(context.Background(), id2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUCCESS", res["status"])
+
+	// cancel the running job
+	id3, _ := task.Add(1000)
+	time.Sleep(50 * time.Millisecond)
+	done = waitJob(task, id3)
+	err = task.Cancel(id3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = <-done
+	assert.Equal(t, "CANCELED", res["status"])
+	assert.Equal(t, int64(2), task.Stats().Canceled)
+	assert.NotNil(t, task.Cancel(id3))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	task := prepareMetricsTask(t)
+	tasksMu.Lock()
+	Tasks["unit-test-metrics"] = task
+	tasksMu.Unlock()
+	defer func() {
+		tasksMu.Lock()
+		delete(Tasks, "unit-test-metrics")
+		tasksMu.Unlock()
+	}()
+
+	go task.Start()
+	defer task.Stop()
+	id, _ := task.Add(10)
+	task.Wait(context.Background(), id)
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), "# TYPE yao_task_jobs_succeeded_total counter")
+	assert.Contains(t, w.Body.String(), `yao_task_jobs_succeeded_total{task="unit-test-metrics"} 1`)
+	assert.Contains(t, w.Body.String(), `yao_task_workers{task="unit-test-metrics"} 2`)
+}
+
+func waitJob(task *Task, id int) chan map[string]interface{} {
+	task.mutex.Lock()
+	job := task.jobs[id]
+	task.mutex.Unlock()
+
+	done := make(chan map[string]interface{}, 1)
+	go func() {
+		<-job.done
+		done <- job.result()
+	}()
+	return done
+}
+
+func prepareMetricsTask(t *testing.T) *Task {
+	return New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				ms, ok := args[0].(int)
+				if !ok {
+					return nil, fmt.Errorf("#%d failed", id)
+				}
+				time.Sleep(time.Duration(ms) * time.Millisecond)
+				return ms, nil
+			},
+		},
+		Option{Name: "unit-test-metrics", WorkerNums: 2, Timeout: 5},
+	)
+}
@@ -1,19 +1,34 @@
 package task
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/store"
 	"github.com/yaoapp/kun/any"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
 )
 
 // TaskHandlers task process handlers
 var TaskHandlers = map[string]process.Handler{
 	"add":      processTaskAdd,
+	"addwith":  processTaskAddWith,
 	"progress": processTaskProgress,
 	"get":      processTaskGet,
+	"dead":     processTaskDead,
+	"requeue":  processTaskRequeue,
+	"purge":    processTaskPurge,
+	"stats":    processTaskStats,
+	"list":     processTaskList,
+	"cancel":   processTaskCancel,
+	"pause":    processTaskPause,
+	"resume":   processTaskResume,
 }
 
 func init() {
@@ -22,14 +37,22 @@ func init() {
 
 // ProcessOption the task process option
 type ProcessOption struct {
-	Name         string      `json:"name"`
-	Process      string      `json:"process"`
-	Size         interface{} `json:"size,omitempty"`
-	WorkerNums   interface{} `json:"worker_nums,omitempty"`
-	AttemptAfter interface{} `json:"attempt_after,omitempty"`
-	Attempts     interface{} `json:"attempts,omitempty"`
-	Timeout      interface{} `json:"timeout,omitempty"`
-	Event        struct {
+	Name            string      `json:"name"`
+	Process         string      `json:"process"`
+	Size            interface{} `json:"size,omitempty"`
+	WorkerNums      interface{} `json:"worker_nums,omitempty"`
+	AttemptAfter    interface{} `json:"attempt_after,omitempty"`
+	Attempts        interface{} `json:"attempts,omitempty"`
+	Backoff         string      `json:"backoff,omitempty"` // fixed, linear, exponential, jitter
+	MaxAttemptAfter interface{} `json:"max_attempt_after,omitempty"`
+	DeadLetterSize  interface{} `json:"dead_letter_size,omitempty"`
+	Timeout         interface{} `json:"timeout,omitempty"`
+	Store           string      `json:"store,omitempty"`     // the job store using a store.Store
+	Connector       string      `json:"connector,omitempty"` // the job store using a database connector
+	Table           string      `json:"table,omitempty"`     // the table of the database job store
+	Poll            interface{} `json:"poll,omitempty"`      // the interval (ms) to pull the waiting jobs
+	Retention       interface{} `json:"retention,omitempty"` // the time (s) to keep the succeeded and canceled jobs in the store, 0 keeps them
+	Event           struct {
 		Next     string `json:"next,omitempty"`
 		Add      string `json:"add,omitempty"`
 		Success  string `json:"success,omitempty"`
@@ -53,12 +76,21 @@ func Load(file string, name string) (*Task, error) {
 	}
 
 	option := Option{
-		Name:           name,
-		Timeout:        helper.EnvInt(o.Timeout),
-		WorkerNums:     helper.EnvInt(o.WorkerNums),
-		JobQueueLength: helper.EnvInt(o.Size),
-		AttemptAfter:   helper.EnvInt(o.AttemptAfter),
-		Attempts:       helper.EnvInt(o.Attempts),
+		Name:            name,
+		Timeout:         helper.EnvInt(o.Timeout),
+		WorkerNums:      helper.EnvInt(o.WorkerNums),
+		JobQueueLength:  helper.EnvInt(o.Size),
+		AttemptAfter:    helper.EnvInt(o.AttemptAfter),
+		Attempts:        helper.EnvInt(o.Attempts),
+		Backoff:         o.Backoff,
+		MaxAttemptAfter: helper.EnvInt(o.MaxAttemptAfter),
+		DeadLetterSize:  helper.EnvInt(o.DeadLetterSize),
+		PollInterval:    helper.EnvInt(o.Poll),
+	}
+
+	option.Store, err = jobStore(o)
+	if err != nil {
+		return nil, err
 	}
 
 	handlers := taskEventHandlers(name, o)
@@ -70,6 +102,40 @@ func Load(file string, name string) (*Task, error) {
 	return t, nil
 }
 
+// jobStore create the job persistence backend of the task
+func jobStore(o ProcessOption) (JobStore, error) {
+	if o.Store != "" {
+		stor, err := store.Get(o.Store)
+		if err != nil {
+			return nil, err
+		}
+		kv := NewKVStore(stor)
+		kv.Retention = time.Duration(helper.EnvInt(o.Retention)) * time.Second
+		return kv, nil
+	}
+
+	if o.Connector != "" {
+		db, err := NewDBStore(o.Connector, o.Table)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+
+	return nil, nil
+}
+
+// Get get the loaded task by name
+func Get(name string) (*Task, error) {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	t, has := Tasks[name]
+	if !has {
+		return nil, fmt.Errorf("task %s does not load", name)
+	}
+	return t, nil
+}
+
 // Select select task by name
 func Select(name string) *Task {
 	tasksMu.RLock()
@@ -81,20 +147,42 @@ func Select(name string) *Task {
 	return t
 }
 
-// processTaskAdd
+// processTaskAdd tasks.<name>.Add(args...) or tasks.<name>.Add({"$option": {...}}, args...)
 func processTaskAdd(process *process.Process) interface{} {
 	t := Select(process.ID)
 	args := []interface{}{}
 	if process.NumOfArgs() > 0 {
 		args = process.Args
 	}
-	v, err := t.Add(args...)
+
+	option, args, err := addOptionOf(args)
+	if err != nil {
+		exception.New("Task %s Add: %s", 400, process.ID, err).Throw()
+	}
+
+	v, err := t.AddWith(option, args...)
 	if err != nil {
 		exception.New("Task %s Add: %s", 500, process.ID, err).Throw()
 	}
 	return v
 }
 
+// processTaskAddWith tasks.<name>.AddWith(option, args...)
+func processTaskAddWith(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	t := Select(process.ID)
+	option, err := ParseAddOption(process.Args[0])
+	if err != nil {
+		exception.New("Task %s AddWith: %s", 400, process.ID, err).Throw()
+	}
+
+	v, err := t.AddWith(option, process.Args[1:]...)
+	if err != nil {
+		exception.New("Task %s AddWith: %s", 500, process.ID, err).Throw()
+	}
+	return v
+}
+
 // processTaskProgress
 func processTaskProgress(process *process.Process) interface{} {
 	process.ValidateArgNums(4)
@@ -122,6 +210,200 @@ func processTaskGet(process *process.Process) interface{} {
 	return job
 }
 
+// processTaskDead tasks.<name>.Dead() list the dead-letter jobs
+func processTaskDead(process *process.Process) interface{} {
+	t := Select(process.ID)
+	records, err := t.Dead()
+	if err != nil {
+		exception.New("Task %s Dead: %s", 500, process.ID, err).Throw()
+	}
+	return records
+}
+
+// processTaskRequeue tasks.<name>.Requeue(ids...) requeue the dead-letter jobs, all if no ids
+func processTaskRequeue(process *process.Process) interface{} {
+	t := Select(process.ID)
+	n, err := t.Requeue(processTaskIDs(process)...)
+	if err != nil {
+		exception.New("Task %s Requeue: %s", 500, process.ID, err).Throw()
+	}
+	return n
+}
+
+// processTaskPurge tasks.<name>.Purge(ids...) remove the dead-letter jobs, all if no ids
+func processTaskPurge(process *process.Process) interface{} {
+	t := Select(process.ID)
+	n, err := t.Purge(processTaskIDs(process)...)
+	if err != nil {
+		exception.New("Task %s Purge: %s", 500, process.ID, err).Throw()
+	}
+	return n
+}
+
+// processTaskStats tasks.<name>.Stats() the task statistics
+func processTaskStats(process *process.Process) interface{} {
+	return Select(process.ID).Stats()
+}
+
+// processTaskList tasks.<name>.List(status...) list the jobs, status: WAITING, RUNNING, SUCCESS, FAILURE, CANCELED
+func processTaskList(process *process.Process) interface{} {
+	t := Select(process.ID)
+	filter := []int{}
+	for _, arg := range process.Args {
+		code, err := statusOf(arg)
+		if err != nil {
+			exception.New("Task %s List: %s", 400, process.ID, err).Throw()
+		}
+		filter = append(filter, code)
+	}
+
+	jobs, err := t.List(filter...)
+	if err != nil {
+		exception.New("Task %s List: %s", 500, process.ID, err).Throw()
+	}
+	return jobs
+}
+
+// processTaskCancel tasks.<name>.Cancel(id) cancel the queued or running job
+func processTaskCancel(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	t := Select(process.ID)
+	err := t.Cancel(process.ArgsInt(0))
+	if err != nil {
+		exception.New("Task %s Cancel: %s", 500, process.ID, err).Throw()
+	}
+	return nil
+}
+
+// processTaskPause tasks.<name>.Pause() stop dispatching the queued jobs
+func processTaskPause(process *process.Process) interface{} {
+	Select(process.ID).Pause()
+	return nil
+}
+
+// processTaskResume tasks.<name>.Resume() resume dispatching the queued jobs
+func processTaskResume(process *process.Process) interface{} {
+	Select(process.ID).Resume()
+	return nil
+}
+
+// statusOf the status code of the status name or code
+func statusOf(v interface{}) (int, error) {
+	if name, ok := v.(string); ok {
+		for code, label := range status {
+			if strings.EqualFold(label, name) {
+				return code, nil
+			}
+		}
+		return 0, fmt.Errorf("the status %s is not supported", name)
+	}
+	return any.Of(v).CInt(), nil
+}
+
+// processTaskIDs the job ids of the args, accepts ids or an array of ids
+func processTaskIDs(process *process.Process) []int {
+	ids := []int{}
+	for _, arg := range process.Args {
+		if values, ok := arg.([]interface{}); ok {
+			for _, v := range values {
+				ids = append(ids, any.Of(v).CInt())
+			}
+			continue
+		}
+		ids = append(ids, any.Of(arg).CInt())
+	}
+	return ids
+}
+
+// addOptionOf split the leading {"$option": {...}} marker from the add args
+func addOptionOf(args []interface{}) (AddOption, []interface{}, error) {
+	if len(args) == 0 {
+		return AddOption{}, args, nil
+	}
+
+	var values map[string]interface{}
+	switch value := args[0].(type) {
+	case map[string]interface{}:
+		values = value
+	case maps.MapStrAny:
+		values = value
+	}
+
+	v, has := values["$option"]
+	if !has || len(values) != 1 {
+		return AddOption{}, args, nil
+	}
+
+	option, err := ParseAddOption(v)
+	if err != nil {
+		return option, nil, fmt.Errorf("$option: %s", err.Error())
+	}
+	return option, args[1:], nil
+}
+
+// ParseAddOption parse the add option from a map
+// {"priority": 10, "at": "2006-01-02T15:04:05Z07:00" | unix seconds, "delay": ms | "5m", "unique": "key", "duplicate": "drop|merge"}
+func ParseAddOption(v interface{}) (AddOption, error) {
+	option := AddOption{}
+	if v == nil {
+		return option, nil
+	}
+
+	var values map[string]interface{}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		values = value
+	case maps.MapStrAny:
+		values = value
+	default:
+		return option, fmt.Errorf("the option should be an object")
+	}
+
+	if priority, has := values["priority"]; has {
+		option.Priority = any.Of(priority).CInt()
+	}
+
+	if at, has := values["at"]; has && at != nil {
+		switch value := at.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return option, fmt.Errorf("at: %s", err.Error())
+			}
+			option.At = t
+		case time.Time:
+			option.At = value
+		default:
+			option.At = time.Unix(int64(any.Of(value).CInt()), 0)
+		}
+	}
+
+	if delay, has := values["delay"]; has && delay != nil {
+		if value, ok := delay.(string); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return option, fmt.Errorf("delay: %s", err.Error())
+			}
+			option.Delay = d
+		} else {
+			option.Delay = time.Duration(any.Of(delay).CInt()) * time.Millisecond
+		}
+	}
+
+	if unique, has := values["unique"]; has && unique != nil {
+		option.Unique = fmt.Sprintf("%v", unique)
+	}
+
+	if duplicate, has := values["duplicate"]; has && duplicate != nil {
+		option.Duplicate = fmt.Sprintf("%v", duplicate)
+		if option.Duplicate != "drop" && option.Duplicate != "merge" {
+			return option, fmt.Errorf("duplicate: %s is not supported (drop, merge)", option.Duplicate)
+		}
+	}
+
+	return option, nil
+}
+
 func taskEventHandlers(name string, o ProcessOption) *Handlers {
 	handlers := &Handlers{
 		Exec: func(id int, args ...interface{}) (interface{}, error) {
@@ -0,0 +1,196 @@
+package task
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Queue the job queue, the due jobs are ordered by priority (higher first) and then by the order of adding,
+// the delayed jobs are held until they are due.
+type Queue struct {
+	ready   *jobHeap
+	delayed *jobHeap
+	unique  map[string]*Job // the queued jobs with a unique key
+	seq     int64
+	notify  chan struct{}
+	mutex   sync.Mutex
+}
+
+type jobHeap struct {
+	jobs []*Job
+	less func(a, b *Job) bool
+}
+
+// NewQueue create a new job queue
+func NewQueue() *Queue {
+	return &Queue{
+		ready: &jobHeap{less: func(a, b *Job) bool {
+			if a.priority != b.priority {
+				return a.priority > b.priority
+			}
+			return a.seq < b.seq
+		}},
+		delayed: &jobHeap{less: func(a, b *Job) bool {
+			if !a.runAt.Equal(b.runAt) {
+				return a.runAt.Before(b.runAt)
+			}
+			return a.seq < b.seq
+		}},
+		unique: map[string]*Job{},
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Push a job to the queue
+func (q *Queue) Push(job *Job) {
+	q.mutex.Lock()
+	q.seq++
+	job.seq = q.seq
+	if job.runAt.After(time.Now()) {
+		heap.Push(q.delayed, job)
+	} else {
+		heap.Push(q.ready, job)
+	}
+
+	if job.unique != "" {
+		q.unique[job.unique] = job
+	}
+	q.mutex.Unlock()
+	q.wake()
+}
+
+// Pop the due job with the highest priority, returns nil and the duration to wait for the next delayed job
+// (-1 if there are no delayed jobs) if no job is due.
+func (q *Queue) Pop() (*Job, time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	for q.delayed.Len() > 0 && !q.delayed.jobs[0].runAt.After(now) {
+		heap.Push(q.ready, heap.Pop(q.delayed))
+	}
+
+	if q.ready.Len() == 0 {
+		if q.delayed.Len() == 0 {
+			return nil, -1
+		}
+		return nil, q.delayed.jobs[0].runAt.Sub(now)
+	}
+
+	job := heap.Pop(q.ready).(*Job)
+	if job.unique != "" && q.unique[job.unique] == job {
+		delete(q.unique, job.unique)
+	}
+	return job, 0
+}
+
+// Ready checks if there is a due job
+func (q *Queue) Ready() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.ready.Len() > 0 {
+		return true
+	}
+	return q.delayed.Len() > 0 && !q.delayed.jobs[0].runAt.After(time.Now())
+}
+
+// Wait returns the duration to wait for the next delayed job, -1 if there are no delayed jobs
+func (q *Queue) Wait() time.Duration {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.delayed.Len() == 0 {
+		return -1
+	}
+	return time.Until(q.delayed.jobs[0].runAt)
+}
+
+// Queued returns the queued job with the unique key
+func (q *Queue) Queued(unique string) (*Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	job, has := q.unique[unique]
+	return job, has
+}
+
+// Merge the args and the priority to the queued job, returns false if the job is not queued
+func (q *Queue) Merge(job *Job, priority int, args []interface{}) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.unique[job.unique] != job {
+		return false
+	}
+
+	job.mu.Lock()
+	job.args = args
+	raise := priority > job.priority
+	if raise {
+		job.priority = priority
+	}
+	job.mu.Unlock()
+
+	if raise {
+		if job.index >= 0 && job.index < q.ready.Len() && q.ready.jobs[job.index] == job {
+			heap.Fix(q.ready, job.index)
+		}
+	}
+	return true
+}
+
+// Remove the queued job, returns false if the job is not queued
+func (q *Queue) Remove(job *Job) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	removed := false
+	for _, h := range []*jobHeap{q.ready, q.delayed} {
+		if job.index >= 0 && job.index < h.Len() && h.jobs[job.index] == job {
+			heap.Remove(h, job.index)
+			removed = true
+			break
+		}
+	}
+
+	if removed && job.unique != "" && q.unique[job.unique] == job {
+		delete(q.unique, job.unique)
+	}
+	return removed
+}
+
+// wake the dispatcher
+func (q *Queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of queued jobs
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.ready.Len() + q.delayed.Len()
+}
+
+func (h *jobHeap) Len() int           { return len(h.jobs) }
+func (h *jobHeap) Less(i, j int) bool { return h.less(h.jobs[i], h.jobs[j]) }
+func (h *jobHeap) Swap(i, j int) {
+	h.jobs[i], h.jobs[j] = h.jobs[j], h.jobs[i]
+	h.jobs[i].index = i
+	h.jobs[j].index = j
+}
+
+func (h *jobHeap) Push(x interface{}) {
+	job := x.(*Job)
+	job.index = len(h.jobs)
+	h.jobs = append(h.jobs, job)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	n := len(h.jobs)
+	job := h.jobs[n-1]
+	h.jobs[n-1] = nil
+	h.jobs = h.jobs[:n-1]
+	job.index = -1
+	return job
+}
@@ -0,0 +1,167 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddPriority(t *testing.T) {
+	task, order, mu := prepareQueueTask(t)
+	task.Add("low")
+	task.AddWith(AddOption{Priority: 10}, "high")
+	task.AddWith(AddOption{Priority: 5}, "medium")
+	task.Add("low-2")
+
+	go task.Start()
+	defer task.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []interface{}{"high", "medium", "low", "low-2"}, *order)
+}
+
+func TestAddDelay(t *testing.T) {
+	task, order, mu := prepareQueueTask(t)
+	go task.Start()
+	defer task.Stop()
+
+	task.AddAfter(300*time.Millisecond, "after")
+	task.AddAt(time.Now().Add(150*time.Millisecond), "at")
+	task.Add("now")
+
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []interface{}{"now"}, *order)
+	mu.Unlock()
+
+	time.Sleep(400 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []interface{}{"now", "at", "after"}, *order)
+	mu.Unlock()
+}
+
+func TestAddUnique(t *testing.T) {
+	task, order, mu := prepareQueueTask(t)
+	id, err := task.AddWith(AddOption{Unique: "mail"}, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id2, err := task.AddWith(AddOption{Unique: "mail"}, "dropped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, id2)
+
+	id3, err := task.AddWith(AddOption{Unique: "sms"}, "sms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id4, err := task.AddWith(AddOption{Unique: "sms", Duplicate: "merge", Priority: 10}, "merged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id3, id4)
+
+	_, err = task.AddWith(AddOption{Unique: "sms", Duplicate: "replace"}, "sms")
+	assert.NotNil(t, err)
+
+	go task.Start()
+	defer task.Stop()
+
+	res, err := task.Wait(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUCCESS", res["status"])
+
+	// the job was dequeued, a new job is added
+	_, err = task.AddWith(AddOption{Unique: "mail"}, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []interface{}{"merged", "first", "second"}, *order)
+}
+
+func TestParseAddOption(t *testing.T) {
+	option, err := ParseAddOption(map[string]interface{}{
+		"priority":  5,
+		"at":        "2026-01-02T15:04:05Z",
+		"delay":     "5m",
+		"unique":    "mail",
+		"duplicate": "merge",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, option.Priority)
+	assert.Equal(t, int64(1767366245), option.At.Unix())
+	assert.Equal(t, 5*time.Minute, option.Delay)
+	assert.Equal(t, "mail", option.Unique)
+	assert.Equal(t, "merge", option.Duplicate)
+
+	option, err = ParseAddOption(map[string]interface{}{"delay": 1500, "at": 1767366245})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1500*time.Millisecond, option.Delay)
+	assert.Equal(t, int64(1767366245), option.At.Unix())
+
+	_, err = ParseAddOption(map[string]interface{}{"duplicate": "replace"})
+	assert.NotNil(t, err)
+
+	_, err = ParseAddOption("priority")
+	assert.NotNil(t, err)
+}
+
+func TestAddOptionOf(t *testing.T) {
+	option, args, err := addOptionOf([]interface{}{
+		map[string]interface{}{"$option": map[string]interface{}{"priority": 5, "unique": "mail"}},
+		"foo", 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, option.Priority)
+	assert.Equal(t, "mail", option.Unique)
+	assert.Equal(t, []interface{}{"foo", 1}, args)
+
+	// an object with other keys is a job arg
+	arg := map[string]interface{}{"$option": "foo", "name": "bar"}
+	option, args, err = addOptionOf([]interface{}{arg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, AddOption{}, option)
+	assert.Equal(t, []interface{}{arg}, args)
+
+	_, _, err = addOptionOf([]interface{}{map[string]interface{}{"$option": map[string]interface{}{"duplicate": "replace"}}})
+	assert.NotNil(t, err)
+}
+
+func prepareQueueTask(t *testing.T) (*Task, *[]interface{}, *sync.Mutex) {
+	var mu sync.Mutex
+	order := []interface{}{}
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				mu.Lock()
+				order = append(order, args[0])
+				mu.Unlock()
+				return args[0], nil
+			},
+		},
+		Option{Name: "unit-test-queue", WorkerNums: 1, Timeout: 5},
+	)
+	return task, &order, &mu
+}
@@ -0,0 +1,215 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/any"
+)
+
+// JobStore the job persistence backend. The tasks using the same backend share the job queue,
+// a job is executed by the process that claims it.
+type JobStore interface {
+	NextID(task string) (int, error)
+	Save(task string, record *Record) error
+	Get(task string, id int) (*Record, error)
+	List(task string, status ...int) ([]*Record, error) // ordered by id
+	Claim(task string, id int, owner string) (bool, error)
+	Delete(task string, id int) error
+}
+
+// Record the persisted job
+type Record struct {
+	ID        int           `json:"id"`
+	Status    int           `json:"status"`
+	Args      []interface{} `json:"args"`
+	Current   int           `json:"current"`
+	Total     int           `json:"total"`
+	Message   string        `json:"message,omitempty"`
+	Response  interface{}   `json:"response,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Owner     string        `json:"owner,omitempty"` // the process which claimed the job
+	Runs      int           `json:"runs"`            // the times the job was queued
+	Attempts  int           `json:"attempts"`        // the failed attempts
+	Priority  int           `json:"priority,omitempty"`
+	RunAt     int64         `json:"run_at,omitempty"` // the time (ms) to run the delayed job
+	Unique    string        `json:"unique,omitempty"`
+	CreatedAt int64         `json:"created_at"`
+	UpdatedAt int64         `json:"updated_at"`
+}
+
+// KVStore the job store using a store.Store (lru, redis, mongo, xun).
+// The job ids are indexed by status, so polling the waiting jobs does not scan the finished ones.
+type KVStore struct {
+	Store     store.Store
+	Prefix    string
+	Retention time.Duration // the time to keep the succeeded and canceled jobs, 0 keeps them. The failed jobs are kept as the dead letters
+}
+
+// statuses the job statuses indexed by the KVStore
+var statuses = []int{WAITING, RUNNING, SUCCESS, FAILURE, CANCELED}
+
+// NewKVStore create a job store using the store.Store
+func NewKVStore(stor store.Store) *KVStore {
+	return &KVStore{Store: stor, Prefix: "task:"}
+}
+
+// NextID get the next job id
+func (kv *KVStore) NextID(task string) (int, error) {
+	id, err := kv.Store.Incr(kv.key(task, "id"), 1)
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// Save the job and move it to the index of its status
+func (kv *KVStore) Save(task string, record *Record) error {
+	record.UpdatedAt = time.Now().UnixMilli()
+	bytes, err := jsoniter.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if record.Status == SUCCESS || record.Status == CANCELED {
+		ttl = kv.Retention
+	}
+
+	if err := kv.Store.Set(kv.key(task, "job", record.ID), string(bytes), ttl); err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status == record.Status {
+			continue
+		}
+		if err := kv.Store.Pull(kv.key(task, "status", status), record.ID); err != nil {
+			return err
+		}
+	}
+	return kv.Store.AddToSet(kv.key(task, "status", record.Status), record.ID)
+}
+
+// Get the job
+func (kv *KVStore) Get(task string, id int) (*Record, error) {
+	value, has := kv.Store.Get(kv.key(task, "job", id))
+	if !has {
+		return nil, fmt.Errorf("job %d does not exist", id)
+	}
+	return kv.record(value)
+}
+
+// List the jobs, the jobs are read from the status indexes
+func (kv *KVStore) List(task string, status ...int) ([]*Record, error) {
+	if len(status) == 0 {
+		status = statuses
+	}
+
+	filter := map[int]bool{}
+	for _, s := range status {
+		filter[s] = true
+	}
+
+	records := []*Record{}
+	listed := map[int]bool{}
+	for s := range filter {
+		index := kv.key(task, "status", s)
+		ids, err := kv.Store.ArrayAll(index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range ids {
+			id := any.Of(item).CInt()
+			if listed[id] {
+				continue
+			}
+
+			value, has := kv.Store.Get(kv.key(task, "job", id))
+			if !has {
+				// the job was deleted or expired
+				kv.Store.Pull(index, item)
+				kv.Store.Del(kv.key(task, "claim", id, "*"))
+				continue
+			}
+
+			record, err := kv.record(value)
+			if err != nil {
+				return nil, err
+			}
+
+			// the job is moving to another status
+			if !filter[record.Status] {
+				continue
+			}
+			listed[id] = true
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+// Claim the waiting job, only the first claim of each run succeeds
+func (kv *KVStore) Claim(task string, id int, owner string) (bool, error) {
+	record, err := kv.Get(task, id)
+	if err != nil {
+		return false, err
+	}
+
+	if record.Status != WAITING {
+		return false, nil
+	}
+
+	n, err := kv.Store.Incr(kv.key(task, "claim", id, record.Runs), 1)
+	if err != nil || n != 1 {
+		return false, err
+	}
+
+	record.Status = RUNNING
+	record.Owner = owner
+	return true, kv.Save(task, record)
+}
+
+// Delete the job
+func (kv *KVStore) Delete(task string, id int) error {
+	kv.Store.Del(kv.key(task, "claim", id, "*"))
+	for _, status := range statuses {
+		kv.Store.Pull(kv.key(task, "status", status), id)
+	}
+	return kv.Store.Del(kv.key(task, "job", id))
+}
+
+func (kv *KVStore) key(task string, parts ...interface{}) string {
+	keys := []string{kv.Prefix + task}
+	for _, part := range parts {
+		keys = append(keys, fmt.Sprintf("%v", part))
+	}
+	return strings.Join(keys, ":")
+}
+
+func (kv *KVStore) record(value interface{}) (*Record, error) {
+	var bytes []byte
+	switch v := value.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		var err error
+		bytes, err = jsoniter.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	record := &Record{}
+	err := jsoniter.Unmarshal(bytes, record)
+	return record, err
+}
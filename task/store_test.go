@@ -0,0 +1,199 @@
+package task
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/store/lru"
+)
+
+func TestStorePersist(t *testing.T) {
+	jobs := prepareJobStore(t)
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				if args[0] == "fail" {
+					return nil, fmt.Errorf("#%d failed", id)
+				}
+				return fmt.Sprintf("#%d done", id), nil
+			},
+		},
+		Option{Name: "unit-test-store", Timeout: 5, Store: jobs, PollInterval: 100},
+	)
+	go task.Start()
+	defer task.Stop()
+
+	id, err := task.Add("ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id2, err := task.Add("fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	record, err := jobs.Get("unit-test-store", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SUCCESS, record.Status)
+	assert.Equal(t, fmt.Sprintf("#%d done", id), record.Response)
+	assert.Equal(t, []interface{}{"ok"}, record.Args)
+
+	record, err = jobs.Get("unit-test-store", id2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, FAILURE, record.Status)
+	assert.Equal(t, fmt.Sprintf("#%d failed", id2), record.Error)
+
+	// the completed jobs are read from the store
+	job, err := task.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUCCESS", job["status"])
+}
+
+func TestStoreRecover(t *testing.T) {
+	jobs := prepareJobStore(t)
+	now := time.Now().UnixMilli()
+	jobs.Save("unit-test-store", &Record{ID: 1, Status: WAITING, Args: []interface{}{"waiting"}, CreatedAt: now})
+	jobs.Save("unit-test-store", &Record{ID: 2, Status: RUNNING, Args: []interface{}{"running"}, Owner: "unit-test", CreatedAt: now})
+	jobs.Save("unit-test-store", &Record{ID: 3, Status: RUNNING, Args: []interface{}{"other"}, Owner: "other", CreatedAt: now})
+
+	var mu sync.Mutex
+	res := map[int]interface{}{}
+	task := New(
+		&Handlers{
+			Exec: func(id int, args ...interface{}) (interface{}, error) {
+				mu.Lock()
+				res[id] = args[0]
+				mu.Unlock()
+				return nil, nil
+			},
+		},
+		Option{Name: "unit-test-store", Timeout: 5, Store: jobs, Owner: "unit-test", PollInterval: 100},
+	)
+	go task.Start()
+	defer task.Stop()
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "waiting", res[1])
+	assert.Equal(t, "running", res[2])
+	assert.Nil(t, res[3]) // claimed by a running process
+
+	record, err := jobs.Get("unit-test-store", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SUCCESS, record.Status)
+	assert.Equal(t, 1, record.Runs)
+}
+
+func TestStoreSharedQueue(t *testing.T) {
+	jobs := prepareJobStore(t)
+	var mu sync.Mutex
+	runs := map[int]int{}
+	handlers := &Handlers{
+		Exec: func(id int, args ...interface{}) (interface{}, error) {
+			mu.Lock()
+			runs[id]++
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			return nil, nil
+		},
+	}
+
+	t1 := New(handlers, Option{Name: "unit-test-store", WorkerNums: 4, Timeout: 5, Store: jobs, Owner: "p1", PollInterval: 20})
+	t2 := New(handlers, Option{Name: "unit-test-store", WorkerNums: 4, Timeout: 5, Store: jobs, Owner: "p2", PollInterval: 20})
+	go t1.Start()
+	go t2.Start()
+	defer t1.Stop()
+	defer t2.Stop()
+
+	for i := 0; i < 20; i++ {
+		if _, err := t1.Add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(time.Second)
+	records, err := jobs.List("unit-test-store", SUCCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 20, len(records))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 20, len(runs))
+	for id, n := range runs {
+		assert.Equal(t, 1, n, "job %d", id)
+	}
+}
+
+func TestStoreIndex(t *testing.T) {
+	jobs := prepareJobStore(t)
+	jobs.Retention = 100 * time.Millisecond
+	now := time.Now().UnixMilli()
+	for id, status := range map[int]int{1: WAITING, 2: WAITING, 3: RUNNING, 4: SUCCESS, 5: FAILURE} {
+		if err := jobs.Save("unit-test-index", &Record{ID: id, Status: status, CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := jobs.List("unit-test-index", WAITING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{1, 2}, recordIDs(records))
+
+	// the job moves to the index of the new status
+	record, err := jobs.Get("unit-test-index", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.Status = CANCELED
+	jobs.Save("unit-test-index", record)
+
+	records, _ = jobs.List("unit-test-index", WAITING)
+	assert.Equal(t, []int{1}, recordIDs(records))
+	records, _ = jobs.List("unit-test-index", SUCCESS, CANCELED)
+	assert.Equal(t, []int{2, 4}, recordIDs(records))
+	records, _ = jobs.List("unit-test-index")
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, recordIDs(records))
+
+	// the succeeded and canceled jobs expire, the failed jobs are kept
+	time.Sleep(200 * time.Millisecond)
+	records, _ = jobs.List("unit-test-index")
+	assert.Equal(t, []int{1, 3, 5}, recordIDs(records))
+	assert.Equal(t, 0, jobs.Store.ArrayLen(jobs.key("unit-test-index", "status", SUCCESS)))
+
+	jobs.Delete("unit-test-index", 1)
+	records, _ = jobs.List("unit-test-index", WAITING)
+	assert.Empty(t, records)
+}
+
+func recordIDs(records []*Record) []int {
+	ids := []int{}
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return ids
+}
+
+func prepareJobStore(t *testing.T) *KVStore {
+	cache, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKVStore(cache)
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/yaoapp/kun/log"
@@ -34,10 +36,22 @@ func New(handlers *Handlers, option Option) *Task {
 		option.JobQueueLength = 1024
 	}
 
+	if option.DeadLetterSize == 0 {
+		option.DeadLetterSize = 1024
+	}
+
+	if option.PollInterval == 0 {
+		option.PollInterval = 1000
+	}
+
+	if option.Owner == "" {
+		option.Owner, _ = os.Hostname()
+	}
+
 	pool := &Pool{
 		size:      option.WorkerNums,
 		max:       option.JobQueueLength,
-		jobque:    make(chan *Job, option.JobQueueLength),
+		queue:     NewQueue(),
 		workerque: make(chan *Worker, option.WorkerNums),
 	}
 
@@ -51,6 +65,8 @@ func New(handlers *Handlers, option Option) *Task {
 		cancel:   cancel,
 		pool:     pool,
 		timeout:  option.Timeout,
+		store:    option.Store,
+		owner:    option.Owner,
 		Option:   option,
 	}
 }
@@ -65,16 +81,51 @@ func (t *Task) Start() {
 		t.startWorker(w)
 	}
 
+	// Re-enqueue the persisted jobs and pull the jobs added by other processes
+	var poll <-chan time.Time
+	if t.store != nil {
+		t.recover()
+		t.poll()
+		ticker := time.NewTicker(time.Duration(t.Option.PollInterval) * time.Millisecond)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
 	for {
+		// Dispatch when a job is due, otherwise wait for the next delayed job
+		var workerque chan *Worker
+		var timer *time.Timer
+		var due <-chan time.Time
+		if t.Paused() {
+			// wait for resuming (notified by the queue)
+		} else if t.pool.queue.Ready() {
+			workerque = t.pool.workerque
+		} else if wait := t.pool.queue.Wait(); wait >= 0 {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
+
 		select {
-		case job := <-t.pool.jobque:
-			worker := <-t.pool.workerque
+		case worker := <-workerque:
+			job, _ := t.pool.queue.Pop()
+			if job == nil {
+				t.pool.workerque <- worker
+				break
+			}
 			worker.job <- job
+		case <-t.pool.queue.notify:
+		case <-due:
+		case <-poll:
+			t.poll()
 		case <-interrupt:
 			return
 		case <-t.ctx.Done():
 			return
 		}
+
+		if timer != nil {
+			timer.Stop()
+		}
 	}
 }
 
@@ -85,28 +136,164 @@ func (t *Task) Stop() {
 
 // Add a job to the job queue
 func (t *Task) Add(args ...interface{}) (int, error) {
+	return t.AddWith(AddOption{}, args...)
+}
+
+// AddAt add a job to the job queue which runs at the given time
+func (t *Task) AddAt(at time.Time, args ...interface{}) (int, error) {
+	return t.AddWith(AddOption{At: at}, args...)
+}
+
+// AddAfter add a job to the job queue which runs after the given delay
+func (t *Task) AddAfter(delay time.Duration, args ...interface{}) (int, error) {
+	return t.AddWith(AddOption{Delay: delay}, args...)
+}
+
+// AddWith add a job to the job queue with the priority, delay and unique key options.
+// If a job with the same unique key is queued, returns the id of the queued job.
+func (t *Task) AddWith(option AddOption, args ...interface{}) (int, error) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
-	id := t.nextID()
-	if len(t.pool.jobque) > t.pool.max {
+	if option.Unique != "" {
+		if queued, has := t.pool.queue.Queued(option.Unique); has {
+			return t.duplicate(queued, option, args)
+		}
+	}
+
+	if t.pool.queue.Len() >= t.pool.max {
 		return 0, fmt.Errorf("[TASK] %s reached the limit of jobs queue", t.name)
 	}
 
+	id, err := t.nextID()
+	if err != nil {
+		return 0, err
+	}
+
+	runAt := option.At
+	if option.Delay > 0 {
+		runAt = time.Now().Add(option.Delay)
+	}
+
+	job := t.newJob(id, args, runAt)
+	job.priority = option.Priority
+	job.unique = option.Unique
+	t.jobs[id] = job
+	t.add(job)
+	t.pool.queue.Push(job)
+	return id, nil
+}
+
+// duplicate handle the duplicate job while the job with the same unique key is queued
+func (t *Task) duplicate(queued *Job, option AddOption, args []interface{}) (int, error) {
+	switch option.Duplicate {
+	case "", "drop":
+		log.Trace("[TASK] %s #%d DUPLICATE %s dropped", t.name, queued.id, option.Unique)
+		return queued.id, nil
+
+	case "merge":
+		if t.pool.queue.Merge(queued, option.Priority, args) {
+			t.save(queued)
+			log.Trace("[TASK] %s #%d DUPLICATE %s merged", t.name, queued.id, option.Unique)
+		}
+		return queued.id, nil
+	}
+
+	return 0, fmt.Errorf("[TASK] %s the duplicate option %s is not supported", t.name, option.Duplicate)
+}
+
+// newJob create a new job, the timeout starts when the job is due
+func (t *Task) newJob(id int, args []interface{}, runAt time.Time) *Job {
 	timeout := time.Duration(t.timeout) * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	job := &Job{
+	deadline := time.Now()
+	if runAt.After(deadline) {
+		deadline = runAt
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(timeout))
+	return &Job{
 		id:      id,
 		args:    args,
 		timeout: timeout,
 		ctx:     ctx,
 		cancel:  cancel,
+		created: time.Now().UnixMilli(),
+		runAt:   runAt,
+		done:    make(chan struct{}),
 	}
+}
 
-	t.jobs[id] = job
-	t.add(job)
-	t.pool.jobque <- job
-	return id, nil
+// recover re-enqueue the RUNNING jobs of the store which were claimed by this owner
+// or were not updated in the timeout (the process was stopped while running the job)
+func (t *Task) recover() {
+	records, err := t.store.List(t.name, RUNNING)
+	if err != nil {
+		log.Error("[TASK] %s recover jobs %s", t.name, err.Error())
+		return
+	}
+
+	expired := time.Now().Add(-time.Duration(t.timeout) * time.Second).UnixMilli()
+	for _, record := range records {
+		if record.Owner != t.owner && record.UpdatedAt > expired {
+			continue
+		}
+
+		record.Status = WAITING
+		record.Owner = ""
+		record.Runs++
+		if err := t.store.Save(t.name, record); err != nil {
+			log.Error("[TASK] %s recover job %d %s", t.name, record.ID, err.Error())
+			continue
+		}
+		log.Trace("[TASK] %s #%d RECOVERED", t.name, record.ID)
+	}
+}
+
+// poll enqueue the WAITING jobs of the store which are not in the local queue
+func (t *Task) poll() {
+	records, err := t.store.List(t.name, WAITING)
+	if err != nil {
+		log.Error("[TASK] %s pull jobs %s", t.name, err.Error())
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, record := range records {
+		if _, has := t.jobs[record.ID]; has {
+			continue
+		}
+
+		// the local queue is full, pull the jobs later
+		if t.pool.queue.Len() >= t.pool.max {
+			return
+		}
+
+		job := t.jobOf(record)
+		t.jobs[record.ID] = job
+		t.pool.queue.Push(job)
+	}
+}
+
+// jobOf create a waiting job from the persisted record
+func (t *Task) jobOf(record *Record) *Job {
+	runAt := time.Time{}
+	if record.RunAt > 0 {
+		runAt = time.UnixMilli(record.RunAt)
+	}
+
+	job := t.newJob(record.ID, record.Args, runAt)
+	job.runs = record.Runs
+	job.attempts = record.Attempts
+	job.created = record.CreatedAt
+	job.priority = record.Priority
+	job.status = WAITING
+	if record.Unique != "" {
+		if _, has := t.pool.queue.Queued(record.Unique); !has {
+			job.unique = record.Unique
+		}
+	}
+	return job
 }
 
 // Progress set the progress of the job
@@ -140,11 +327,177 @@ func (t *Task) Get(id int) (map[string]interface{}, error) {
 	t.mutex.Lock()
 	job, has := t.jobs[id]
 	t.mutex.Unlock()
+	if !has && t.store != nil {
+		record, err := t.store.Get(t.name, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return record.result(), nil
+	}
+
 	if !has {
 		return nil, fmt.Errorf("job %d does not exist or was completed", id)
 	}
+	return job.result(), nil
+}
+
+// Wait wait for the job to complete and returns the job details
+func (t *Task) Wait(ctx context.Context, id int) (map[string]interface{}, error) {
+	t.mutex.Lock()
+	job, has := t.jobs[id]
+	t.mutex.Unlock()
+	if !has {
+		return t.Get(id)
+	}
+
+	select {
+	case <-job.done:
+		return job.result(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// List the jobs with the given status (all if no status). Without a store, only the queued, running
+// and dead-letter jobs are listed.
+func (t *Task) List(status ...int) ([]map[string]interface{}, error) {
+	if t.store != nil {
+		records, err := t.store.List(t.name, status...)
+		if err != nil {
+			return nil, err
+		}
+
+		res := []map[string]interface{}{}
+		for _, record := range records {
+			res = append(res, record.result())
+		}
+		return res, nil
+	}
+
+	filter := map[int]bool{}
+	for _, s := range status {
+		filter[s] = true
+	}
+
+	t.mutex.Lock()
+	records := []*Record{}
+	for _, job := range t.jobs {
+		records = append(records, t.record(job))
+	}
+	records = append(records, t.dead...)
+	t.mutex.Unlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	res := []map[string]interface{}{}
+	for _, record := range records {
+		if len(filter) > 0 && !filter[record.Status] {
+			continue
+		}
+		res = append(res, record.result())
+	}
+	return res, nil
+}
+
+// Cancel the queued or running job
+func (t *Task) Cancel(id int) error {
+	t.mutex.Lock()
+	job, has := t.jobs[id]
+	t.mutex.Unlock()
+
+	if !has && t.store != nil {
+		record, err := t.store.Get(t.name, id)
+		if err != nil {
+			return err
+		}
+
+		if record.Status != WAITING {
+			return fmt.Errorf("job %d is %s and can't be canceled", id, status[record.Status])
+		}
+
+		record.Status = CANCELED
+		atomic.AddInt64(&t.metrics.canceled, 1)
+		return t.store.Save(t.name, record)
+	}
+
+	if !has {
+		return fmt.Errorf("job %d does not exist or was completed", id)
+	}
+
+	job.mu.Lock()
+	job.abort = true
+	job.mu.Unlock()
+
+	// The queued job is removed from the queue, the running job is canceled by the context
+	if t.pool.queue.Remove(job) {
+		t.canceled(job)
+		t.mutex.Lock()
+		if t.jobs[job.id] == job {
+			delete(t.jobs, job.id)
+			close(job.done)
+		}
+		t.mutex.Unlock()
+	}
+
+	job.cancel()
+	return nil
+}
+
+// Pause stop dispatching the queued jobs, the running jobs are not affected
+func (t *Task) Pause() {
+	atomic.StoreInt32(&t.paused, 1)
+	log.Trace("[TASK] %s PAUSED", t.name)
+}
+
+// Resume dispatching the queued jobs
+func (t *Task) Resume() {
+	atomic.StoreInt32(&t.paused, 0)
+	t.pool.queue.wake()
+	log.Trace("[TASK] %s RESUMED", t.name)
+}
+
+// Paused checks if the task is paused
+func (t *Task) Paused() bool {
+	return atomic.LoadInt32(&t.paused) == 1
+}
+
+func (t *Task) canceled(job *Job) {
+	job.mu.Lock()
+	job.status = CANCELED
+	job.response = nil
+	job.mu.Unlock()
+	atomic.AddInt64(&t.metrics.canceled, 1)
+	log.Trace("[TASK] %s #%d CANCELED", t.name, job.id)
+	t.save(job)
+}
+
+func (job *Job) isCanceled() bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.abort
+}
+
+// result returns the job details
+func (record *Record) result() map[string]interface{} {
+	response := record.Response
+	if record.Status == FAILURE {
+		response = record.Error
+	}
+	return map[string]interface{}{
+		"id":       record.ID,
+		"status":   status[record.Status],
+		"current":  record.Current,
+		"total":    record.Total,
+		"message":  record.Message,
+		"response": response,
+	}
+}
+
+// result returns the job details
+func (job *Job) result() map[string]interface{} {
 	job.mu.Lock()
-	result := map[string]interface{}{
+	defer job.mu.Unlock()
+	return map[string]interface{}{
 		"id":       job.id,
 		"status":   status[job.status],
 		"current":  job.curr,
@@ -152,8 +505,6 @@ func (t *Task) Get(id int) (map[string]interface{}, error) {
 		"message":  job.message,
 		"response": job.response,
 	}
-	job.mu.Unlock()
-	return result, nil
 }
 
 // createWorker create a new worker
@@ -182,10 +533,39 @@ func (t *Task) start(job *Job) {
 	defer job.cancel()
 	defer func() {
 		t.mutex.Lock()
-		delete(t.jobs, job.id)
+		if t.jobs[job.id] == job { // the retried job is replaced
+			delete(t.jobs, job.id)
+			close(job.done)
+		}
 		t.mutex.Unlock()
 	}()
 
+	// The job could be claimed by other processes sharing the store
+	if t.store != nil {
+		claimed, err := t.store.Claim(t.name, job.id, t.owner)
+		if err != nil {
+			log.Error("[TASK] %s Job:%v claim %s", t.name, job.id, err.Error())
+			return
+		}
+		if !claimed {
+			return
+		}
+	}
+
+	// The job was canceled before running
+	if job.isCanceled() {
+		t.canceled(job)
+		return
+	}
+
+	atomic.AddInt64(&t.metrics.running, 1)
+	started := time.Now()
+	defer func() {
+		atomic.AddInt64(&t.metrics.running, -1)
+		atomic.AddInt64(&t.metrics.duration, int64(time.Since(started)))
+		atomic.AddInt64(&t.metrics.completed, 1)
+	}()
+
 	ch := make(chan interface{}, 1) // the result channel
 	chError := make(chan error, 1)  // the error channel
 
@@ -212,13 +592,21 @@ func (t *Task) start(job *Job) {
 		return
 
 	case <-job.ctx.Done():
+		if job.isCanceled() {
+			t.canceled(job)
+			return
+		}
 		log.Error("[TASK] %s Job:%v the job was canceled (%v)", t.name, job.id, job.ctx.Err())
-		t.failure(job, job.ctx.Err())
+		if !t.retry(job, job.ctx.Err()) {
+			t.failure(job, job.ctx.Err())
+		}
 		return
 
 	case err := <-chError:
 		log.Error("[TASK] %s Job:%v  %v", t.name, job.id, err.Error())
-		t.failure(job, err)
+		if !t.retry(job, err) {
+			t.failure(job, err)
+		}
 		return
 
 	case res := <-ch:
@@ -228,17 +616,22 @@ func (t *Task) start(job *Job) {
 	}
 }
 
-func (t *Task) nextID() int {
+func (t *Task) nextID() (int, error) {
 	if t.handlers.NextID == nil {
-		return len(t.pool.jobque) + 1
+		if t.store != nil {
+			return t.store.NextID(t.name)
+		}
+		t.seq++
+		return t.seq, nil
 	}
 
 	id, err := t.handlers.NextID()
 	if err != nil {
 		log.Error("[TASK] %s can't get next id (%s)", t.name, err.Error())
-		return len(t.pool.jobque) + 1
+		t.seq++
+		return t.seq, nil
 	}
-	return id
+	return id, nil
 }
 
 // exec excute the job
@@ -249,6 +642,7 @@ func (t *Task) exec(job *Job) (interface{}, error) {
 	job.mu.Lock()
 	job.status = RUNNING
 	job.mu.Unlock()
+	t.save(job)
 	if t.handlers.Exec == nil {
 		err := fmt.Errorf("[TASK] %s Job:%v, is not set the execute handler", t.name, job.id)
 		return nil, err
@@ -260,7 +654,11 @@ func (t *Task) failure(job *Job, err error) {
 	job.mu.Lock()
 	job.status = FAILURE
 	job.response = err.Error()
+	job.err = err.Error()
 	job.mu.Unlock()
+	atomic.AddInt64(&t.metrics.failed, 1)
+	t.save(job)
+	t.bury(job)
 	if t.handlers.Error == nil {
 		return
 	}
@@ -272,6 +670,8 @@ func (t *Task) success(job *Job, response interface{}) {
 	job.status = SUCCESS
 	job.response = response
 	job.mu.Unlock()
+	atomic.AddInt64(&t.metrics.succeeded, 1)
+	t.save(job)
 	if t.handlers.Success == nil {
 		return
 	}
@@ -282,6 +682,7 @@ func (t *Task) add(job *Job) {
 	job.mu.Lock()
 	job.status = WAITING
 	job.mu.Unlock()
+	t.save(job)
 	if t.handlers.Add == nil {
 		return
 	}
@@ -289,8 +690,54 @@ func (t *Task) add(job *Job) {
 }
 
 func (t *Task) progress(job *Job, curr, total int, message string) {
+	t.save(job)
 	if t.handlers.Progress == nil {
 		return
 	}
 	t.handlers.Progress(job.id, curr, total, message)
 }
+
+// save persist the job to the store
+func (t *Task) save(job *Job) {
+	if t.store == nil {
+		return
+	}
+
+	record := t.record(job)
+	if err := t.store.Save(t.name, record); err != nil {
+		log.Error("[TASK] %s Job:%v save %s", t.name, job.id, err.Error())
+	}
+}
+
+// record the persisted record of the job
+func (t *Task) record(job *Job) *Record {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	record := &Record{
+		ID:        job.id,
+		Status:    job.status,
+		Args:      job.args,
+		Current:   job.curr,
+		Total:     job.total,
+		Message:   job.message,
+		Error:     job.err,
+		Runs:      job.runs,
+		Attempts:  job.attempts,
+		Priority:  job.priority,
+		Unique:    job.unique,
+		CreatedAt: job.created,
+	}
+
+	if !job.runAt.IsZero() {
+		record.RunAt = job.runAt.UnixMilli()
+	}
+
+	if job.status == SUCCESS {
+		record.Response = job.response
+	}
+
+	if job.status == RUNNING {
+		record.Owner = t.owner
+	}
+	return record
+}
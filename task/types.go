@@ -18,13 +18,17 @@ const (
 
 	// FAILURE the job is failure
 	FAILURE
+
+	// CANCELED the job was canceled
+	CANCELED
 )
 
 var status = map[int]string{
-	WAITING: "WAITING",
-	RUNNING: "RUNNING",
-	SUCCESS: "SUCCESS",
-	FAILURE: "SUCCESS",
+	WAITING:  "WAITING",
+	RUNNING:  "RUNNING",
+	SUCCESS:  "SUCCESS",
+	FAILURE:  "FAILURE",
+	CANCELED: "CANCELED",
 }
 
 // Task the task struct
@@ -37,24 +41,36 @@ type Task struct {
 	mutex    sync.Mutex
 	ctx      context.Context
 	cancel   context.CancelFunc
+	store    JobStore
+	owner    string
+	seq      int       // the last job id if the next id handler is not set
+	dead     []*Record // the dead-letter list of the jobs which exhausted the attempts (without store)
+	paused   int32     // 1 if the dispatching is paused
+	metrics  metrics   // the job counters and durations
 	Option   Option
 }
 
 // Option the task option
 type Option struct {
-	Name           string
-	JobQueueLength int
-	WorkerNums     int
-	AttemptAfter   int
-	Attempts       int
-	Timeout        int
+	Name            string
+	JobQueueLength  int
+	WorkerNums      int
+	AttemptAfter    int    // the delay (ms) before retrying a failed job, default is 200
+	Attempts        int    // the max attempts of a job, the failed job is retried until the attempts are exhausted
+	Backoff         string // the retry backoff strategy: fixed (default), linear, exponential, jitter (exponential with full jitter)
+	MaxAttemptAfter int    // the max delay (ms) before retrying, 0 means no limit
+	DeadLetterSize  int    // the max size of the in-memory dead-letter list, default is 1024
+	Timeout         int
+	Store           JobStore // the job persistence backend, the jobs are in memory if nil
+	Owner           string   // the owner name of the claimed jobs, default is the hostname
+	PollInterval    int      // the interval (ms) to pull the waiting jobs from the store, default is 1000
 }
 
 // Pool the worker pool
 type Pool struct {
 	size      int
 	max       int
-	jobque    chan *Job
+	queue     *Queue
 	workerque chan *Worker
 }
 
@@ -75,9 +91,29 @@ type Job struct {
 	message  string
 	response interface{}
 	args     []interface{}
+	runs     int
+	attempts int    // the failed attempts
+	err      string // the error of the last attempt
+	created  int64
+	priority int
+	runAt    time.Time
+	unique   string
+	seq      int64
+	index    int
+	done     chan struct{}
+	abort    bool // canceled by the Cancel method
 	mu       sync.Mutex
 }
 
+// AddOption the option of adding a job
+type AddOption struct {
+	Priority  int           // the jobs with higher priority run first
+	At        time.Time     // run the job at the time
+	Delay     time.Duration // run the job after the delay
+	Unique    string        // the unique key, a duplicate job is dropped or merged while one with the same key is queued
+	Duplicate string        // the way to handle the duplicate job: drop (default), merge (replace the args of the queued job)
+}
+
 // Handlers the event handlers
 type Handlers struct {
 	Exec     func(int, ...interface{}) (interface{}, error)